@@ -0,0 +1,545 @@
+package bounded
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dbunion/com/cache"
+)
+
+const (
+	defaultShards          = 16
+	defaultCleanupInterval = time.Minute
+	// shards are halved until each one holds at least this many entries,
+	// or 64 times this many bytes, so small limits stay exact.
+	minShardEntries = 64
+	// size of values other than string, []byte and numbers
+	defaultValueSize = 64
+)
+
+// Stats - counters of a bounded cache
+type Stats struct {
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	Evictions   int64 `json:"evictions"`
+	Expirations int64 `json:"expirations"`
+	Entries     int64 `json:"entries"`
+	Bytes       int64 `json:"bytes"`
+}
+
+// entry - cached value and its policy bookkeeping
+type entry struct {
+	key      string
+	val      interface{}
+	size     int64
+	weight   int64
+	expireAt time.Time
+	// locks are never evicted and not counted by the policy
+	pinned bool
+
+	elem    *list.Element
+	segment int
+	freq    int64
+	tick    uint64
+	index   int
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
+
+// eviction - entry removed by the cache itself, reported to OnEvict
+type eviction struct {
+	key    string
+	val    interface{}
+	reason string
+}
+
+// shard - independently locked part of the key space
+type shard struct {
+	mutex     sync.Mutex
+	items     map[string]*entry
+	policy    policy
+	newPolicy func() policy
+	capacity  int64
+	bytes     int64
+	stats     Stats
+}
+
+// Cache is in-process cache adapter with a limit of entries or bytes.
+type Cache struct {
+	shards     []*shard
+	mask       uint32
+	byBytes    bool
+	expiration time.Duration
+	sizeOf     func(key string, val interface{}) int64
+	onEvict    func(key string, val interface{}, reason string)
+	now        func() time.Time
+	stop       chan struct{}
+	once       sync.Once
+}
+
+// NewBoundedCache create new bounded cache.
+func NewBoundedCache() cache.Cache {
+	return &Cache{}
+}
+
+// shard return the shard of key, by FNV-1a so it is independent
+// from the hash of the frequency sketch.
+func (rc *Cache) shard(key string) *shard {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return rc.shards[h&rc.mask]
+}
+
+// deadline return expire time of timeout, 0 use the default expiration
+// and a negative timeout never expires.
+func (rc *Cache) deadline(timeout time.Duration) time.Time {
+	if timeout == 0 {
+		timeout = rc.expiration
+	}
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return rc.now().Add(timeout)
+}
+
+// notify call OnEvict outside of shard locks.
+func (rc *Cache) notify(evicted []eviction) {
+	if rc.onEvict == nil {
+		return
+	}
+	for _, e := range evicted {
+		rc.onEvict(e.key, e.val, e.reason)
+	}
+}
+
+// lookup return the live entry of key, must be called with mutex held.
+func (s *shard) lookup(key string, now time.Time, evicted *[]eviction) *entry {
+	e, ok := s.items[key]
+	if !ok {
+		return nil
+	}
+	if e.expired(now) {
+		s.unlink(e)
+		if !e.pinned {
+			s.stats.Expirations++
+			*evicted = append(*evicted, eviction{key: e.key, val: e.val, reason: cache.EvictReasonExpired})
+		}
+		return nil
+	}
+	return e
+}
+
+// unlink remove e from items and policy, must be called with mutex held.
+func (s *shard) unlink(e *entry) {
+	delete(s.items, e.key)
+	s.bytes -= e.size
+	if !e.pinned {
+		s.policy.remove(e)
+	}
+}
+
+// set replace the entry of key, must be called with mutex held.
+func (s *shard) set(rc *Cache, key string, val interface{}, expireAt time.Time, pinned bool, evicted *[]eviction) error {
+	if old, ok := s.items[key]; ok {
+		s.unlink(old)
+	}
+
+	e := &entry{key: key, val: val, size: rc.sizeOf(key, val), expireAt: expireAt, pinned: pinned}
+	e.weight = 1
+	if rc.byBytes {
+		e.weight = e.size
+	}
+	if !pinned && e.weight > s.capacity {
+		return fmt.Errorf("size %d of key %s exceeds shard capacity %d", e.weight, key, s.capacity)
+	}
+
+	s.items[key] = e
+	s.bytes += e.size
+	if pinned {
+		return nil
+	}
+	for _, v := range s.policy.add(e) {
+		delete(s.items, v.key)
+		s.bytes -= v.size
+		s.stats.Evictions++
+		*evicted = append(*evicted, eviction{key: v.key, val: v.val, reason: cache.EvictReasonCapacity})
+	}
+	return nil
+}
+
+// Get get cache from bounded cache.
+func (rc *Cache) Get(key string) interface{} {
+	v, _ := rc.get(key)
+	return v
+}
+
+func (rc *Cache) get(key string) (interface{}, bool) {
+	var evicted []eviction
+	s := rc.shard(key)
+	s.mutex.Lock()
+	e := s.lookup(key, rc.now(), &evicted)
+	if e == nil {
+		s.stats.Misses++
+	} else {
+		s.stats.Hits++
+		if !e.pinned {
+			s.policy.access(e)
+		}
+	}
+	s.mutex.Unlock()
+	rc.notify(evicted)
+
+	if e == nil {
+		return nil, false
+	}
+	return rc.untag(key, e.val)
+}
+
+// GetMulti get cache from bounded cache.
+func (rc *Cache) GetMulti(keys []string) []interface{} {
+	values := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, rc.Get(key))
+	}
+	return values
+}
+
+// Put put cache to bounded cache, evicting entries if it is full.
+func (rc *Cache) Put(key string, val interface{}, timeout time.Duration) error {
+	return rc.put(key, val, rc.deadline(timeout))
+}
+
+func (rc *Cache) put(key string, val interface{}, expireAt time.Time) error {
+	var evicted []eviction
+	s := rc.shard(key)
+	s.mutex.Lock()
+	err := s.set(rc, key, val, expireAt, false, &evicted)
+	s.mutex.Unlock()
+	rc.notify(evicted)
+	return err
+}
+
+// Delete delete cache in bounded cache.
+func (rc *Cache) Delete(key string) error {
+	s := rc.shard(key)
+	s.mutex.Lock()
+	if e, ok := s.items[key]; ok {
+		s.unlink(e)
+	}
+	s.mutex.Unlock()
+	return nil
+}
+
+// IsExist check cache's existence in bounded cache.
+func (rc *Cache) IsExist(key string) bool {
+	var evicted []eviction
+	s := rc.shard(key)
+	s.mutex.Lock()
+	e := s.lookup(key, rc.now(), &evicted)
+	var v interface{}
+	if e != nil {
+		v = e.val
+	}
+	s.mutex.Unlock()
+	rc.notify(evicted)
+
+	if e == nil {
+		return false
+	}
+	_, found := rc.untag(key, v)
+	return found
+}
+
+// Incr increase counter in bounded cache, a missing counter starts from zero.
+func (rc *Cache) Incr(key string) error {
+	_, err := rc.incrBy(key, 1)
+	return err
+}
+
+// Decr decrease counter in bounded cache.
+func (rc *Cache) Decr(key string) error {
+	_, err := rc.incrBy(key, -1)
+	return err
+}
+
+// IncrBy increase counter in bounded cache and return value.
+func (rc *Cache) IncrBy(key string) (interface{}, error) {
+	return rc.incrBy(key, 1)
+}
+
+// DecrBy decrease counter in bounded cache and return value.
+func (rc *Cache) DecrBy(key string) (interface{}, error) {
+	return rc.incrBy(key, -1)
+}
+
+// incrBy add delta to counter, the counter is stored as int64
+// and keeps its expiration.
+func (rc *Cache) incrBy(key string, delta int64) (int64, error) {
+	var evicted []eviction
+	defer func() { rc.notify(evicted) }()
+
+	s := rc.shard(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	expireAt := rc.deadline(0)
+	var n int64
+	if e := s.lookup(key, rc.now(), &evicted); e != nil {
+		switch v := e.val.(type) {
+		case int:
+			n = int64(v)
+		case int8:
+			n = int64(v)
+		case int16:
+			n = int64(v)
+		case int32:
+			n = int64(v)
+		case int64:
+			n = v
+		case uint8:
+			n = int64(v)
+		case uint16:
+			n = int64(v)
+		case uint32:
+			n = int64(v)
+		case uint64:
+			n = int64(v)
+		default:
+			return 0, fmt.Errorf("value of key %s is not an integer", key)
+		}
+		expireAt = e.expireAt
+	}
+	n += delta
+	if err := s.set(rc, key, n, expireAt, false, &evicted); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ClearAll clean all cache in bounded cache, including locks.
+func (rc *Cache) ClearAll() error {
+	for _, s := range rc.shards {
+		s.mutex.Lock()
+		s.items = make(map[string]*entry)
+		s.policy = s.newPolicy()
+		s.bytes = 0
+		s.mutex.Unlock()
+	}
+	return nil
+}
+
+// TryLock set key if it does not exist, atomically within its shard.
+// lock entries are never evicted to make room for others.
+func (rc *Cache) TryLock(key string, val interface{}, timeout time.Duration) error {
+	var evicted []eviction
+	s := rc.shard(key)
+	s.mutex.Lock()
+	err := cache.ErrLockFailure
+	if s.lookup(key, rc.now(), &evicted) == nil {
+		err = s.set(rc, key, val, rc.deadline(timeout), true, &evicted)
+	}
+	s.mutex.Unlock()
+	rc.notify(evicted)
+	return err
+}
+
+// UnLock release the lock only if it is still held by val.
+func (rc *Cache) UnLock(key string, val interface{}) error {
+	var evicted []eviction
+	s := rc.shard(key)
+	s.mutex.Lock()
+	if e := s.lookup(key, rc.now(), &evicted); e != nil && fmt.Sprint(e.val) == fmt.Sprint(val) {
+		s.unlink(e)
+	}
+	s.mutex.Unlock()
+	rc.notify(evicted)
+	return nil
+}
+
+// Set put cache to bounded cache without expiration.
+func (rc *Cache) Set(key string, val interface{}) (bool, error) {
+	if err := rc.put(key, val, time.Time{}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Expire key.
+func (rc *Cache) Expire(key string, timeout time.Duration) error {
+	err := rc.expire(key, timeout)
+	if err == cache.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func (rc *Cache) expire(key string, timeout time.Duration) error {
+	var evicted []eviction
+	s := rc.shard(key)
+	s.mutex.Lock()
+	e := s.lookup(key, rc.now(), &evicted)
+	if e != nil {
+		e.expireAt = rc.deadline(timeout)
+	}
+	s.mutex.Unlock()
+	rc.notify(evicted)
+
+	if e == nil {
+		return cache.ErrNotFound
+	}
+	return nil
+}
+
+// Stats return the counters of all shards.
+func (rc *Cache) Stats() Stats {
+	var stats Stats
+	for _, s := range rc.shards {
+		s.mutex.Lock()
+		stats.Hits += s.stats.Hits
+		stats.Misses += s.stats.Misses
+		stats.Evictions += s.stats.Evictions
+		stats.Expirations += s.stats.Expirations
+		stats.Entries += int64(len(s.items))
+		stats.Bytes += s.bytes
+		s.mutex.Unlock()
+	}
+	return stats
+}
+
+// cleanup remove expired entries of all shards.
+func (rc *Cache) cleanup() {
+	for _, s := range rc.shards {
+		var evicted []eviction
+		now := rc.now()
+		s.mutex.Lock()
+		for key := range s.items {
+			s.lookup(key, now, &evicted)
+		}
+		s.mutex.Unlock()
+		rc.notify(evicted)
+	}
+}
+
+func (rc *Cache) gc(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			rc.cleanup()
+		case <-rc.stop:
+			return
+		}
+	}
+}
+
+// Close stop the cleanup routine.
+func (rc *Cache) Close() error {
+	rc.once.Do(func() { close(rc.stop) })
+	return nil
+}
+
+// StartAndGC start bounded cache adapter.
+// config.Bounded set the limit and eviction, expired entries
+// are removed every CleanupInterval.
+func (rc *Cache) StartAndGC(config cache.Config) error {
+	bounded := config.Bounded
+	if bounded == nil {
+		return errors.New("bounded config is empty")
+	}
+	if bounded.MaxEntries < 0 || bounded.MaxBytes < 0 || (bounded.MaxEntries > 0) == (bounded.MaxBytes > 0) {
+		return errors.New("exactly one of bounded max_entries and max_bytes must be set")
+	}
+
+	capacity, minShard := bounded.MaxEntries, int64(minShardEntries)
+	if bounded.MaxBytes > 0 {
+		capacity, minShard = bounded.MaxBytes, 64*minShardEntries
+		rc.byBytes = true
+	}
+
+	n := int64(1)
+	for n < bounded.Shards || (bounded.Shards <= 0 && n < defaultShards) {
+		n <<= 1
+	}
+	for n > 1 && capacity/n < minShard {
+		n >>= 1
+	}
+
+	// the frequency sketch is sized by expected entries of a shard
+	expected := capacity / n
+	if rc.byBytes {
+		expected /= defaultValueSize
+	}
+
+	rc.shards = make([]*shard, n)
+	for i := range rc.shards {
+		// spread the remainder so shard capacities add up to the limit
+		c := capacity / n
+		if int64(i) < capacity%n {
+			c++
+		}
+		newPolicy, err := newPolicy(bounded.Eviction, c, expected)
+		if err != nil {
+			return err
+		}
+		rc.shards[i] = &shard{
+			items:     make(map[string]*entry),
+			policy:    newPolicy(),
+			newPolicy: newPolicy,
+			capacity:  c,
+		}
+	}
+	rc.mask = uint32(n - 1)
+
+	rc.expiration = config.Expiration
+	rc.sizeOf = bounded.SizeOf
+	if rc.sizeOf == nil {
+		rc.sizeOf = sizeOf
+	}
+	rc.onEvict = bounded.OnEvict
+	if rc.now == nil {
+		rc.now = time.Now
+	}
+
+	interval := bounded.CleanupInterval
+	if interval <= 0 {
+		interval = defaultCleanupInterval
+	}
+	rc.stop = make(chan struct{})
+	go rc.gc(interval)
+	return nil
+}
+
+// sizeOf estimate bytes of key and val.
+func sizeOf(key string, val interface{}) int64 {
+	n := int64(len(key))
+	switch v := val.(type) {
+	case string:
+		n += int64(len(v))
+	case []byte:
+		n += int64(len(v))
+	case bool, int8, uint8:
+		n++
+	case int16, uint16:
+		n += 2
+	case int32, uint32, float32:
+		n += 4
+	case int, int64, uint, uint64, uintptr, float64, time.Duration:
+		n += 8
+	default:
+		n += defaultValueSize
+	}
+	return n
+}
+
+func init() {
+	cache.Register(cache.TypeBoundedCache, NewBoundedCache)
+}
@@ -0,0 +1,250 @@
+package bounded
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// clock is a fake clock only moved by FastForward.
+type clock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *clock) FastForward(d time.Duration) {
+	c.mutex.Lock()
+	c.now = c.now.Add(d)
+	c.mutex.Unlock()
+}
+
+// evictLog record OnEvict calls.
+type evictLog struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+func (l *evictLog) OnEvict(key string, val interface{}, reason string) {
+	l.mutex.Lock()
+	l.lines = append(l.lines, fmt.Sprintf("%s=%v %s", key, val, reason))
+	l.mutex.Unlock()
+}
+
+func (l *evictLog) Lines() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+func newTestCache(t *testing.T, bounded cache.BoundedConfig, c *clock) *Cache {
+	rc := &Cache{}
+	if c != nil {
+		rc.now = c.Now
+	}
+	if err := rc.StartAndGC(cache.Config{Bounded: &bounded}); err != nil {
+		t.Fatalf("create new cache error, err:%v", err)
+	}
+	t.Cleanup(func() { _ = rc.Close() })
+	return rc
+}
+
+func TestBoundedConfig(t *testing.T) {
+	for _, config := range []cache.Config{
+		{},
+		{Bounded: &cache.BoundedConfig{}},
+		{Bounded: &cache.BoundedConfig{MaxEntries: 10, MaxBytes: 10}},
+		{Bounded: &cache.BoundedConfig{MaxEntries: -1}},
+		{Bounded: &cache.BoundedConfig{MaxEntries: 10, Eviction: "fifo"}},
+	} {
+		_, err := cache.NewCache(cache.TypeBoundedCache, config)
+		assert.NotNil(t, err)
+	}
+
+	bm, err := cache.NewCache(cache.TypeBoundedCache, cache.Config{Bounded: &cache.BoundedConfig{MaxEntries: 10000, Shards: 5}})
+	assert.Nil(t, err)
+	// shards are rounded up to a power of two and shrunk to hold minShardEntries
+	assert.Len(t, bm.(*Cache).shards, 8)
+	_ = bm.(*Cache).Close()
+
+	bm, err = cache.NewCache(cache.TypeBoundedCache, cache.Config{Bounded: &cache.BoundedConfig{MaxEntries: 100}})
+	assert.Nil(t, err)
+	assert.Len(t, bm.(*Cache).shards, 1)
+	_ = bm.(*Cache).Close()
+}
+
+func TestBoundedLRU(t *testing.T) {
+	log := &evictLog{}
+	rc := newTestCache(t, cache.BoundedConfig{MaxEntries: 3, OnEvict: log.OnEvict}, nil)
+
+	assert.Nil(t, rc.Put("a", 1, time.Minute))
+	assert.Nil(t, rc.Put("b", 2, time.Minute))
+	assert.Nil(t, rc.Put("c", 3, time.Minute))
+	assert.Equal(t, 1, rc.Get("a"))
+	assert.Nil(t, rc.Put("d", 4, time.Minute))
+
+	assert.False(t, rc.IsExist("b"))
+	assert.True(t, rc.IsExist("a"))
+	assert.Equal(t, []string{"b=2 capacity"}, log.Lines())
+	assert.Equal(t, int64(3), rc.Stats().Entries)
+}
+
+func TestBoundedLFU(t *testing.T) {
+	log := &evictLog{}
+	rc := newTestCache(t, cache.BoundedConfig{MaxEntries: 3, Eviction: cache.EvictionLFU, OnEvict: log.OnEvict}, nil)
+
+	assert.Nil(t, rc.Put("a", 1, time.Minute))
+	assert.Nil(t, rc.Put("b", 2, time.Minute))
+	assert.Nil(t, rc.Put("c", 3, time.Minute))
+	rc.Get("a")
+	rc.Get("a")
+	rc.Get("b")
+	rc.Get("c")
+	rc.Get("c")
+
+	// b is the least frequently used
+	assert.Nil(t, rc.Put("d", 4, time.Minute))
+	// d has the lowest frequency
+	assert.Nil(t, rc.Put("e", 5, time.Minute))
+
+	assert.Equal(t, []string{"b=2 capacity", "d=4 capacity"}, log.Lines())
+	assert.Equal(t, []interface{}{1, 3, 5}, rc.GetMulti([]string{"a", "c", "e"}))
+}
+
+func TestBoundedTinyLFU(t *testing.T) {
+	// hot keys survive a scan of one-hit keys with W-TinyLFU but not with LRU
+	survivors := func(eviction string) int {
+		rc := newTestCache(t, cache.BoundedConfig{MaxEntries: 100, Eviction: eviction}, nil)
+		for round := 0; round < 5; round++ {
+			for i := 0; i < 50; i++ {
+				key := fmt.Sprintf("hot%d", i)
+				if rc.Get(key) == nil {
+					assert.Nil(t, rc.Put(key, i, time.Minute))
+				}
+			}
+		}
+		for i := 0; i < 1000; i++ {
+			assert.Nil(t, rc.Put(fmt.Sprintf("scan%d", i), i, time.Minute))
+		}
+		assert.LessOrEqual(t, rc.Stats().Entries, int64(100))
+
+		n := 0
+		for i := 0; i < 50; i++ {
+			if rc.IsExist(fmt.Sprintf("hot%d", i)) {
+				n++
+			}
+		}
+		return n
+	}
+
+	assert.Equal(t, 0, survivors(cache.EvictionLRU))
+	assert.GreaterOrEqual(t, survivors(cache.EvictionTinyLFU), 45)
+}
+
+func TestBoundedBytes(t *testing.T) {
+	log := &evictLog{}
+	rc := newTestCache(t, cache.BoundedConfig{MaxBytes: 100, OnEvict: log.OnEvict}, nil)
+
+	// 2 bytes of key and 20 bytes of value
+	value := "01234567890123456789"
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, rc.Put(fmt.Sprintf("k%d", i), value, time.Minute))
+	}
+	stats := rc.Stats()
+	assert.Equal(t, int64(4), stats.Entries)
+	assert.Equal(t, int64(88), stats.Bytes)
+	assert.Equal(t, int64(6), stats.Evictions)
+	assert.Len(t, log.Lines(), 6)
+
+	// a single entry larger than the budget is rejected
+	assert.NotNil(t, rc.Put("big", string(make([]byte, 100)), time.Minute))
+	assert.False(t, rc.IsExist("big"))
+
+	// custom size
+	rc = newTestCache(t, cache.BoundedConfig{MaxBytes: 100, SizeOf: func(key string, val interface{}) int64 { return 50 }}, nil)
+	assert.Nil(t, rc.Put("a", 1, time.Minute))
+	assert.Nil(t, rc.Put("b", 1, time.Minute))
+	assert.Nil(t, rc.Put("c", 1, time.Minute))
+	assert.Equal(t, int64(2), rc.Stats().Entries)
+}
+
+func TestBoundedExpire(t *testing.T) {
+	c := &clock{now: time.Now()}
+	log := &evictLog{}
+	rc := newTestCache(t, cache.BoundedConfig{MaxEntries: 10, OnEvict: log.OnEvict}, c)
+
+	assert.Nil(t, rc.Put("a", 1, time.Second))
+	assert.Nil(t, rc.Put("b", 2, time.Second))
+	assert.Nil(t, rc.Put("c", 3, time.Minute))
+	c.FastForward(2 * time.Second)
+
+	assert.Nil(t, rc.Get("a"))
+	rc.cleanup()
+	assert.Equal(t, []string{"a=1 expired", "b=2 expired"}, log.Lines())
+
+	stats := rc.Stats()
+	assert.Equal(t, int64(2), stats.Expirations)
+	assert.Equal(t, int64(1), stats.Entries)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, int64(0), stats.Hits)
+}
+
+func TestBoundedLock(t *testing.T) {
+	rc := newTestCache(t, cache.BoundedConfig{MaxEntries: 64}, nil)
+
+	// only one of concurrent lockers wins
+	var wins int64
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if rc.TryLock("lock", i, time.Minute) == nil {
+				atomic.AddInt64(&wins, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1), wins)
+
+	// locks are not evicted by other entries
+	for i := 0; i < 1000; i++ {
+		assert.Nil(t, rc.Put(fmt.Sprintf("key%d", i), i, time.Minute))
+	}
+	assert.True(t, rc.IsExist("lock"))
+	assert.Equal(t, cache.ErrLockFailure, rc.TryLock("lock", "other", time.Minute))
+}
+
+func TestBoundedConcurrent(t *testing.T) {
+	for _, eviction := range []string{cache.EvictionLRU, cache.EvictionLFU, cache.EvictionTinyLFU} {
+		rc := newTestCache(t, cache.BoundedConfig{MaxEntries: 1000, Eviction: eviction}, nil)
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 2000; i++ {
+					key := fmt.Sprintf("key%d", (g*i)%3000)
+					if rc.Get(key) == nil {
+						_ = rc.Put(key, i, time.Minute)
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		stats := rc.Stats()
+		assert.LessOrEqual(t, stats.Entries, int64(1000))
+		assert.Equal(t, int64(8*2000), stats.Hits+stats.Misses)
+	}
+}
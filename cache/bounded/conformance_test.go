@@ -0,0 +1,18 @@
+package bounded
+
+import (
+	"testing"
+
+	"github.com/dbunion/com/cache"
+	"github.com/dbunion/com/cache/cachetest"
+)
+
+func TestBoundedConformance(t *testing.T) {
+	for _, eviction := range []string{cache.EvictionLRU, cache.EvictionLFU, cache.EvictionTinyLFU} {
+		t.Run(eviction, func(t *testing.T) {
+			c := &clock{}
+			rc := newTestCache(t, cache.BoundedConfig{MaxEntries: 1000, Eviction: eviction}, c)
+			cachetest.Run(t, rc, c.FastForward)
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package bounded
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+
+	"github.com/dbunion/com/cache"
+)
+
+// policy decide which entries leave a full shard, the capacity and
+// weight of entries are in the same unit, entries or bytes.
+type policy interface {
+	// add track a new entry and return the entries evicted to stay
+	// within capacity, may include e itself if it is not admitted.
+	add(e *entry) []*entry
+	// access record a hit of e.
+	access(e *entry)
+	// remove stop tracking e.
+	remove(e *entry)
+}
+
+// newPolicy return a policy factory by eviction name.
+func newPolicy(eviction string, capacity, expected int64) (func() policy, error) {
+	switch eviction {
+	case "", cache.EvictionLRU:
+		return func() policy { return newLRU(capacity) }, nil
+	case cache.EvictionLFU:
+		return func() policy { return newLFU(capacity) }, nil
+	case cache.EvictionTinyLFU:
+		return func() policy { return newTinyLFU(capacity, expected) }, nil
+	}
+	return nil, fmt.Errorf("unknown eviction %s", eviction)
+}
+
+// lru evict the least recently used entry.
+type lru struct {
+	capacity int64
+	weight   int64
+	ll       *list.List
+}
+
+func newLRU(capacity int64) *lru {
+	return &lru{capacity: capacity, ll: list.New()}
+}
+
+func (p *lru) add(e *entry) []*entry {
+	var evicted []*entry
+	for p.weight+e.weight > p.capacity && p.ll.Len() > 0 {
+		victim := p.ll.Back().Value.(*entry)
+		p.remove(victim)
+		evicted = append(evicted, victim)
+	}
+	e.elem = p.ll.PushFront(e)
+	p.weight += e.weight
+	return evicted
+}
+
+func (p *lru) access(e *entry) {
+	p.ll.MoveToFront(e.elem)
+}
+
+func (p *lru) remove(e *entry) {
+	p.ll.Remove(e.elem)
+	p.weight -= e.weight
+}
+
+// lfu evict the least frequently used entry, the least recently used
+// one among entries of the same frequency.
+type lfu struct {
+	capacity int64
+	weight   int64
+	tick     uint64
+	h        lfuHeap
+}
+
+func newLFU(capacity int64) *lfu {
+	return &lfu{capacity: capacity}
+}
+
+func (p *lfu) add(e *entry) []*entry {
+	var evicted []*entry
+	for p.weight+e.weight > p.capacity && p.h.Len() > 0 {
+		victim := heap.Pop(&p.h).(*entry)
+		p.weight -= victim.weight
+		evicted = append(evicted, victim)
+	}
+	p.tick++
+	e.freq, e.tick = 1, p.tick
+	heap.Push(&p.h, e)
+	p.weight += e.weight
+	return evicted
+}
+
+func (p *lfu) access(e *entry) {
+	p.tick++
+	e.freq, e.tick = e.freq+1, p.tick
+	heap.Fix(&p.h, e.index)
+}
+
+func (p *lfu) remove(e *entry) {
+	heap.Remove(&p.h, e.index)
+	p.weight -= e.weight
+}
+
+// lfuHeap - min-heap of entries by frequency and last access
+type lfuHeap []*entry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+// segments of tinyLFU
+const (
+	segmentWindow = iota
+	segmentProbation
+	segmentProtected
+)
+
+// tinyLFU is W-TinyLFU, new entries go to a small LRU window, entries
+// leaving the window are only admitted to the main segmented LRU if a
+// frequency sketch estimates them more popular than the main victim.
+type tinyLFU struct {
+	sketch *sketch
+
+	window    *list.List
+	probation *list.List
+	protected *list.List
+
+	windowCap    int64
+	mainCap      int64
+	protectedCap int64
+
+	windowWeight    int64
+	probationWeight int64
+	protectedWeight int64
+}
+
+// newTinyLFU create W-TinyLFU with 1% window and 80% of main protected,
+// expected is the number of entries used to size the sketch.
+func newTinyLFU(capacity, expected int64) *tinyLFU {
+	windowCap := capacity / 100
+	if windowCap < 1 {
+		windowCap = 1
+	}
+	mainCap := capacity - windowCap
+	return &tinyLFU{
+		sketch:       newSketch(expected),
+		window:       list.New(),
+		probation:    list.New(),
+		protected:    list.New(),
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: mainCap * 8 / 10,
+	}
+}
+
+func (p *tinyLFU) add(e *entry) []*entry {
+	p.sketch.increment(e.key)
+	e.segment = segmentWindow
+	e.elem = p.window.PushFront(e)
+	p.windowWeight += e.weight
+
+	var evicted []*entry
+	for p.windowWeight > p.windowCap && p.window.Len() > 0 {
+		candidate := p.window.Back().Value.(*entry)
+		p.window.Remove(candidate.elem)
+		p.windowWeight -= candidate.weight
+		evicted = append(evicted, p.admit(candidate)...)
+	}
+	return evicted
+}
+
+// admit move candidate from window to probation if it wins against
+// the victims of main, return the losers.
+func (p *tinyLFU) admit(candidate *entry) []*entry {
+	var evicted []*entry
+	for p.probationWeight+p.protectedWeight+candidate.weight > p.mainCap {
+		victim := p.probation.Back()
+		if victim == nil {
+			victim = p.protected.Back()
+		}
+		if victim == nil {
+			return append(evicted, candidate)
+		}
+		v := victim.Value.(*entry)
+		if p.sketch.estimate(candidate.key) <= p.sketch.estimate(v.key) {
+			return append(evicted, candidate)
+		}
+		p.remove(v)
+		evicted = append(evicted, v)
+	}
+	candidate.segment = segmentProbation
+	candidate.elem = p.probation.PushFront(candidate)
+	p.probationWeight += candidate.weight
+	return evicted
+}
+
+func (p *tinyLFU) access(e *entry) {
+	p.sketch.increment(e.key)
+	switch e.segment {
+	case segmentWindow:
+		p.window.MoveToFront(e.elem)
+	case segmentProbation:
+		p.probation.Remove(e.elem)
+		p.probationWeight -= e.weight
+		e.segment = segmentProtected
+		e.elem = p.protected.PushFront(e)
+		p.protectedWeight += e.weight
+		// demote the tail of protected back to probation
+		for p.protectedWeight > p.protectedCap && p.protected.Len() > 1 {
+			d := p.protected.Back().Value.(*entry)
+			p.protected.Remove(d.elem)
+			p.protectedWeight -= d.weight
+			d.segment = segmentProbation
+			d.elem = p.probation.PushFront(d)
+			p.probationWeight += d.weight
+		}
+	case segmentProtected:
+		p.protected.MoveToFront(e.elem)
+	}
+}
+
+func (p *tinyLFU) remove(e *entry) {
+	switch e.segment {
+	case segmentWindow:
+		p.window.Remove(e.elem)
+		p.windowWeight -= e.weight
+	case segmentProbation:
+		p.probation.Remove(e.elem)
+		p.probationWeight -= e.weight
+	case segmentProtected:
+		p.protected.Remove(e.elem)
+		p.protectedWeight -= e.weight
+	}
+}
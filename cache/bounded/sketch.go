@@ -0,0 +1,77 @@
+package bounded
+
+const (
+	sketchDepth   = 4
+	sketchMax     = 15
+	sketchMinSize = 16
+	sketchMaxSize = 1 << 22
+)
+
+// sketch is a count-min sketch of 4-bit saturating counters, all
+// counters are halved after 10 * width increments so old popularity fades.
+type sketch struct {
+	counters  []uint8
+	width     uint64
+	additions int64
+	resetAt   int64
+}
+
+// newSketch create sketch for about expected distinct keys.
+func newSketch(expected int64) *sketch {
+	width := uint64(sketchMinSize)
+	for int64(width) < expected && width < sketchMaxSize {
+		width <<= 1
+	}
+	return &sketch{
+		counters: make([]uint8, sketchDepth*width),
+		width:    width,
+		resetAt:  10 * int64(width),
+	}
+}
+
+// index return the counter of key in row i.
+func (s *sketch) index(h uint64, i int) uint64 {
+	h1, h2 := h&0xffffffff, h>>32
+	return uint64(i)*s.width + (h1+uint64(i)*h2)&(s.width-1)
+}
+
+func (s *sketch) increment(key string) {
+	h := hash64(key)
+	for i := 0; i < sketchDepth; i++ {
+		if idx := s.index(h, i); s.counters[idx] < sketchMax {
+			s.counters[idx]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+func (s *sketch) estimate(key string) uint8 {
+	h := hash64(key)
+	min := uint8(sketchMax)
+	for i := 0; i < sketchDepth; i++ {
+		if c := s.counters[s.index(h, i)]; c < min {
+			min = c
+		}
+	}
+	return min
+}
+
+func (s *sketch) reset() {
+	for i := range s.counters {
+		s.counters[i] >>= 1
+	}
+	s.additions /= 2
+}
+
+// hash64 is FNV-1a of key.
+func hash64(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
@@ -0,0 +1,83 @@
+package bounded
+
+import (
+	"context"
+	"time"
+
+	"github.com/dbunion/com/cache"
+)
+
+// Store is the context-aware view of bounded cache adapter.
+type Store struct {
+	rc *Cache
+}
+
+// Store returns the context-aware view of bounded cache adapter.
+func (rc *Cache) Store() cache.Store {
+	return &Store{rc: rc}
+}
+
+// Get get cache from bounded cache.
+func (s *Store) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	v, found := s.rc.get(key)
+	return v, found, nil
+}
+
+// GetMulti get cache from bounded cache.
+func (s *Store) GetMulti(ctx context.Context, keys []string) ([]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.rc.GetMulti(keys), nil
+}
+
+// Put put cache to bounded cache.
+func (s *Store) Put(ctx context.Context, key string, val interface{}, timeout time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.rc.Put(key, val, timeout)
+}
+
+// Delete delete cache in bounded cache.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.rc.Delete(key)
+}
+
+// IncrBy add delta to counter in bounded cache, a missing counter starts from zero.
+func (s *Store) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.rc.incrBy(key, delta)
+}
+
+// Expire reset expiration of key in bounded cache.
+func (s *Store) Expire(ctx context.Context, key string, timeout time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.rc.expire(key, timeout)
+}
+
+// IsExist check cache's existence in bounded cache.
+func (s *Store) IsExist(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.rc.IsExist(key), nil
+}
+
+// ClearAll clean all cache in bounded cache.
+func (s *Store) ClearAll(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.rc.ClearAll()
+}
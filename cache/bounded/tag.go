@@ -0,0 +1,37 @@
+package bounded
+
+import (
+	"time"
+
+	"github.com/dbunion/com/cache"
+)
+
+// PutWithTags put cache to bounded cache with the current generations of tags.
+// generation counters are entries of the cache, an evicted counter
+// invalidates the values of its tag.
+func (rc *Cache) PutWithTags(key string, val interface{}, timeout time.Duration, tags ...string) error {
+	generations, err := cache.TagGenerations(rc, tags)
+	if err != nil {
+		return err
+	}
+	return rc.Put(key, &cache.Tagged{Val: val, Generations: generations}, timeout)
+}
+
+// InvalidateTag invalidate values put with tag by moving its generation.
+func (rc *Cache) InvalidateTag(tag string) error {
+	return cache.NextTagGeneration(rc, tag)
+}
+
+// untag return the value of key, values of invalidated tags are deleted.
+// must be called without shard locks, generations are read from the cache.
+func (rc *Cache) untag(key string, v interface{}) (interface{}, bool) {
+	t, ok := v.(*cache.Tagged)
+	if !ok {
+		return v, true
+	}
+	if !cache.TagGenerationsValid(rc, t.Generations) {
+		_ = rc.Delete(key)
+		return nil, false
+	}
+	return t.Val, true
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"errors"
 	"fmt"
 	"time"
 )
@@ -12,8 +13,38 @@ const (
 	TypeMemoryCache = "memCache"
 	// TypeRedisCache - type use redis
 	TypeRedisCache = "redisCache"
+	// TypeTieredCache - type use go cache in front of a remote cache
+	TypeTieredCache = "tieredCache"
+	// TypeBoundedCache - type in-process cache with a size limit and eviction
+	TypeBoundedCache = "boundedCache"
 )
 
+const (
+	// WriteThrough - tiered cache writes both L1 and L2
+	WriteThrough = "write_through"
+	// WriteAround - tiered cache writes L2 only and invalidates L1
+	WriteAround = "write_around"
+)
+
+const (
+	// EvictionLRU - evict the least recently used entry
+	EvictionLRU = "lru"
+	// EvictionLFU - evict the least frequently used entry
+	EvictionLFU = "lfu"
+	// EvictionTinyLFU - admit entries by W-TinyLFU frequency sketch
+	EvictionTinyLFU = "w_tinylfu"
+)
+
+const (
+	// EvictReasonCapacity - entry evicted to stay within the size limit
+	EvictReasonCapacity = "capacity"
+	// EvictReasonExpired - entry removed after its expiration
+	EvictReasonExpired = "expired"
+)
+
+// ErrLockFailure is returned by TryLock if the lock is held by someone else.
+var ErrLockFailure = errors.New("lock failure")
+
 // Config - define cache config
 type Config struct {
 	Expiration time.Duration `json:"expiration"`
@@ -29,6 +60,26 @@ type Config struct {
 	MaxIdle   int64  `json:"max_idle"`
 	MaxActive int64  `json:"max_active"`
 	Key       string `json:"key"`
+	// redis sentinel, name of the monitored master and host:port of sentinels
+	MasterName       string   `json:"master_name"`
+	SentinelAddrs    []string `json:"sentinel_addrs"`
+	SentinelPassword string   `json:"sentinel_password"`
+	// redis cluster, host:port of seed nodes
+	ClusterAddrs []string `json:"cluster_addrs"`
+	// redis cluster, max MOVED/ASK redirects followed per command, default 3
+	MaxRedirects int64 `json:"max_redirects"`
+
+	// memcache, servers of the consistent-hash ring, use Server and Port if empty
+	Nodes []Node `json:"nodes"`
+
+	// tiered
+	Tiered *TieredConfig `json:"tiered"`
+
+	// bounded
+	Bounded *BoundedConfig `json:"bounded"`
+
+	// wrap the adapter with metrics, tracing and slow-op logging if set
+	Instrument *InstrumentConfig `json:"instrument"`
 
 	// Extend fields
 	// Extended fields can be used if there is a special implementation
@@ -36,6 +87,54 @@ type Config struct {
 	Extend2 string `json:"extend_2"`
 }
 
+// Node - weighted server of a consistent-hash ring
+type Node struct {
+	// host:port
+	Addr string `json:"addr"`
+	// share of keys relative to other nodes, default 1
+	Weight int64 `json:"weight"`
+}
+
+// TieredConfig - two-level cache config, L1 is the in-process go cache
+type TieredConfig struct {
+	// L2 adapter name and config
+	Remote       string `json:"remote"`
+	RemoteConfig Config `json:"remote_config"`
+
+	// L1 expiration, capped by the timeout of each put
+	LocalExpiration time.Duration `json:"local_expiration"`
+	// L2 expiration, used when put without timeout
+	RemoteExpiration time.Duration `json:"remote_expiration"`
+	// WriteThrough or WriteAround, default WriteThrough
+	WriteMode string `json:"write_mode"`
+
+	// redis pub/sub channel to broadcast L1 invalidation, disabled if empty
+	Channel string `json:"channel"`
+	// redis server of the channel, use RemoteConfig if L2 is redis
+	PubSub *Config `json:"pub_sub"`
+}
+
+// BoundedConfig - bounded in-process cache config, exactly one of MaxEntries
+// and MaxBytes must be set
+type BoundedConfig struct {
+	// max number of entries
+	MaxEntries int64 `json:"max_entries"`
+	// max estimated bytes of keys and values
+	MaxBytes int64 `json:"max_bytes"`
+	// EvictionLRU, EvictionLFU or EvictionTinyLFU, default EvictionLRU
+	Eviction string `json:"eviction"`
+	// number of independently locked shards, rounded up to a power of two, default 16
+	Shards int64 `json:"shards"`
+	// interval to remove expired entries, default 1 minute
+	CleanupInterval time.Duration `json:"cleanup_interval"`
+
+	// estimate the bytes of an entry, default count key and
+	// string/[]byte values by length and numbers by their width
+	SizeOf func(key string, val interface{}) int64 `json:"-"`
+	// called after an entry is evicted with EvictReasonCapacity or EvictReasonExpired
+	OnEvict func(key string, val interface{}, reason string) `json:"-"`
+}
+
 // Cache interface contains all behaviors for cache adapter.
 type Cache interface {
 	// get cached value by key.
@@ -91,7 +190,8 @@ func Register(name string, adapter Instance) {
 }
 
 // NewCache Create a new cache driver by adapter name and config setting.
-// it will start gc automatically.
+// it will start gc automatically, and wrap the adapter with Instrument
+// if config.Instrument is set.
 func NewCache(adapterName string, config Config) (adapter Cache, err error) {
 	instanceFunc, ok := adapters[adapterName]
 	if !ok {
@@ -101,7 +201,10 @@ func NewCache(adapterName string, config Config) (adapter Cache, err error) {
 	adapter = instanceFunc()
 	err = adapter.StartAndGC(config)
 	if err != nil {
-		adapter = nil
+		return nil, err
+	}
+	if config.Instrument != nil {
+		return Instrument(adapterName, adapter, *config.Instrument)
 	}
 	return
 }
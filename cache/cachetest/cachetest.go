@@ -0,0 +1,245 @@
+// Package cachetest is the conformance suite every cache.Cache adapter must pass.
+//
+// Usage:
+//
+//	func TestConformance(t *testing.T) {
+//		bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{})
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		cachetest.Run(t, bm, nil)
+//	}
+package cachetest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// Run run the conformance suite against c, every case start with ClearAll.
+// cases of tags are skipped if c does not implement cache.Tagger.
+// wait let d elapse on the backend, time.Sleep is used if wait is nil.
+func Run(t *testing.T, c cache.Cache, wait func(d time.Duration)) {
+	if wait == nil {
+		wait = time.Sleep
+	}
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, c cache.Cache, wait func(d time.Duration))
+	}{
+		{"GetPut", testGetPut},
+		{"GetMulti", testGetMulti},
+		{"Delete", testDelete},
+		{"Counter", testCounter},
+		{"Expire", testExpire},
+		{"Lock", testLock},
+		{"Set", testSet},
+		{"ClearAll", testClearAll},
+		{"Tags", testTags},
+		{"Namespace", testNamespace},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := c.ClearAll(); err != nil {
+				t.Fatalf("clear all error, err:%v", err)
+			}
+			tc.fn(t, c, wait)
+		})
+	}
+}
+
+// str normalize a cached value, adapters may return []byte or the put value.
+func str(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(s)
+	case string:
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+func testGetPut(t *testing.T, c cache.Cache, wait func(d time.Duration)) {
+	assert.Nil(t, c.Get("key"))
+	assert.False(t, c.IsExist("key"))
+
+	assert.Nil(t, c.Put("key", "value", 10*time.Second))
+	assert.True(t, c.IsExist("key"))
+	assert.Equal(t, "value", str(c.Get("key")))
+
+	// put overwrite
+	assert.Nil(t, c.Put("key", "value2", 10*time.Second))
+	assert.Equal(t, "value2", str(c.Get("key")))
+
+	// put with timeout expire
+	assert.Nil(t, c.Put("ttl", "value", time.Second))
+	wait(2 * time.Second)
+	assert.False(t, c.IsExist("ttl"))
+	assert.Nil(t, c.Get("ttl"))
+	assert.True(t, c.IsExist("key"))
+}
+
+func testGetMulti(t *testing.T, c cache.Cache, wait func(d time.Duration)) {
+	assert.Nil(t, c.Put("key1", "value1", 10*time.Second))
+	assert.Nil(t, c.Put("key2", "value2", 10*time.Second))
+
+	// values keep the order of keys, nil for missing keys
+	values := c.GetMulti([]string{"key2", "missing", "key1"})
+	if !assert.Len(t, values, 3) {
+		return
+	}
+	assert.Equal(t, "value2", str(values[0]))
+	assert.Nil(t, values[1])
+	assert.Equal(t, "value1", str(values[2]))
+}
+
+func testDelete(t *testing.T, c cache.Cache, wait func(d time.Duration)) {
+	assert.Nil(t, c.Put("key", "value", 10*time.Second))
+	assert.Nil(t, c.Delete("key"))
+	assert.False(t, c.IsExist("key"))
+
+	// delete is idempotent
+	assert.Nil(t, c.Delete("key"))
+}
+
+func testCounter(t *testing.T, c cache.Cache, wait func(d time.Duration)) {
+	// a missing counter starts from zero
+	assert.Nil(t, c.Incr("counter"))
+	assert.Equal(t, "1", str(c.Get("counter")))
+
+	v, err := c.IncrBy("counter")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", str(v))
+	assert.Equal(t, "2", str(c.Get("counter")))
+
+	v, err = c.DecrBy("counter")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", str(v))
+
+	assert.Nil(t, c.Decr("counter"))
+	assert.Equal(t, "0", str(c.Get("counter")))
+
+	v, err = c.IncrBy("other")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", str(v))
+}
+
+func testExpire(t *testing.T, c cache.Cache, wait func(d time.Duration)) {
+	assert.Nil(t, c.Put("key", "value", time.Minute))
+	assert.Nil(t, c.Put("keep", "value", time.Minute))
+	assert.Nil(t, c.Expire("key", time.Second))
+	assert.True(t, c.IsExist("key"))
+
+	wait(2 * time.Second)
+	assert.False(t, c.IsExist("key"))
+	assert.True(t, c.IsExist("keep"))
+
+	// expire a missing key is ignored
+	assert.Nil(t, c.Expire("missing", time.Second))
+	assert.False(t, c.IsExist("missing"))
+}
+
+func testLock(t *testing.T, c cache.Cache, wait func(d time.Duration)) {
+	assert.Nil(t, c.TryLock("lock", "owner1", 10*time.Second))
+	assert.Equal(t, cache.ErrLockFailure, c.TryLock("lock", "owner2", 10*time.Second))
+
+	// only the owner can unlock
+	assert.Nil(t, c.UnLock("lock", "owner2"))
+	assert.Equal(t, cache.ErrLockFailure, c.TryLock("lock", "owner2", 10*time.Second))
+
+	assert.Nil(t, c.UnLock("lock", "owner1"))
+	assert.Nil(t, c.TryLock("lock", "owner2", time.Second))
+
+	// the lock expire with its timeout
+	wait(2 * time.Second)
+	assert.Nil(t, c.TryLock("lock", "owner3", 10*time.Second))
+	assert.Nil(t, c.UnLock("lock", "owner3"))
+
+	// unlock a missing lock is ignored
+	assert.Nil(t, c.UnLock("missing", "owner1"))
+}
+
+func testSet(t *testing.T, c cache.Cache, wait func(d time.Duration)) {
+	ok, err := c.Set("key", "value")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value", str(c.Get("key")))
+}
+
+func testClearAll(t *testing.T, c cache.Cache, wait func(d time.Duration)) {
+	assert.Nil(t, c.Put("key1", "value1", 10*time.Second))
+	assert.Nil(t, c.Put("key2", "value2", 10*time.Second))
+	assert.Nil(t, c.ClearAll())
+	assert.False(t, c.IsExist("key1"))
+	assert.False(t, c.IsExist("key2"))
+}
+
+func testTags(t *testing.T, c cache.Cache, wait func(d time.Duration)) {
+	tagger, ok := c.(cache.Tagger)
+	if !ok {
+		t.Skip("adapter does not implement cache.Tagger")
+	}
+
+	assert.Nil(t, tagger.PutWithTags("key1", "value1", 10*time.Second, "tag1", "tag2"))
+	assert.Nil(t, tagger.PutWithTags("key2", "value2", 10*time.Second, "tag2"))
+	assert.Nil(t, tagger.PutWithTags("key3", "value3", 10*time.Second))
+	assert.Equal(t, "value1", str(c.Get("key1")))
+	values := c.GetMulti([]string{"key1", "key2", "key3"})
+	if assert.Len(t, values, 3) {
+		assert.Equal(t, "value2", str(values[1]))
+	}
+
+	assert.Nil(t, tagger.InvalidateTag("tag1"))
+	assert.False(t, c.IsExist("key1"))
+	assert.Nil(t, c.Get("key1"))
+	assert.Equal(t, "value2", str(c.Get("key2")))
+
+	// values put after the invalidation are kept
+	assert.Nil(t, tagger.PutWithTags("key4", "value4", 10*time.Second, "tag1"))
+	assert.Equal(t, "value4", str(c.Get("key4")))
+
+	assert.Nil(t, tagger.InvalidateTag("tag2"))
+	assert.Nil(t, c.Get("key2"))
+	assert.Equal(t, "value4", str(c.Get("key4")))
+	assert.Equal(t, "value3", str(c.Get("key3")))
+
+	// invalidate an unknown tag is ignored
+	assert.Nil(t, tagger.InvalidateTag("missing"))
+}
+
+func testNamespace(t *testing.T, c cache.Cache, wait func(d time.Duration)) {
+	if _, ok := c.(cache.Tagger); !ok {
+		t.Skip("adapter does not implement cache.Tagger")
+	}
+
+	ns1, err := cache.Namespace(c, "ns1")
+	assert.Nil(t, err)
+	ns2, err := cache.Namespace(c, "ns2")
+	assert.Nil(t, err)
+
+	assert.Nil(t, ns1.Put("key", "value1", 10*time.Second))
+	assert.Nil(t, ns2.Put("key", "value2", 10*time.Second))
+	assert.Nil(t, c.Put("key", "value", 10*time.Second))
+	assert.Equal(t, "value1", str(ns1.Get("key")))
+	assert.Equal(t, "value2", str(ns2.Get("key")))
+	assert.Equal(t, "value1", str(c.Get("ns1:key")))
+
+	// tags are scoped by the namespace
+	assert.Nil(t, ns1.PutWithTags("tagged", "value1", 10*time.Second, "tag"))
+	assert.Nil(t, ns2.PutWithTags("tagged", "value2", 10*time.Second, "tag"))
+	assert.Nil(t, ns1.InvalidateTag("tag"))
+	assert.False(t, ns1.IsExist("tagged"))
+	assert.True(t, ns2.IsExist("tagged"))
+
+	assert.Nil(t, ns1.ClearAll())
+	assert.False(t, ns1.IsExist("key"))
+	assert.Equal(t, "value2", str(ns2.Get("key")))
+	assert.Equal(t, "value", str(c.Get("key")))
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// CodecJSON - serialize values with encoding/json
+	CodecJSON = "json"
+	// CodecGob - serialize values with encoding/gob
+	CodecGob = "gob"
+	// CodecMsgpack - serialize values with msgpack
+	CodecMsgpack = "msgpack"
+	// CodecProtobuf - serialize values with protobuf, values must be proto messages
+	CodecProtobuf = "protobuf"
+)
+
+// Codec serialize values to the bytes stored by cache adapters.
+type Codec interface {
+	// Marshal encode v to bytes.
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal decode data into the value pointed to by v.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var codecs = make(map[string]Codec)
+
+// RegisterCodec makes a codec available by the codec name.
+// If RegisterCodec is called twice with the same name or if codec is nil,
+// it panics.
+func RegisterCodec(name string, codec Codec) {
+	if codec == nil {
+		panic("cache: RegisterCodec codec is nil")
+	}
+	if _, ok := codecs[name]; ok {
+		panic("cache: RegisterCodec called twice for codec " + name)
+	}
+	codecs[name] = codec
+}
+
+// GetCodec returns the codec registered by name.
+func GetCodec(name string) (Codec, error) {
+	codec, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("cache: unknown codec name %q (forgot to import?)", name)
+	}
+	return codec, nil
+}
+
+// jsonCodec - encoding/json codec
+type jsonCodec struct{}
+
+// Marshal encode v to json.
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decode json into v.
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// gobCodec - encoding/gob codec
+type gobCodec struct{}
+
+// Marshal encode v to gob.
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decode gob into v.
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func init() {
+	RegisterCodec(CodecJSON, jsonCodec{})
+	RegisterCodec(CodecGob, gobCodec{})
+}
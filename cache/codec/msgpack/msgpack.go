@@ -0,0 +1,28 @@
+package msgpack
+
+import (
+	"github.com/dbunion/com/cache"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec is msgpack cache codec.
+type Codec struct{}
+
+// NewMsgpackCodec create new msgpack codec.
+func NewMsgpackCodec() cache.Codec {
+	return &Codec{}
+}
+
+// Marshal encode v to msgpack.
+func (c *Codec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+// Unmarshal decode msgpack into v.
+func (c *Codec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+func init() {
+	cache.RegisterCodec(cache.CodecMsgpack, NewMsgpackCodec())
+}
@@ -0,0 +1,51 @@
+package protobuf
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/dbunion/com/cache"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec is protobuf cache codec, values must be proto messages.
+type Codec struct{}
+
+// NewProtobufCodec create new protobuf codec.
+func NewProtobufCodec() cache.Codec {
+	return &Codec{}
+}
+
+// Marshal encode v to protobuf.
+func (c *Codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf: %T is not a proto message", v)
+	}
+	return proto.Marshal(m)
+}
+
+// Unmarshal decode protobuf into v, v is either a message or
+// a pointer to a message pointer which is allocated on demand.
+func (c *Codec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && rv.Elem().Kind() == reflect.Ptr {
+		elem := reflect.New(rv.Elem().Type().Elem())
+		if m, ok := elem.Interface().(proto.Message); ok {
+			if err := proto.Unmarshal(data, m); err != nil {
+				return err
+			}
+			rv.Elem().Set(elem)
+			return nil
+		}
+	}
+	return fmt.Errorf("protobuf: %T is not a proto message", v)
+}
+
+func init() {
+	cache.RegisterCodec(cache.CodecProtobuf, NewProtobufCodec())
+}
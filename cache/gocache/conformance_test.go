@@ -0,0 +1,16 @@
+package gocache
+
+import (
+	"testing"
+
+	"github.com/dbunion/com/cache"
+	"github.com/dbunion/com/cache/cachetest"
+)
+
+func TestGoCacheConformance(t *testing.T) {
+	bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{})
+	if err != nil {
+		t.Fatalf("create new cache error, err:%v", err)
+	}
+	cachetest.Run(t, bm, nil)
+}
@@ -1,6 +1,7 @@
 package gocache
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -21,7 +22,7 @@ func NewGoCache() cache.Cache {
 
 // Get cache from go-cache.
 func (rc *Cache) Get(key string) interface{} {
-	if v, found := rc.p.Get(key); found {
+	if v, found := rc.get(key); found {
 		return v
 	}
 	return nil
@@ -31,7 +32,7 @@ func (rc *Cache) Get(key string) interface{} {
 func (rc *Cache) GetMulti(keys []string) []interface{} {
 	values := make([]interface{}, 0)
 	for _, key := range keys {
-		v, found := rc.p.Get(key)
+		v, found := rc.get(key)
 		if found {
 			values = append(values, v)
 			continue
@@ -55,43 +56,34 @@ func (rc *Cache) Delete(key string) error {
 
 // IsExist check cache's existence in go-cache.
 func (rc *Cache) IsExist(key string) bool {
-	_, found := rc.p.Get(key)
+	_, found := rc.get(key)
 	return found
 }
 
-// Incr increase counter in go-cache.
+// Incr increase counter in go-cache, a missing counter starts from zero.
 func (rc *Cache) Incr(key string) error {
-	return rc.p.Increment(key, 1)
+	_, err := rc.incrBy(key, 1)
+	return err
 }
 
 // Decr decrease counter in go-cache.
 func (rc *Cache) Decr(key string) error {
-	return rc.p.Decrement(key, 1)
+	_, err := rc.incrBy(key, -1)
+	return err
 }
 
-// IncrBy increase counter in go-cache.
+// IncrBy increase counter in go-cache and return value.
 func (rc *Cache) IncrBy(key string) (interface{}, error) {
-	if err := rc.p.Increment(key, 1); err != nil {
-		return nil, err
-	}
-
-	if v, found := rc.p.Get(key); found {
-		return v, nil
-	}
-
-	return nil, fmt.Errorf("key not exist")
+	return rc.incrBy(key, 1)
 }
 
-// DecrBy decrease counter in go-cache.
+// DecrBy decrease counter in go-cache and return value.
 func (rc *Cache) DecrBy(key string) (interface{}, error) {
-	if err := rc.p.Decrement(key, -1); err != nil {
-		return nil, err
-	}
+	return rc.incrBy(key, -1)
+}
 
-	if v, found := rc.p.Get(key); found {
-		return v, nil
-	}
-	return nil, fmt.Errorf("key not exist")
+func (rc *Cache) incrBy(key string, delta int64) (int64, error) {
+	return (&Store{rc: rc}).IncrBy(context.Background(), key, delta)
 }
 
 // ClearAll clean all cache in redis. delete this redis collection.
@@ -107,21 +99,18 @@ func (rc *Cache) ClearAll() error {
 
 // TryLock ...
 func (rc *Cache) TryLock(key string, val interface{}, timeout time.Duration) error {
-	if rc.IsExist(key) {
-		return fmt.Errorf("lock failure")
+	if err := rc.p.Add(key, val, timeout); err != nil {
+		return cache.ErrLockFailure
 	}
-
-	rc.p.Set(key, val, timeout)
 	return nil
 }
 
-// UnLock ...
+// UnLock release the lock only if it is still held by val.
 func (rc *Cache) UnLock(key string, val interface{}) error {
-	if !rc.IsExist(key) {
-		return nil
+	if v, found := rc.p.Get(key); found && fmt.Sprint(v) == fmt.Sprint(val) {
+		return rc.Delete(key)
 	}
-
-	return rc.Delete(key)
+	return nil
 }
 
 // Set put cache to redis.
@@ -132,6 +121,9 @@ func (rc *Cache) Set(key string, val interface{}) (bool, error) {
 
 // Expire key.
 func (rc *Cache) Expire(key string, timeout time.Duration) error {
+	if v, found := rc.p.Get(key); found {
+		rc.p.Set(key, v, timeout)
+	}
 	return nil
 }
 
@@ -0,0 +1,112 @@
+package gocache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dbunion/com/cache"
+)
+
+// Store is the context-aware view of go-cache adapter.
+type Store struct {
+	rc *Cache
+}
+
+// Store returns the context-aware view of go-cache adapter.
+func (rc *Cache) Store() cache.Store {
+	return &Store{rc: rc}
+}
+
+// Get get cache from go-cache.
+func (s *Store) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	v, found := s.rc.get(key)
+	return v, found, nil
+}
+
+// GetMulti get cache from go-cache.
+func (s *Store) GetMulti(ctx context.Context, keys []string) ([]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.rc.GetMulti(keys), nil
+}
+
+// Put put cache to go-cache.
+func (s *Store) Put(ctx context.Context, key string, val interface{}, timeout time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.rc.Put(key, val, timeout)
+}
+
+// Delete delete cache in go-cache.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.rc.Delete(key)
+}
+
+// IncrBy add delta to counter in go-cache, a missing counter starts from zero.
+func (s *Store) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := s.rc.p.Add(key, delta, s.rc.defaultExpiration); err == nil {
+		return delta, nil
+	}
+
+	if err := s.rc.p.Increment(key, delta); err != nil {
+		return 0, err
+	}
+
+	v, found := s.rc.p.Get(key)
+	if !found {
+		return 0, cache.ErrNotFound
+	}
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint64:
+		return int64(n), nil
+	}
+	return 0, fmt.Errorf("value of key %s is not an integer", key)
+}
+
+// Expire reset expiration of key in go-cache.
+func (s *Store) Expire(ctx context.Context, key string, timeout time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	v, found := s.rc.p.Get(key)
+	if !found {
+		return cache.ErrNotFound
+	}
+	s.rc.p.Set(key, v, timeout)
+	return nil
+}
+
+// IsExist check cache's existence in go-cache.
+func (s *Store) IsExist(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.rc.IsExist(key), nil
+}
+
+// ClearAll clean all cache in go-cache.
+func (s *Store) ClearAll(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.rc.p.Flush()
+	return nil
+}
@@ -0,0 +1,119 @@
+package gocache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// shimCache hide the native store, force cache.AsStore to use the shim.
+type shimCache struct {
+	cache.Cache
+}
+
+// testStore test s, a shim only step counters by one.
+func testStore(t *testing.T, s cache.Store, shim bool) {
+	ctx := context.Background()
+
+	v, found, err := s.Get(ctx, cacheKey)
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.Nil(t, v)
+
+	assert.Nil(t, s.Put(ctx, cacheKey, "author", 10*time.Second))
+	v, found, err = s.Get(ctx, cacheKey)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "author", v)
+
+	vv, err := s.GetMulti(ctx, []string{cacheKey, cacheKey1})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"author", nil}, vv)
+
+	if shim {
+		_, err := s.IncrBy(ctx, cacheKey1, 5)
+		assert.ErrorIs(t, err, cache.ErrDeltaUnsupported)
+		for i := 1; i <= 5; i++ {
+			n, err := s.IncrBy(ctx, cacheKey1, 1)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(i), n)
+		}
+	} else {
+		n, err := s.IncrBy(ctx, cacheKey1, 5)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(5), n)
+	}
+	n, err := s.IncrBy(ctx, cacheKey1, -1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), n)
+
+	assert.Equal(t, cache.ErrNotFound, s.Expire(ctx, "missingKey", time.Second))
+	assert.Nil(t, s.Expire(ctx, cacheKey, time.Second))
+
+	assert.Nil(t, s.Delete(ctx, cacheKey1))
+	assert.Nil(t, s.Delete(ctx, cacheKey1))
+	exist, err := s.IsExist(ctx, cacheKey1)
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _, err = s.Get(canceled, cacheKey)
+	assert.Equal(t, context.Canceled, err)
+
+	assert.Nil(t, s.ClearAll(ctx))
+}
+
+func TestGoCacheStore(t *testing.T) {
+	s, err := cache.NewStore(cache.TypeGoCache, cache.Config{Expiration: 0})
+	if err != nil {
+		t.Fatalf("init err:%v", err)
+	}
+	assert.IsType(t, &Store{}, s)
+	testStore(t, s, false)
+
+	ctx := context.Background()
+	assert.Nil(t, s.Put(ctx, cacheKey, "author", 10*time.Second))
+	assert.Nil(t, s.Expire(ctx, cacheKey, time.Second))
+	time.Sleep(1100 * time.Millisecond)
+	exist, err := s.IsExist(ctx, cacheKey)
+	assert.Nil(t, err)
+	assert.False(t, exist)
+}
+
+func TestGoCacheStoreShim(t *testing.T) {
+	bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{Expiration: 0})
+	if err != nil {
+		t.Fatalf("init err:%v", err)
+	}
+	s := cache.AsStore(shimCache{bm})
+	assert.NotEqual(t, &Store{}, s)
+	testStore(t, s, true)
+}
+
+func TestGoCacheTryLock(t *testing.T) {
+	bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{Expiration: 0})
+	if err != nil {
+		t.Fatalf("init err:%v", err)
+	}
+
+	assert.Nil(t, bm.TryLock("lock", "owner1", 10*time.Second))
+	assert.Equal(t, cache.ErrLockFailure, bm.TryLock("lock", "owner2", 10*time.Second))
+	assert.Nil(t, bm.UnLock("lock", "owner1"))
+	assert.Nil(t, bm.TryLock("lock", "owner2", 10*time.Second))
+}
+
+func TestGoCacheExpire(t *testing.T) {
+	bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{Expiration: 0})
+	if err != nil {
+		t.Fatalf("init err:%v", err)
+	}
+
+	assert.Nil(t, bm.Put(cacheKey, "author", 10*time.Second))
+	assert.Nil(t, bm.Expire(cacheKey, 100*time.Millisecond))
+	time.Sleep(200 * time.Millisecond)
+	assert.False(t, bm.IsExist(cacheKey))
+}
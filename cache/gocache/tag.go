@@ -0,0 +1,38 @@
+package gocache
+
+import (
+	"time"
+
+	"github.com/dbunion/com/cache"
+)
+
+// PutWithTags put cache to go-cache with the current generations of tags.
+// generation counters live in go-cache with the default expiration,
+// a lost counter invalidates the values of its tag.
+func (rc *Cache) PutWithTags(key string, val interface{}, timeout time.Duration, tags ...string) error {
+	generations, err := cache.TagGenerations(rc, tags)
+	if err != nil {
+		return err
+	}
+	rc.p.Set(key, &cache.Tagged{Val: val, Generations: generations}, timeout)
+	return nil
+}
+
+// InvalidateTag invalidate values put with tag by moving its generation.
+func (rc *Cache) InvalidateTag(tag string) error {
+	return cache.NextTagGeneration(rc, tag)
+}
+
+// get return the value of key, values of invalidated tags are deleted.
+func (rc *Cache) get(key string) (interface{}, bool) {
+	v, found := rc.p.Get(key)
+	t, ok := v.(*cache.Tagged)
+	if !ok {
+		return v, found
+	}
+	if !cache.TagGenerationsValid(rc, t.Generations) {
+		rc.p.Delete(key)
+		return nil, false
+	}
+	return t.Val, true
+}
@@ -0,0 +1,514 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/dbunion/com/internal/prom"
+	"github.com/dbunion/com/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/dbunion/com/cache"
+
+// InstrumentConfig - observability of a cache adapter
+type InstrumentConfig struct {
+	// export prometheus metrics per adapter and operation
+	Metrics bool `json:"metrics"`
+	// emit an opentelemetry span per operation
+	Tracing bool `json:"tracing"`
+	// log operations slower than SlowThreshold through Logger, 0 disable
+	SlowThreshold time.Duration `json:"slow_threshold"`
+	// record the first key of an operation in its span, keys may be
+	// sensitive so only the number of keys is recorded by default
+	TraceKeys bool `json:"trace_keys"`
+
+	// logger of slow operations
+	Logger log.Logger `json:"-"`
+	// registerer of metrics, default prometheus.DefaultRegisterer
+	Registerer prometheus.Registerer `json:"-"`
+	// provider of tracers, default the global provider
+	TracerProvider trace.TracerProvider `json:"-"`
+}
+
+// metrics - collectors shared by every instrumented adapter of a registerer
+type metrics struct {
+	requests *prometheus.CounterVec
+	hits     *prometheus.CounterVec
+	misses   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+var registered prom.Registry[metrics]
+
+// register return the collectors of reg, registering them once.
+func register(reg prometheus.Registerer) (*metrics, error) {
+	return registered.Load(reg, newMetrics)
+}
+
+// newMetrics create and register the collectors of reg.
+func newMetrics(reg prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "cache",
+			Name:      "requests_total",
+			Help:      "Number of cache operations by result.",
+		}, []string{"adapter", "operation", "result"}),
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "cache",
+			Name:      "hits_total",
+			Help:      "Number of keys found by cache operations.",
+		}, []string{"adapter", "operation"}),
+		misses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "cache",
+			Name:      "misses_total",
+			Help:      "Number of keys missed by cache operations.",
+		}, []string{"adapter", "operation"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "cache",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of cache operations.",
+			Buckets:   []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1},
+		}, []string{"adapter", "operation"}),
+	}
+	var err error
+	if m.requests, err = prom.Register(reg, m.requests); err != nil {
+		return nil, err
+	}
+	if m.hits, err = prom.Register(reg, m.hits); err != nil {
+		return nil, err
+	}
+	if m.misses, err = prom.Register(reg, m.misses); err != nil {
+		return nil, err
+	}
+	if m.duration, err = prom.Register(reg, m.duration); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Instrumented is a cache decorator recording metrics, spans and slow
+// operations of the wrapped adapter.
+type Instrumented struct {
+	c       Cache
+	adapter string
+	config  InstrumentConfig
+	metrics *metrics
+	tracer  trace.Tracer
+}
+
+// TaggedInstrumented is the decorator of an adapter implementing Tagger.
+type TaggedInstrumented struct {
+	*Instrumented
+	t Tagger
+}
+
+// Instrument wrap c, adapterName label its metrics and spans. The
+// wrapper implement Tagger only if c does.
+func Instrument(adapterName string, c Cache, config InstrumentConfig) (Cache, error) {
+	i, err := instrument(adapterName, c, config)
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := c.(Tagger); ok {
+		return &TaggedInstrumented{Instrumented: i, t: t}, nil
+	}
+	return i, nil
+}
+
+// instrument create the decorator of c.
+func instrument(adapterName string, c Cache, config InstrumentConfig) (*Instrumented, error) {
+	i := &Instrumented{c: c, adapter: adapterName, config: config}
+	if config.Metrics {
+		reg := config.Registerer
+		if reg == nil {
+			reg = prometheus.DefaultRegisterer
+		}
+		m, err := register(reg)
+		if err != nil {
+			return nil, err
+		}
+		i.metrics = m
+	}
+	if config.Tracing {
+		provider := config.TracerProvider
+		if provider == nil {
+			provider = otel.GetTracerProvider()
+		}
+		i.tracer = provider.Tracer(instrumentationName)
+	}
+	return i, nil
+}
+
+// Unwrap return the wrapped adapter.
+func (i *Instrumented) Unwrap() Cache {
+	return i.c
+}
+
+// outcome - result of an operation
+type outcome struct {
+	hits   int
+	misses int
+	err    error
+}
+
+// observe run op on keys and record its outcome.
+func (i *Instrumented) observe(ctx context.Context, op string, keys []string, fn func(ctx context.Context) outcome) {
+	start := time.Now()
+	var span trace.Span
+	if i.tracer != nil {
+		attrs := []label.KeyValue{
+			label.String("cache.adapter", i.adapter),
+			label.Int("cache.keys", len(keys)),
+		}
+		if i.config.TraceKeys && len(keys) > 0 {
+			attrs = append(attrs, label.String("cache.key", keys[0]))
+		}
+		ctx, span = i.tracer.Start(ctx, "cache."+op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attrs...))
+	}
+
+	o := fn(ctx)
+	elapsed := time.Since(start)
+
+	if i.metrics != nil {
+		result := "ok"
+		if o.err != nil {
+			result = "error"
+		}
+		i.metrics.requests.WithLabelValues(i.adapter, op, result).Inc()
+		i.metrics.duration.WithLabelValues(i.adapter, op).Observe(elapsed.Seconds())
+		if o.hits > 0 {
+			i.metrics.hits.WithLabelValues(i.adapter, op).Add(float64(o.hits))
+		}
+		if o.misses > 0 {
+			i.metrics.misses.WithLabelValues(i.adapter, op).Add(float64(o.misses))
+		}
+	}
+
+	if span != nil {
+		span.SetAttributes(label.Int("cache.hits", o.hits), label.Int("cache.misses", o.misses))
+		if o.err != nil {
+			span.RecordError(o.err)
+			span.SetStatus(codes.Error, o.err.Error())
+		}
+		span.End()
+	}
+
+	if i.config.SlowThreshold > 0 && elapsed >= i.config.SlowThreshold && i.config.Logger != nil {
+		i.config.Logger.Warnf("cache: slow %s %s of adapter %s took %v, err:%v",
+			op, describe(keys), i.adapter, elapsed, o.err)
+	}
+}
+
+// describe return the first key and the number of the others, so a
+// batch does not make an unbounded log line.
+func describe(keys []string) string {
+	if len(keys) <= 1 {
+		return strings.Join(keys, "")
+	}
+	return fmt.Sprintf("%s and %d more keys", keys[0], len(keys)-1)
+}
+
+// found return the outcome of a lookup, adapters may return an error as value.
+func found(v interface{}) outcome {
+	if err, ok := v.(error); ok {
+		return outcome{err: err}
+	}
+	if v == nil {
+		return outcome{misses: 1}
+	}
+	return outcome{hits: 1}
+}
+
+// locked return the outcome of a lock attempt, a held lock is a miss.
+func locked(err error) outcome {
+	if err == ErrLockFailure {
+		return outcome{misses: 1}
+	}
+	return outcome{err: err}
+}
+
+// Get get cached value by key.
+func (i *Instrumented) Get(key string) (v interface{}) {
+	i.observe(context.Background(), "get", []string{key}, func(context.Context) outcome {
+		v = i.c.Get(key)
+		return found(v)
+	})
+	return
+}
+
+// GetMulti is a batch version of Get.
+func (i *Instrumented) GetMulti(keys []string) (values []interface{}) {
+	i.observe(context.Background(), "get_multi", keys, func(context.Context) outcome {
+		values = i.c.GetMulti(keys)
+		var o outcome
+		for _, v := range values {
+			r := found(v)
+			o.hits += r.hits
+			o.misses += r.misses
+			if r.err != nil {
+				o.err = r.err
+			}
+		}
+		return o
+	})
+	return
+}
+
+// Put set cached value with key and expire time.
+func (i *Instrumented) Put(key string, val interface{}, timeout time.Duration) (err error) {
+	i.observe(context.Background(), "put", []string{key}, func(context.Context) outcome {
+		err = i.c.Put(key, val, timeout)
+		return outcome{err: err}
+	})
+	return
+}
+
+// Delete delete cached value by key.
+func (i *Instrumented) Delete(key string) (err error) {
+	i.observe(context.Background(), "delete", []string{key}, func(context.Context) outcome {
+		err = i.c.Delete(key)
+		return outcome{err: err}
+	})
+	return
+}
+
+// Incr increase cached int value by key, as a counter.
+func (i *Instrumented) Incr(key string) (err error) {
+	i.observe(context.Background(), "incr", []string{key}, func(context.Context) outcome {
+		err = i.c.Incr(key)
+		return outcome{err: err}
+	})
+	return
+}
+
+// Decr decrease cached int value by key, as a counter.
+func (i *Instrumented) Decr(key string) (err error) {
+	i.observe(context.Background(), "decr", []string{key}, func(context.Context) outcome {
+		err = i.c.Decr(key)
+		return outcome{err: err}
+	})
+	return
+}
+
+// IncrBy increase cached int value by key and return value.
+func (i *Instrumented) IncrBy(key string) (v interface{}, err error) {
+	i.observe(context.Background(), "incr_by", []string{key}, func(context.Context) outcome {
+		v, err = i.c.IncrBy(key)
+		return outcome{err: err}
+	})
+	return
+}
+
+// DecrBy decrease cached int value by key and return value.
+func (i *Instrumented) DecrBy(key string) (v interface{}, err error) {
+	i.observe(context.Background(), "decr_by", []string{key}, func(context.Context) outcome {
+		v, err = i.c.DecrBy(key)
+		return outcome{err: err}
+	})
+	return
+}
+
+// TryLock lock key with timeout.
+func (i *Instrumented) TryLock(key string, val interface{}, timeout time.Duration) (err error) {
+	i.observe(context.Background(), "try_lock", []string{key}, func(context.Context) outcome {
+		err = i.c.TryLock(key, val, timeout)
+		return locked(err)
+	})
+	return
+}
+
+// UnLock unlock key.
+func (i *Instrumented) UnLock(key string, val interface{}) (err error) {
+	i.observe(context.Background(), "unlock", []string{key}, func(context.Context) outcome {
+		err = i.c.UnLock(key, val)
+		return outcome{err: err}
+	})
+	return
+}
+
+// Set set key.
+func (i *Instrumented) Set(key string, val interface{}) (ok bool, err error) {
+	i.observe(context.Background(), "set", []string{key}, func(context.Context) outcome {
+		ok, err = i.c.Set(key, val)
+		return outcome{err: err}
+	})
+	return
+}
+
+// Expire expire key with timeout.
+func (i *Instrumented) Expire(key string, timeout time.Duration) (err error) {
+	i.observe(context.Background(), "expire", []string{key}, func(context.Context) outcome {
+		err = i.c.Expire(key, timeout)
+		return outcome{err: err}
+	})
+	return
+}
+
+// IsExist check if cached value exists or not.
+func (i *Instrumented) IsExist(key string) (ok bool) {
+	i.observe(context.Background(), "is_exist", []string{key}, func(context.Context) outcome {
+		ok = i.c.IsExist(key)
+		if ok {
+			return outcome{hits: 1}
+		}
+		return outcome{misses: 1}
+	})
+	return
+}
+
+// ClearAll clear all cache.
+func (i *Instrumented) ClearAll() (err error) {
+	i.observe(context.Background(), "clear_all", nil, func(context.Context) outcome {
+		err = i.c.ClearAll()
+		return outcome{err: err}
+	})
+	return
+}
+
+// StartAndGC start the wrapped adapter.
+func (i *Instrumented) StartAndGC(config Config) error {
+	return i.c.StartAndGC(config)
+}
+
+// Close close the wrapped adapter if it implements io.Closer.
+func (i *Instrumented) Close() error {
+	if closer, ok := i.c.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// PutWithTags put cached value with key, expire time and tags.
+func (ti *TaggedInstrumented) PutWithTags(key string, val interface{}, timeout time.Duration, tags ...string) (err error) {
+	ti.observe(context.Background(), "put_with_tags", []string{key}, func(context.Context) outcome {
+		err = ti.t.PutWithTags(key, val, timeout, tags...)
+		return outcome{err: err}
+	})
+	return
+}
+
+// InvalidateTag delete all cached values put with tag.
+func (ti *TaggedInstrumented) InvalidateTag(tag string) (err error) {
+	ti.observe(context.Background(), "invalidate_tag", nil, func(context.Context) outcome {
+		err = ti.t.InvalidateTag(tag)
+		return outcome{err: err}
+	})
+	return
+}
+
+// Store returns the context-aware view of the wrapped adapter,
+// spans of its operations are children of the span in ctx.
+func (i *Instrumented) Store() Store {
+	return &instrumentedStore{i: i, s: AsStore(i.c)}
+}
+
+// instrumentedStore - instrumented context-aware view
+type instrumentedStore struct {
+	i *Instrumented
+	s Store
+}
+
+// Get get cached value by key.
+func (is *instrumentedStore) Get(ctx context.Context, key string) (v interface{}, ok bool, err error) {
+	is.i.observe(ctx, "get", []string{key}, func(ctx context.Context) outcome {
+		v, ok, err = is.s.Get(ctx, key)
+		if err != nil {
+			return outcome{err: err}
+		}
+		if ok {
+			return outcome{hits: 1}
+		}
+		return outcome{misses: 1}
+	})
+	return
+}
+
+// GetMulti get cached values by keys.
+func (is *instrumentedStore) GetMulti(ctx context.Context, keys []string) (values []interface{}, err error) {
+	is.i.observe(ctx, "get_multi", keys, func(ctx context.Context) outcome {
+		values, err = is.s.GetMulti(ctx, keys)
+		o := outcome{err: err}
+		for _, v := range values {
+			if v == nil {
+				o.misses++
+			} else {
+				o.hits++
+			}
+		}
+		return o
+	})
+	return
+}
+
+// Put set cached value with key and expire time.
+func (is *instrumentedStore) Put(ctx context.Context, key string, val interface{}, timeout time.Duration) (err error) {
+	is.i.observe(ctx, "put", []string{key}, func(ctx context.Context) outcome {
+		err = is.s.Put(ctx, key, val, timeout)
+		return outcome{err: err}
+	})
+	return
+}
+
+// Delete delete cached value by key.
+func (is *instrumentedStore) Delete(ctx context.Context, key string) (err error) {
+	is.i.observe(ctx, "delete", []string{key}, func(ctx context.Context) outcome {
+		err = is.s.Delete(ctx, key)
+		return outcome{err: err}
+	})
+	return
+}
+
+// IncrBy add delta to cached int value by key and return the new value.
+func (is *instrumentedStore) IncrBy(ctx context.Context, key string, delta int64) (v int64, err error) {
+	is.i.observe(ctx, "incr_by", []string{key}, func(ctx context.Context) outcome {
+		v, err = is.s.IncrBy(ctx, key, delta)
+		return outcome{err: err}
+	})
+	return
+}
+
+// Expire expire key with timeout.
+func (is *instrumentedStore) Expire(ctx context.Context, key string, timeout time.Duration) (err error) {
+	is.i.observe(ctx, "expire", []string{key}, func(ctx context.Context) outcome {
+		err = is.s.Expire(ctx, key, timeout)
+		if err == ErrNotFound {
+			return outcome{misses: 1}
+		}
+		return outcome{err: err}
+	})
+	return
+}
+
+// IsExist check if cached value exists or not.
+func (is *instrumentedStore) IsExist(ctx context.Context, key string) (ok bool, err error) {
+	is.i.observe(ctx, "is_exist", []string{key}, func(ctx context.Context) outcome {
+		ok, err = is.s.IsExist(ctx, key)
+		if err != nil {
+			return outcome{err: err}
+		}
+		if ok {
+			return outcome{hits: 1}
+		}
+		return outcome{misses: 1}
+	})
+	return
+}
+
+// ClearAll clear all cache.
+func (is *instrumentedStore) ClearAll(ctx context.Context) (err error) {
+	is.i.observe(ctx, "clear_all", nil, func(ctx context.Context) outcome {
+		err = is.s.ClearAll(ctx)
+		return outcome{err: err}
+	})
+	return
+}
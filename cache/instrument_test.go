@@ -0,0 +1,188 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/cache"
+	"github.com/dbunion/com/cache/gocache"
+	"github.com/dbunion/com/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/oteltest"
+)
+
+// warnLogger record warnings, other methods are not implemented.
+type warnLogger struct {
+	log.Logger
+	mutex sync.Mutex
+	lines []string
+}
+
+func (l *warnLogger) Warnf(format string, v ...interface{}) {
+	l.mutex.Lock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+	l.mutex.Unlock()
+}
+
+func TestInstrument(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	recorder := &oteltest.StandardSpanRecorder{}
+	logger := &warnLogger{}
+
+	bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{
+		Instrument: &cache.InstrumentConfig{
+			Metrics:        true,
+			Tracing:        true,
+			SlowThreshold:  time.Nanosecond,
+			Logger:         logger,
+			Registerer:     reg,
+			TracerProvider: oteltest.NewTracerProvider(oteltest.WithSpanRecorder(recorder)),
+		},
+	})
+	if err != nil {
+		t.Fatalf("create new cache error, err:%v", err)
+	}
+	assert.IsType(t, &cache.TaggedInstrumented{}, bm)
+
+	assert.Nil(t, bm.Put("key", "value", time.Minute))
+	assert.Equal(t, "value", bm.Get("key"))
+	assert.Nil(t, bm.Get("missing"))
+	assert.Len(t, bm.GetMulti([]string{"key", "missing", "missing"}), 3)
+	assert.Nil(t, bm.TryLock("lock", "owner1", time.Minute))
+	assert.Equal(t, cache.ErrLockFailure, bm.TryLock("lock", "owner2", time.Minute))
+
+	assert.IsType(t, &gocache.Cache{}, bm.(*cache.TaggedInstrumented).Unwrap())
+
+	// metrics per adapter and operation
+	assert.Equal(t, float64(1), counter(t, reg, "cache_requests_total", "put", "ok"))
+	assert.Equal(t, float64(2), counter(t, reg, "cache_requests_total", "get", "ok"))
+	assert.Equal(t, float64(1), counter(t, reg, "cache_hits_total", "get"))
+	assert.Equal(t, float64(1), counter(t, reg, "cache_misses_total", "get"))
+	assert.Equal(t, float64(1), counter(t, reg, "cache_hits_total", "get_multi"))
+	assert.Equal(t, float64(2), counter(t, reg, "cache_misses_total", "get_multi"))
+	assert.Equal(t, float64(1), counter(t, reg, "cache_misses_total", "try_lock"))
+
+	// spans of the store view are children of the span in ctx
+	tracer := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(recorder)).Tracer("test")
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	s := cache.AsStore(bm)
+	_, found, err := s.Get(ctx, "key")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, cache.ErrNotFound, s.Expire(ctx, "missing", time.Minute))
+	parent.End()
+
+	spans := recorder.Completed()
+	last := spans[len(spans)-2]
+	assert.Equal(t, "cache.expire", last.Name())
+	assert.Equal(t, parent.SpanContext().SpanID, last.ParentSpanID())
+	assert.Equal(t, "goCache", last.Attributes()["cache.adapter"].AsString())
+	// keys are counted but not recorded by default
+	assert.Equal(t, int64(1), last.Attributes()["cache.keys"].AsInt64())
+	_, ok := last.Attributes()["cache.key"]
+	assert.False(t, ok)
+	assert.Equal(t, codes.Unset, last.StatusCode())
+
+	// every operation is slower than 1ns
+	logger.mutex.Lock()
+	assert.NotEmpty(t, logger.lines)
+	assert.Contains(t, logger.lines[0], "cache: slow put key of adapter goCache")
+	assert.Contains(t, strings.Join(logger.lines, "\n"), "cache: slow get_multi key and 2 more keys of adapter goCache")
+	logger.mutex.Unlock()
+
+	// collectors are shared by adapters of the same registerer
+	_, err = cache.NewCache(cache.TypeGoCache, cache.Config{
+		Instrument: &cache.InstrumentConfig{Metrics: true, Registerer: reg},
+	})
+	assert.Nil(t, err)
+}
+
+func TestInstrumentError(t *testing.T) {
+	recorder := &oteltest.StandardSpanRecorder{}
+	bm, err := cache.Instrument("goCache", &errCache{}, cache.InstrumentConfig{
+		Tracing:        true,
+		TraceKeys:      true,
+		TracerProvider: oteltest.NewTracerProvider(oteltest.WithSpanRecorder(recorder)),
+	})
+	assert.Nil(t, err)
+
+	assert.NotNil(t, bm.Put("key", "value", time.Minute))
+	// an adapter without tags is not a Tagger when instrumented
+	_, ok := bm.(cache.Tagger)
+	assert.False(t, ok)
+	_, err = cache.Namespace(bm, "ns")
+	assert.Equal(t, cache.ErrTagNotSupported, err)
+	spans := recorder.Completed()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].StatusCode())
+	assert.Equal(t, "put failed", spans[0].StatusMessage())
+	assert.Equal(t, "key", spans[0].Attributes()["cache.key"].AsString())
+}
+
+// errCache fail every put.
+type errCache struct {
+	cache.Cache
+}
+
+func (c *errCache) Put(key string, val interface{}, timeout time.Duration) error {
+	return fmt.Errorf("put failed")
+}
+
+func TestInstrumentClose(t *testing.T) {
+	closer := &closeCache{}
+	bm, err := cache.Instrument("goCache", closer, cache.InstrumentConfig{})
+	assert.Nil(t, err)
+	assert.Nil(t, bm.(io.Closer).Close())
+	assert.True(t, closer.closed)
+
+	// adapters without Close are left alone
+	bm, err = cache.Instrument("goCache", &errCache{}, cache.InstrumentConfig{})
+	assert.Nil(t, err)
+	assert.Nil(t, bm.(io.Closer).Close())
+}
+
+// closeCache record Close.
+type closeCache struct {
+	cache.Cache
+	closed bool
+}
+
+func (c *closeCache) Close() error {
+	c.closed = true
+	return nil
+}
+
+// counter return the value of counter name with label values, adapter label is goCache.
+func counter(t *testing.T, reg *prometheus.Registry, name string, labels ...string) float64 {
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather metrics error, err:%v", err)
+	}
+	values := append([]string{"goCache"}, labels...)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			match := true
+			for i, pair := range metric.GetLabel() {
+				// labels are sorted by name: adapter, operation, result
+				if i >= len(values) || pair.GetValue() != values[i] {
+					match = false
+				}
+			}
+			if match && len(metric.GetLabel()) == len(values) {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	t.Fatalf("metric %s %v not found", name, labels)
+	return 0
+}
@@ -0,0 +1,246 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// entry header: flags(1 byte) + fresh until unix nano(8 bytes)
+	entryHeaderSize = 9
+	// entry flag of a cached miss
+	entryFlagMissing = 1
+
+	defaultLockTimeout = 10 * time.Second
+	defaultLockWait    = 50 * time.Millisecond
+	defaultLoadTimeout = 30 * time.Second
+)
+
+// LoaderConfig - read-through loader config
+type LoaderConfig struct {
+	// serve values for StaleTTL after ttl elapsed while refreshing in background
+	StaleTTL time.Duration `json:"stale_ttl"`
+	// cache misses reported by the load func for NegativeTTL, 0 disable
+	NegativeTTL time.Duration `json:"negative_ttl"`
+	// timeout of a load, which is not canceled with the caller's ctx
+	// since the result is shared by every caller of the key, default 30s
+	LoadTimeout time.Duration `json:"load_timeout"`
+
+	// take a distributed lock through Cache.TryLock before loading,
+	// so only one process loads a key at a time
+	DistributedLock bool `json:"distributed_lock"`
+	// lock expiration, default 10s
+	LockTimeout time.Duration `json:"lock_timeout"`
+	// interval to poll the cache while another process holds the lock, default 50ms
+	LockWait time.Duration `json:"lock_wait"`
+
+	// called if a loaded value can not be cached, the value is returned
+	// anyway so a cache outage does not fail the reads
+	OnPutError func(key string, err error) `json:"-"`
+}
+
+// LoadFunc load the value of key from the source of truth,
+// it returns ErrNotFound if the value does not exist.
+type LoadFunc[T any] func(ctx context.Context, key string) (T, error)
+
+// Loader is a read-through wrapper over a Cache.
+// Concurrent loads of a key are deduplicated in-process, and
+// optionally across processes with a distributed lock.
+type Loader[T any] struct {
+	c      Cache
+	store  Store
+	codec  Codec
+	config LoaderConfig
+	group  singleflight.Group
+}
+
+// NewLoader create a read-through loader over c using the codec registered by codecName.
+func NewLoader[T any](c Cache, codecName string, config LoaderConfig) (*Loader[T], error) {
+	codec, err := GetCodec(codecName)
+	if err != nil {
+		return nil, err
+	}
+	if config.LockTimeout <= 0 {
+		config.LockTimeout = defaultLockTimeout
+	}
+	if config.LockWait <= 0 {
+		config.LockWait = defaultLockWait
+	}
+	if config.LoadTimeout <= 0 {
+		config.LoadTimeout = defaultLoadTimeout
+	}
+	return &Loader[T]{c: c, store: AsStore(c), codec: codec, config: config}, nil
+}
+
+// entry - cached value with its soft expiration
+type entry[T any] struct {
+	value      T
+	missing    bool
+	freshUntil time.Time
+}
+
+// GetOrLoad get cached value by key, call load on a miss and cache its result for ttl.
+// A stale value is returned while it is refreshed in background,
+// a cached miss is reported as ErrNotFound.
+func (l *Loader[T]) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load LoadFunc[T]) (val T, err error) {
+	e, found, err := l.get(ctx, key)
+	if err != nil {
+		return val, err
+	}
+	if found {
+		if !time.Now().After(e.freshUntil) {
+			return e.result()
+		}
+		if l.config.StaleTTL > 0 {
+			l.group.DoChan("refresh:"+key, func() (interface{}, error) {
+				return l.detached(ctx, key, ttl, load, true)
+			})
+			return e.result()
+		}
+	}
+
+	ch := l.group.DoChan(key, func() (interface{}, error) {
+		return l.detached(ctx, key, ttl, load, false)
+	})
+	select {
+	case <-ctx.Done():
+		return val, ctx.Err()
+	case r := <-ch:
+		if r.Err != nil {
+			return val, r.Err
+		}
+		return r.Val.(*entry[T]).result()
+	}
+}
+
+// detached load key under a ctx which keeps the values of ctx but not its
+// cancellation, a caller gone does not fail the load of the others.
+func (l *Loader[T]) detached(ctx context.Context, key string, ttl time.Duration, load LoadFunc[T], refresh bool) (*entry[T], error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), l.config.LoadTimeout)
+	defer cancel()
+	return l.load(ctx, key, ttl, load, refresh)
+}
+
+// result return the value of entry, or ErrNotFound for a cached miss.
+func (e *entry[T]) result() (val T, err error) {
+	if e.missing {
+		return val, ErrNotFound
+	}
+	return e.value, nil
+}
+
+// load call load and cache the result, refresh is true if a stale value is cached.
+func (l *Loader[T]) load(ctx context.Context, key string, ttl time.Duration, load LoadFunc[T], refresh bool) (*entry[T], error) {
+	if l.config.DistributedLock {
+		lockKey, token := key+":lock", uuid.New().String()
+		for {
+			err := l.c.TryLock(lockKey, token, l.config.LockTimeout)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, ErrLockFailure) {
+				return nil, err
+			}
+			if refresh {
+				// someone else is refreshing
+				return nil, nil
+			}
+
+			// wait for the lock holder to fill the cache
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(l.config.LockWait):
+			}
+			if e, found, err := l.get(ctx, key); err != nil || found {
+				return e, err
+			}
+		}
+		defer func() {
+			_ = l.c.UnLock(lockKey, token)
+		}()
+
+		// the cache may have been filled before the lock was taken
+		if e, found, err := l.get(ctx, key); err != nil || (found && !refresh && !time.Now().After(e.freshUntil)) {
+			return e, err
+		}
+	}
+
+	e := &entry[T]{}
+	v, err := load(ctx, key)
+	switch {
+	case err == nil:
+		e.value = v
+		e.freshUntil = time.Now().Add(ttl)
+		l.put(ctx, key, e, ttl+l.config.StaleTTL)
+		return e, nil
+	case errors.Is(err, ErrNotFound) && l.config.NegativeTTL > 0:
+		e.missing = true
+		e.freshUntil = time.Now().Add(l.config.NegativeTTL)
+		l.put(ctx, key, e, l.config.NegativeTTL)
+		return e, nil
+	}
+	return nil, err
+}
+
+// get get and decode the cached entry of key.
+func (l *Loader[T]) get(ctx context.Context, key string) (*entry[T], bool, error) {
+	v, found, err := l.store.Get(ctx, key)
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	var data []byte
+	switch raw := v.(type) {
+	case []byte:
+		data = raw
+	case string:
+		data = []byte(raw)
+	default:
+		return nil, false, fmt.Errorf("cache: unexpected raw value type %T of key %s", v, key)
+	}
+	if len(data) < entryHeaderSize {
+		return nil, false, fmt.Errorf("cache: invalid entry of key %s", key)
+	}
+
+	e := &entry[T]{
+		missing:    data[0]&entryFlagMissing != 0,
+		freshUntil: time.Unix(0, int64(binary.BigEndian.Uint64(data[1:entryHeaderSize]))),
+	}
+	if !e.missing {
+		if err := l.codec.Unmarshal(data[entryHeaderSize:], &e.value); err != nil {
+			return nil, false, fmt.Errorf("cache: decode key %s err:%v", key, err)
+		}
+	}
+	return e, true, nil
+}
+
+// put cache entry of key for timeout, errors are reported to OnPutError.
+func (l *Loader[T]) put(ctx context.Context, key string, e *entry[T], timeout time.Duration) {
+	if err := l.encode(ctx, key, e, timeout); err != nil && l.config.OnPutError != nil {
+		l.config.OnPutError(key, err)
+	}
+}
+
+// encode encode and cache entry of key for timeout.
+func (l *Loader[T]) encode(ctx context.Context, key string, e *entry[T], timeout time.Duration) error {
+	header := make([]byte, entryHeaderSize)
+	binary.BigEndian.PutUint64(header[1:], uint64(e.freshUntil.UnixNano()))
+	data := header
+	if e.missing {
+		header[0] |= entryFlagMissing
+	} else {
+		payload, err := l.codec.Marshal(e.value)
+		if err != nil {
+			return fmt.Errorf("cache: encode key %s err:%v", key, err)
+		}
+		data = append(header, payload...)
+	}
+	return l.store.Put(ctx, key, data, timeout)
+}
@@ -0,0 +1,230 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dbunion/com/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoaderSingleflight(t *testing.T) {
+	bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{})
+	if err != nil {
+		t.Fatalf("create cache error, err:%v", err)
+	}
+	loader, err := cache.NewLoader[user](bm, cache.CodecJSON, cache.LoaderConfig{})
+	if err != nil {
+		t.Fatalf("create loader error, err:%v", err)
+	}
+
+	var calls int32
+	load := func(ctx context.Context, key string) (user, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return user{Name: key}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := loader.GetOrLoad(context.Background(), "user:1", time.Minute, load)
+			assert.Nil(t, err)
+			assert.Equal(t, user{Name: "user:1"}, v)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// served from cache
+	v, err := loader.GetOrLoad(context.Background(), "user:1", time.Minute, load)
+	assert.Nil(t, err)
+	assert.Equal(t, user{Name: "user:1"}, v)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// load errors are not cached
+	loadErr := errors.New("database down")
+	_, err = loader.GetOrLoad(context.Background(), "user:2", time.Minute, func(ctx context.Context, key string) (user, error) {
+		return user{}, loadErr
+	})
+	assert.Equal(t, loadErr, err)
+	assert.False(t, bm.IsExist("user:2"))
+}
+
+func TestLoaderCallerCanceled(t *testing.T) {
+	bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{})
+	if err != nil {
+		t.Fatalf("create cache error, err:%v", err)
+	}
+	loader, err := cache.NewLoader[user](bm, cache.CodecJSON, cache.LoaderConfig{})
+	if err != nil {
+		t.Fatalf("create loader error, err:%v", err)
+	}
+
+	started := make(chan struct{})
+	load := func(ctx context.Context, key string) (user, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			return user{}, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+		return user{Name: key}, nil
+	}
+
+	// the first caller gives up, the load goes on for the second one
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := loader.GetOrLoad(ctx, "user:1", time.Minute, load)
+		first <- err
+	}()
+	<-started
+
+	second := make(chan error, 1)
+	go func() {
+		v, err := loader.GetOrLoad(context.Background(), "user:1", time.Minute, load)
+		assert.Equal(t, user{Name: "user:1"}, v)
+		second <- err
+	}()
+	cancel()
+
+	assert.Equal(t, context.Canceled, <-first)
+	assert.Nil(t, <-second)
+	assert.True(t, bm.IsExist("user:1"))
+}
+
+func TestLoaderStaleWhileRevalidate(t *testing.T) {
+	bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{})
+	if err != nil {
+		t.Fatalf("create cache error, err:%v", err)
+	}
+	loader, err := cache.NewLoader[int](bm, cache.CodecGob, cache.LoaderConfig{StaleTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("create loader error, err:%v", err)
+	}
+
+	var calls int32
+	load := func(ctx context.Context, key string) (int, error) {
+		return int(atomic.AddInt32(&calls, 1)), nil
+	}
+
+	ctx := context.Background()
+	v, err := loader.GetOrLoad(ctx, "counter", 50*time.Millisecond, load)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	// the stale value is served while refreshed in background
+	time.Sleep(100 * time.Millisecond)
+	v, err = loader.GetOrLoad(ctx, "counter", 50*time.Millisecond, load)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+	assert.Eventually(t, func() bool {
+		v, err := loader.GetOrLoad(ctx, "counter", time.Minute, load)
+		return err == nil && v == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestLoaderNegativeCache(t *testing.T) {
+	bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{})
+	if err != nil {
+		t.Fatalf("create cache error, err:%v", err)
+	}
+	loader, err := cache.NewLoader[user](bm, cache.CodecJSON, cache.LoaderConfig{NegativeTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("create loader error, err:%v", err)
+	}
+
+	var calls int32
+	load := func(ctx context.Context, key string) (user, error) {
+		atomic.AddInt32(&calls, 1)
+		return user{}, cache.ErrNotFound
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err = loader.GetOrLoad(context.Background(), "user:3", time.Minute, load)
+		assert.Equal(t, cache.ErrNotFound, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestLoaderDistributedLock(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	defer mr.Close()
+
+	cfg := cache.Config{Server: mr.Host(), Port: int64(mr.Server().Addr().Port)}
+	var calls int32
+	load := func(ctx context.Context, key string) (user, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		return user{Name: key}, nil
+	}
+
+	// separate loaders act as separate processes
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		bm, err := cache.NewCache(cache.TypeRedisCache, cfg)
+		if err != nil {
+			t.Fatalf("create cache error, err:%v", err)
+		}
+		loader, err := cache.NewLoader[user](bm, cache.CodecMsgpack, cache.LoaderConfig{
+			DistributedLock: true,
+			LockWait:        10 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("create loader error, err:%v", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := loader.GetOrLoad(context.Background(), "user:4", time.Minute, load)
+			assert.Nil(t, err)
+			assert.Equal(t, user{Name: "user:4"}, v)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.False(t, mr.Exists("user:4:lock"))
+}
+
+// putErrCache fail every put of a cache.
+type putErrCache struct {
+	cache.Cache
+}
+
+func (c *putErrCache) Put(key string, val interface{}, timeout time.Duration) error {
+	return errors.New("cache down")
+}
+
+func TestLoaderPutError(t *testing.T) {
+	bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{})
+	if err != nil {
+		t.Fatalf("create cache error, err:%v", err)
+	}
+	var putErrs []string
+	loader, err := cache.NewLoader[user](&putErrCache{Cache: bm}, cache.CodecJSON, cache.LoaderConfig{
+		OnPutError: func(key string, err error) { putErrs = append(putErrs, key+": "+err.Error()) },
+	})
+	if err != nil {
+		t.Fatalf("create loader error, err:%v", err)
+	}
+
+	// the loaded value is returned and the put error reported
+	v, err := loader.GetOrLoad(context.Background(), "user:1", time.Minute, func(ctx context.Context, key string) (user, error) {
+		return user{Name: key}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, user{Name: "user:1"}, v)
+	assert.Equal(t, []string{"user:1: cache down"}, putErrs)
+}
@@ -1,6 +1,7 @@
 package memcache
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -11,9 +12,8 @@ import (
 
 // Cache - Memcache adapter.
 type Cache struct {
-	conn   *memcache.Client
-	server string
-	port   int64
+	conn  *memcache.Client
+	nodes []cache.Node
 }
 
 // NewMemCache create new memcache adapter.
@@ -21,6 +21,15 @@ func NewMemCache() cache.Cache {
 	return &Cache{}
 }
 
+// expiration convert timeout to memcache expiration seconds,
+// a positive timeout below one second is rounded up since 0 never expires.
+func expiration(timeout time.Duration) int32 {
+	if timeout > 0 && timeout < time.Second {
+		return 1
+	}
+	return int32(timeout / time.Second)
+}
+
 // Get get value from memcache.
 func (rc *Cache) Get(key string) interface{} {
 	if rc.conn == nil {
@@ -29,31 +38,22 @@ func (rc *Cache) Get(key string) interface{} {
 		}
 	}
 	if item, err := rc.conn.Get(key); err == nil {
-		return item.Value
+		if v, found := rc.untag(item.Value); found {
+			return v
+		}
 	}
 	return nil
 }
 
 // GetMulti get value from memcache.
 func (rc *Cache) GetMulti(keys []string) []interface{} {
-	size := len(keys)
-	var rv []interface{}
-	if rc.conn == nil {
-		if err := rc.connectInit(); err != nil {
-			for i := 0; i < size; i++ {
-				rv = append(rv, err)
-			}
-			return rv
-		}
-	}
-	mv, err := rc.conn.GetMulti(keys)
+	values, err := rc.Store().GetMulti(context.Background(), keys)
 	if err == nil {
-		for _, v := range mv {
-			rv = append(rv, v.Value)
-		}
-		return rv
+		return values
 	}
-	for i := 0; i < size; i++ {
+
+	rv := make([]interface{}, 0, len(keys))
+	for range keys {
 		rv = append(rv, err)
 	}
 	return rv
@@ -66,7 +66,7 @@ func (rc *Cache) Put(key string, val interface{}, timeout time.Duration) error {
 			return err
 		}
 	}
-	item := memcache.Item{Key: key, Expiration: int32(timeout / time.Second)}
+	item := memcache.Item{Key: key, Expiration: expiration(timeout)}
 	if v, ok := val.([]byte); ok {
 		item.Value = v
 	} else if str, ok := val.(string); ok {
@@ -79,54 +79,29 @@ func (rc *Cache) Put(key string, val interface{}, timeout time.Duration) error {
 
 // Delete delete value in memcache.
 func (rc *Cache) Delete(key string) error {
-	if rc.conn == nil {
-		if err := rc.connectInit(); err != nil {
-			return err
-		}
-	}
-	return rc.conn.Delete(key)
+	return rc.Store().Delete(context.Background(), key)
 }
 
-// Incr increase counter.
+// Incr increase counter, a missing counter starts from zero.
 func (rc *Cache) Incr(key string) error {
-	if rc.conn == nil {
-		if err := rc.connectInit(); err != nil {
-			return err
-		}
-	}
-	_, err := rc.conn.Increment(key, 1)
+	_, err := rc.Store().IncrBy(context.Background(), key, 1)
 	return err
 }
 
 // Decr decrease counter.
 func (rc *Cache) Decr(key string) error {
-	if rc.conn == nil {
-		if err := rc.connectInit(); err != nil {
-			return err
-		}
-	}
-	_, err := rc.conn.Decrement(key, 1)
+	_, err := rc.Store().IncrBy(context.Background(), key, -1)
 	return err
 }
 
 // IncrBy increase counter.
 func (rc *Cache) IncrBy(key string) (interface{}, error) {
-	if rc.conn == nil {
-		if err := rc.connectInit(); err != nil {
-			return nil, err
-		}
-	}
-	return rc.conn.Increment(key, 1)
+	return rc.Store().IncrBy(context.Background(), key, 1)
 }
 
 // DecrBy decrease counter.
 func (rc *Cache) DecrBy(key string) (interface{}, error) {
-	if rc.conn == nil {
-		if err := rc.connectInit(); err != nil {
-			return nil, err
-		}
-	}
-	return rc.conn.Decrement(key, 1)
+	return rc.Store().IncrBy(context.Background(), key, -1)
 }
 
 // IsExist check value exists in memcache.
@@ -136,8 +111,12 @@ func (rc *Cache) IsExist(key string) bool {
 			return false
 		}
 	}
-	_, err := rc.conn.Get(key)
-	return !(err != nil)
+	item, err := rc.conn.Get(key)
+	if err != nil {
+		return false
+	}
+	_, found := rc.untag(item.Value)
+	return found
 }
 
 // ClearAll clear all cached in memcache.
@@ -150,32 +129,78 @@ func (rc *Cache) ClearAll() error {
 	return rc.conn.FlushAll()
 }
 
-// TryLock ...
+// TryLock add the lock item, it fails if the key already exists.
 func (rc *Cache) TryLock(key string, val interface{}, timeout time.Duration) error {
-	return nil
+	if rc.conn == nil {
+		if err := rc.connectInit(); err != nil {
+			return err
+		}
+	}
+	err := rc.conn.Add(&memcache.Item{Key: key, Value: []byte(fmt.Sprintf("%v", val)), Expiration: expiration(timeout)})
+	if err == memcache.ErrNotStored {
+		return cache.ErrLockFailure
+	}
+	return err
 }
 
-// UnLock ...
+// UnLock release the lock only if it is still held by val.
+// The lock is expired by compare-and-swap, so a lock taken
+// by someone else in the meantime is kept.
 func (rc *Cache) UnLock(key string, val interface{}) error {
-	return nil
+	if rc.conn == nil {
+		if err := rc.connectInit(); err != nil {
+			return err
+		}
+	}
+	item, err := rc.conn.Get(key)
+	if err == memcache.ErrCacheMiss {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if string(item.Value) != fmt.Sprintf("%v", val) {
+		return nil
+	}
+
+	// a negative expiration expire the item immediately
+	item.Expiration = -1
+	err = rc.conn.CompareAndSwap(item)
+	if err == memcache.ErrCASConflict || err == memcache.ErrCacheMiss || err == memcache.ErrNotStored {
+		return nil
+	}
+	return err
 }
 
-// Set put cache to redis.
+// Set put value to memcache without expiration.
 func (rc *Cache) Set(key string, val interface{}) (bool, error) {
-	return false, nil
+	if err := rc.Put(key, val, 0); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-// Expire key.
+// Expire reset expiration of key, missing key is ignored.
 func (rc *Cache) Expire(key string, timeout time.Duration) error {
-	return nil
+	if timeout < 0 {
+		return nil
+	}
+	err := rc.Store().Expire(context.Background(), key, timeout)
+	if err == cache.ErrNotFound {
+		return nil
+	}
+	return err
 }
 
 // StartAndGC start memcache adapter.
-// config string is like {"conn":"connection info"}.
+// keys are spread over config.Nodes by consistent hashing,
+// a single Server and Port is used if no node is configured.
 // if connecting error, return.
 func (rc *Cache) StartAndGC(config cache.Config) error {
-	rc.server = config.Server
-	rc.port = config.Port
+	rc.nodes = config.Nodes
+	if len(rc.nodes) == 0 {
+		rc.nodes = []cache.Node{{Addr: fmt.Sprintf("%s:%d", config.Server, config.Port)}}
+	}
 	if rc.conn == nil {
 		if err := rc.connectInit(); err != nil {
 			return err
@@ -189,7 +214,11 @@ func (rc *Cache) StartAndGC(config cache.Config) error {
 
 // connect to memcached and keep the connection.
 func (rc *Cache) connectInit() error {
-	rc.conn = memcache.New(fmt.Sprintf("%s:%d", rc.server, rc.port))
+	r, err := newRing(rc.nodes)
+	if err != nil {
+		return err
+	}
+	rc.conn = memcache.NewFromSelector(r)
 	return nil
 }
 
@@ -0,0 +1,94 @@
+package memcache
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/dbunion/com/cache"
+)
+
+// ketama points of a node per unit of weight
+const pointsPerWeight = 160
+
+// point - position of a node on the ring
+type point struct {
+	hash uint32
+	addr net.Addr
+}
+
+// ring is a ketama consistent-hash server selector, adding or removing
+// a node only remaps the keys of its own points.
+type ring struct {
+	points []point
+	addrs  []net.Addr
+}
+
+// newRing create ring of nodes.
+func newRing(nodes []cache.Node) (*ring, error) {
+	if len(nodes) == 0 {
+		return nil, errors.New("server config is empty")
+	}
+
+	r := &ring{}
+	seen := make(map[string]bool)
+	for _, node := range nodes {
+		if seen[node.Addr] {
+			return nil, fmt.Errorf("duplicate memcache node %s", node.Addr)
+		}
+		seen[node.Addr] = true
+
+		addr, err := net.ResolveTCPAddr("tcp", node.Addr)
+		if err != nil {
+			return nil, err
+		}
+		r.addrs = append(r.addrs, addr)
+
+		weight := node.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		// each md5 digest of "addr-i" gives 4 points, hashing the configured
+		// address keeps the ring stable when the host resolves elsewhere
+		for i := 0; i < int(weight)*pointsPerWeight/4; i++ {
+			digest := md5.Sum([]byte(fmt.Sprintf("%s-%d", node.Addr, i)))
+			for j := 0; j < 4; j++ {
+				r.points = append(r.points, point{hash: binary.LittleEndian.Uint32(digest[j*4:]), addr: addr})
+			}
+		}
+	}
+	sort.SliceStable(r.points, func(i, j int) bool {
+		return r.points[i].hash < r.points[j].hash
+	})
+	return r, nil
+}
+
+// PickServer return the node of the first point after the hash of key.
+func (r *ring) PickServer(key string) (net.Addr, error) {
+	if len(r.points) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+	digest := md5.Sum([]byte(key))
+	hash := binary.LittleEndian.Uint32(digest[:4])
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= hash
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].addr, nil
+}
+
+// Each call f on every node.
+func (r *ring) Each(f func(net.Addr) error) error {
+	for _, addr := range r.addrs {
+		if err := f(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
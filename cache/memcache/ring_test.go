@@ -0,0 +1,84 @@
+package memcache
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/cache"
+	"github.com/dbunion/com/cache/cachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRing(t *testing.T) {
+	_, err := newRing(nil)
+	assert.NotNil(t, err)
+	_, err = newRing([]cache.Node{{Addr: "127.0.0.1:11211"}, {Addr: "127.0.0.1:11211"}})
+	assert.NotNil(t, err)
+
+	nodes := []cache.Node{{Addr: "127.0.0.1:11211"}, {Addr: "127.0.0.1:11212"}, {Addr: "127.0.0.1:11213", Weight: 2}}
+	r, err := newRing(nodes)
+	assert.Nil(t, err)
+
+	const n = 10000
+	picks := make(map[string]string, n)
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key%d", i)
+		addr, err := r.PickServer(key)
+		assert.Nil(t, err)
+		picks[key] = addr.String()
+		counts[addr.String()]++
+	}
+
+	// keys are spread by weight, 1:1:2
+	assert.InDelta(t, n/4, counts["127.0.0.1:11211"], n/20)
+	assert.InDelta(t, n/4, counts["127.0.0.1:11212"], n/20)
+	assert.InDelta(t, n/2, counts["127.0.0.1:11213"], n/20)
+
+	// removing a node only remap its own keys
+	r, err = newRing(nodes[:2])
+	assert.Nil(t, err)
+	for key, prev := range picks {
+		addr, err := r.PickServer(key)
+		assert.Nil(t, err)
+		if prev != "127.0.0.1:11213" {
+			assert.Equal(t, prev, addr.String())
+		}
+	}
+
+	var each []string
+	assert.Nil(t, r.Each(func(addr net.Addr) error {
+		each = append(each, addr.String())
+		return nil
+	}))
+	assert.Equal(t, []string{"127.0.0.1:11211", "127.0.0.1:11212"}, each)
+}
+
+func TestMemcachedRing(t *testing.T) {
+	servers := []*fakeServer{newFakeServer(t), newFakeServer(t)}
+	var nodes []cache.Node
+	for _, s := range servers {
+		defer s.Close()
+		nodes = append(nodes, cache.Node{Addr: s.Addr()})
+	}
+
+	bm, err := cache.NewCache(cache.TypeMemoryCache, cache.Config{Nodes: nodes})
+	if err != nil {
+		t.Fatalf("create new cache error, err:%v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, bm.Put(fmt.Sprintf("key%d", i), "value", 10*time.Second))
+	}
+	assert.NotZero(t, servers[0].Len())
+	assert.NotZero(t, servers[1].Len())
+	assert.Equal(t, 100, servers[0].Len()+servers[1].Len())
+
+	cachetest.Run(t, bm, func(d time.Duration) {
+		for _, s := range servers {
+			s.FastForward(d)
+		}
+	})
+}
@@ -0,0 +1,233 @@
+package memcache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/cache"
+)
+
+// fakeItem - item stored by fakeServer
+type fakeItem struct {
+	value    []byte
+	flags    uint32
+	cas      uint64
+	expireAt time.Time
+}
+
+// fakeServer is an in-process memcached speaking the text protocol
+// commands used by gomemcache, its clock only move by FastForward.
+type fakeServer struct {
+	l     net.Listener
+	mutex sync.Mutex
+	items map[string]*fakeItem
+	now   time.Time
+	cas   uint64
+	wg    sync.WaitGroup
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("start memcached error, err:%v", err)
+	}
+	fs := &fakeServer{l: l, items: make(map[string]*fakeItem), now: time.Now()}
+	fs.wg.Add(1)
+	go fs.serve()
+	return fs
+}
+
+func (fs *fakeServer) Addr() string {
+	return fs.l.Addr().String()
+}
+
+// Config return the cache config of a single node on the server.
+func (fs *fakeServer) Config() cache.Config {
+	host, port, _ := net.SplitHostPort(fs.Addr())
+	p, _ := strconv.ParseInt(port, 10, 64)
+	return cache.Config{Server: host, Port: p}
+}
+
+func (fs *fakeServer) Close() {
+	_ = fs.l.Close()
+	fs.wg.Wait()
+}
+
+// FastForward move the clock of the server.
+func (fs *fakeServer) FastForward(d time.Duration) {
+	fs.mutex.Lock()
+	fs.now = fs.now.Add(d)
+	fs.mutex.Unlock()
+}
+
+// Len return the number of live items.
+func (fs *fakeServer) Len() int {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	n := 0
+	for key := range fs.items {
+		if fs.item(key) != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func (fs *fakeServer) serve() {
+	defer fs.wg.Done()
+	for {
+		conn, err := fs.l.Accept()
+		if err != nil {
+			return
+		}
+		go fs.handle(conn)
+	}
+}
+
+// item return the live item of key, must be called with mutex held.
+func (fs *fakeServer) item(key string) *fakeItem {
+	it, ok := fs.items[key]
+	if !ok {
+		return nil
+	}
+	if !it.expireAt.IsZero() && !fs.now.Before(it.expireAt) {
+		delete(fs.items, key)
+		return nil
+	}
+	return it
+}
+
+// expireAt convert memcache expiration seconds to a deadline.
+func (fs *fakeServer) expireAt(exptime int64) time.Time {
+	switch {
+	case exptime == 0:
+		return time.Time{}
+	case exptime < 0:
+		return fs.now
+	}
+	return fs.now.Add(time.Duration(exptime) * time.Second)
+}
+
+func (fs *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+
+		var data []byte
+		switch args[0] {
+		case "set", "add", "replace", "cas":
+			if len(args) < 5 {
+				fmt.Fprintf(rw, "ERROR\r\n")
+				if err := rw.Flush(); err != nil {
+					return
+				}
+				continue
+			}
+			size, _ := strconv.Atoi(args[4])
+			data = make([]byte, size+2)
+			if _, err := io.ReadFull(rw, data); err != nil {
+				return
+			}
+			data = data[:size]
+		}
+
+		fs.mutex.Lock()
+		fs.dispatch(rw, args, data)
+		fs.mutex.Unlock()
+		if err := rw.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// dispatch run a command, must be called with mutex held.
+func (fs *fakeServer) dispatch(w io.Writer, args []string, data []byte) {
+	switch args[0] {
+	case "version":
+		fmt.Fprintf(w, "VERSION 1.6.0\r\n")
+	case "get", "gets":
+		for _, key := range args[1:] {
+			if it := fs.item(key); it != nil {
+				fmt.Fprintf(w, "VALUE %s %d %d %d\r\n%s\r\n", key, it.flags, len(it.value), it.cas, it.value)
+			}
+		}
+		fmt.Fprintf(w, "END\r\n")
+	case "set", "add", "replace", "cas":
+		key := args[1]
+		flags, _ := strconv.ParseUint(args[2], 10, 32)
+		exptime, _ := strconv.ParseInt(args[3], 10, 64)
+		it := fs.item(key)
+		switch {
+		case args[0] == "add" && it != nil, args[0] == "replace" && it == nil:
+			fmt.Fprintf(w, "NOT_STORED\r\n")
+			return
+		case args[0] == "cas" && it == nil:
+			fmt.Fprintf(w, "NOT_FOUND\r\n")
+			return
+		case args[0] == "cas" && args[5] != strconv.FormatUint(it.cas, 10):
+			fmt.Fprintf(w, "EXISTS\r\n")
+			return
+		}
+		fs.cas++
+		fs.items[key] = &fakeItem{value: data, flags: uint32(flags), cas: fs.cas, expireAt: fs.expireAt(exptime)}
+		fmt.Fprintf(w, "STORED\r\n")
+	case "delete":
+		if fs.item(args[1]) == nil {
+			fmt.Fprintf(w, "NOT_FOUND\r\n")
+			return
+		}
+		delete(fs.items, args[1])
+		fmt.Fprintf(w, "DELETED\r\n")
+	case "incr", "decr":
+		it := fs.item(args[1])
+		if it == nil {
+			fmt.Fprintf(w, "NOT_FOUND\r\n")
+			return
+		}
+		v, err := strconv.ParseUint(string(it.value), 10, 64)
+		if err != nil {
+			fmt.Fprintf(w, "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n")
+			return
+		}
+		delta, _ := strconv.ParseUint(args[2], 10, 64)
+		if args[0] == "incr" {
+			v += delta
+		} else if delta > v {
+			v = 0
+		} else {
+			v -= delta
+		}
+		fs.cas++
+		it.value, it.cas = []byte(strconv.FormatUint(v, 10)), fs.cas
+		fmt.Fprintf(w, "%d\r\n", v)
+	case "touch":
+		it := fs.item(args[1])
+		if it == nil {
+			fmt.Fprintf(w, "NOT_FOUND\r\n")
+			return
+		}
+		exptime, _ := strconv.ParseInt(args[2], 10, 64)
+		it.expireAt = fs.expireAt(exptime)
+		fmt.Fprintf(w, "TOUCHED\r\n")
+	case "flush_all":
+		fs.items = make(map[string]*fakeItem)
+		fmt.Fprintf(w, "OK\r\n")
+	default:
+		fmt.Fprintf(w, "ERROR\r\n")
+	}
+}
@@ -0,0 +1,162 @@
+package memcache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/dbunion/com/cache"
+)
+
+// Store is the context-aware view of memcache adapter.
+// The memcache client has no context support, so ctx is only
+// checked before each request is issued.
+type Store struct {
+	rc *Cache
+}
+
+// Store returns the context-aware view of memcache adapter.
+func (rc *Cache) Store() cache.Store {
+	return &Store{rc: rc}
+}
+
+// client check ctx and return the memcache client.
+func (s *Store) client(ctx context.Context) (*memcache.Client, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.rc.conn == nil {
+		if err := s.rc.connectInit(); err != nil {
+			return nil, err
+		}
+	}
+	return s.rc.conn, nil
+}
+
+// Get get value from memcache.
+func (s *Store) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	conn, err := s.client(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	item, err := conn.Get(key)
+	if err == memcache.ErrCacheMiss {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	v, found := s.rc.untag(item.Value)
+	return v, found, nil
+}
+
+// GetMulti get value from memcache.
+func (s *Store) GetMulti(ctx context.Context, keys []string) ([]interface{}, error) {
+	conn, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items, err := conn.GetMulti(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if item, ok := items[key]; ok {
+			if v, found := s.rc.untag(item.Value); found {
+				values[i] = v
+			}
+		}
+	}
+	return values, nil
+}
+
+// Put put value to memcache.
+func (s *Store) Put(ctx context.Context, key string, val interface{}, timeout time.Duration) error {
+	if _, err := s.client(ctx); err != nil {
+		return err
+	}
+	return s.rc.Put(key, val, timeout)
+}
+
+// Delete delete value in memcache.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	conn, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	if err := conn.Delete(key); err != nil && err != memcache.ErrCacheMiss {
+		return err
+	}
+	return nil
+}
+
+// IncrBy add delta to counter in memcache, a missing counter starts from zero.
+// memcache counters are unsigned, decreasing below zero yields zero.
+func (s *Store) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	conn, err := s.client(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		var v uint64
+		if delta < 0 {
+			v, err = conn.Decrement(key, uint64(-delta))
+		} else {
+			v, err = conn.Increment(key, uint64(delta))
+		}
+		if err == nil {
+			return int64(v), nil
+		}
+		if err != memcache.ErrCacheMiss {
+			return 0, err
+		}
+
+		initial := delta
+		if initial < 0 {
+			initial = 0
+		}
+		err = conn.Add(&memcache.Item{Key: key, Value: []byte(strconv.FormatInt(initial, 10))})
+		if err == nil {
+			return initial, nil
+		}
+		if err != memcache.ErrNotStored {
+			return 0, err
+		}
+		// lost the race against another writer, retry the counter
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Expire reset expiration of key in memcache.
+func (s *Store) Expire(ctx context.Context, key string, timeout time.Duration) error {
+	conn, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	err = conn.Touch(key, expiration(timeout))
+	if err == memcache.ErrCacheMiss {
+		return cache.ErrNotFound
+	}
+	return err
+}
+
+// IsExist check value exists in memcache.
+func (s *Store) IsExist(ctx context.Context, key string) (bool, error) {
+	_, found, err := s.Get(ctx, key)
+	return found, err
+}
+
+// ClearAll clear all cached in memcache.
+func (s *Store) ClearAll(ctx context.Context) error {
+	conn, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.FlushAll()
+}
@@ -0,0 +1,53 @@
+package memcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedStore(t *testing.T) {
+	fs := newFakeServer(t)
+	defer fs.Close()
+	s, err := cache.NewStore(cache.TypeMemoryCache, fs.Config())
+	if err != nil {
+		t.Fatalf("create new store error, err:%v", err)
+	}
+	assert.IsType(t, &Store{}, s)
+
+	ctx := context.Background()
+	assert.Nil(t, s.ClearAll(ctx))
+
+	v, found, err := s.Get(ctx, cacheKey)
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.Nil(t, v)
+
+	assert.Nil(t, s.Put(ctx, cacheKey, "author", 10*time.Second))
+	v, found, err = s.Get(ctx, cacheKey)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("author"), v)
+
+	vv, err := s.GetMulti(ctx, []string{cacheKey, cacheKey1})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]byte("author"), nil}, vv)
+
+	n, err := s.IncrBy(ctx, cacheKey1, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), n)
+	n, err = s.IncrBy(ctx, cacheKey1, -2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+
+	assert.Equal(t, cache.ErrNotFound, s.Expire(ctx, "missingKey", time.Second))
+	assert.Nil(t, s.Expire(ctx, cacheKey, time.Second))
+
+	assert.Nil(t, s.Delete(ctx, cacheKey1))
+	assert.Nil(t, s.Delete(ctx, cacheKey1))
+
+	assert.Nil(t, s.ClearAll(ctx))
+}
@@ -0,0 +1,68 @@
+package memcache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dbunion/com/cache"
+)
+
+// taggedMagic start values put with tags, followed by the json
+// generations of the tags, a newline and the value.
+var taggedMagic = []byte("\x00tagged\x00")
+
+// PutWithTags put value to memcache with the current generations of tags.
+func (rc *Cache) PutWithTags(key string, val interface{}, timeout time.Duration, tags ...string) error {
+	var data []byte
+	if v, ok := val.([]byte); ok {
+		data = v
+	} else if str, ok := val.(string); ok {
+		data = []byte(str)
+	} else {
+		return errors.New("val only support string and []byte")
+	}
+
+	generations, err := cache.TagGenerations(rc, tags)
+	if err != nil {
+		return err
+	}
+	header, err := json.Marshal(generations)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, len(taggedMagic)+len(header)+1+len(data))
+	buf = append(buf, taggedMagic...)
+	buf = append(buf, header...)
+	buf = append(buf, '\n')
+	buf = append(buf, data...)
+	return rc.Put(key, buf, timeout)
+}
+
+// InvalidateTag invalidate values put with tag by moving its generation.
+func (rc *Cache) InvalidateTag(tag string) error {
+	return cache.NextTagGeneration(rc, tag)
+}
+
+// untag return the value of an item, found is false if the tags
+// of the value were invalidated.
+func (rc *Cache) untag(value []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(value, taggedMagic) {
+		return value, true
+	}
+	rest := value[len(taggedMagic):]
+	i := bytes.IndexByte(rest, '\n')
+	if i < 0 {
+		return value, true
+	}
+	var generations map[string]int64
+	if err := json.Unmarshal(rest[:i], &generations); err != nil {
+		return value, true
+	}
+	if !cache.TagGenerationsValid(rc, generations) {
+		return nil, false
+	}
+	return rest[i+1:], true
+}
@@ -0,0 +1,36 @@
+package memcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	Name string
+	Age  int
+}
+
+func TestMemcachedTyped(t *testing.T) {
+	fs := newFakeServer(t)
+	defer fs.Close()
+	for _, codecName := range []string{cache.CodecJSON, cache.CodecGob} {
+		users, err := cache.NewTypedCache[user](cache.TypeMemoryCache, fs.Config(), codecName)
+		if err != nil {
+			t.Fatalf("create typed cache error, err:%v", err)
+		}
+
+		ctx := context.Background()
+		expect := user{Name: "author", Age: 18}
+		assert.Nil(t, users.Put(ctx, cacheKey, expect, 10*time.Second))
+
+		v, found, err := users.Get(ctx, cacheKey)
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, expect, v)
+		assert.Nil(t, users.Delete(ctx, cacheKey))
+	}
+}
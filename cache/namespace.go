@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Namespaced is a view of a cache with keys and tags prefixed by its name,
+// values put through it are tagged so ClearAll only removes them.
+type Namespaced struct {
+	c      Cache
+	t      Tagger
+	prefix string
+	tag    string
+}
+
+// Namespace return the view of c named name, c must implement Tagger.
+// counters and locks are prefixed too but are not removed by ClearAll.
+func Namespace(c Cache, name string) (*Namespaced, error) {
+	t, ok := c.(Tagger)
+	if !ok {
+		return nil, ErrTagNotSupported
+	}
+	return &Namespaced{c: c, t: t, prefix: name + ":", tag: "namespace:" + name}, nil
+}
+
+func (n *Namespaced) key(key string) string {
+	return n.prefix + key
+}
+
+// Get get cached value by key.
+func (n *Namespaced) Get(key string) interface{} {
+	return n.c.Get(n.key(key))
+}
+
+// GetMulti get cached values by keys.
+func (n *Namespaced) GetMulti(keys []string) []interface{} {
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, n.key(key))
+	}
+	return n.c.GetMulti(prefixed)
+}
+
+// Put put cached value with the namespace tag.
+func (n *Namespaced) Put(key string, val interface{}, timeout time.Duration) error {
+	return n.t.PutWithTags(n.key(key), val, timeout, n.tag)
+}
+
+// PutWithTags put cached value with the namespace tag and prefixed tags.
+func (n *Namespaced) PutWithTags(key string, val interface{}, timeout time.Duration, tags ...string) error {
+	prefixed := make([]string, 0, len(tags)+1)
+	prefixed = append(prefixed, n.tag)
+	for _, tag := range tags {
+		prefixed = append(prefixed, n.key(tag))
+	}
+	return n.t.PutWithTags(n.key(key), val, timeout, prefixed...)
+}
+
+// InvalidateTag delete cached values put with tag in the namespace.
+func (n *Namespaced) InvalidateTag(tag string) error {
+	return n.t.InvalidateTag(n.key(tag))
+}
+
+// Delete delete cached value by key.
+func (n *Namespaced) Delete(key string) error {
+	return n.c.Delete(n.key(key))
+}
+
+// Incr increase counter.
+func (n *Namespaced) Incr(key string) error {
+	return n.c.Incr(n.key(key))
+}
+
+// Decr decrease counter.
+func (n *Namespaced) Decr(key string) error {
+	return n.c.Decr(n.key(key))
+}
+
+// IncrBy increase counter and return value.
+func (n *Namespaced) IncrBy(key string) (interface{}, error) {
+	return n.c.IncrBy(n.key(key))
+}
+
+// IncrByDelta add delta to counter and return value.
+func (n *Namespaced) IncrByDelta(ctx context.Context, key string, delta int64) (int64, error) {
+	return AsStore(n.c).IncrBy(ctx, n.key(key), delta)
+}
+
+// DecrBy decrease counter and return value.
+func (n *Namespaced) DecrBy(key string) (interface{}, error) {
+	return n.c.DecrBy(n.key(key))
+}
+
+// TryLock lock key with timeout.
+func (n *Namespaced) TryLock(key string, val interface{}, timeout time.Duration) error {
+	return n.c.TryLock(n.key(key), val, timeout)
+}
+
+// UnLock unlock key.
+func (n *Namespaced) UnLock(key string, val interface{}) error {
+	return n.c.UnLock(n.key(key), val)
+}
+
+// Set put cached value with the namespace tag and without timeout.
+func (n *Namespaced) Set(key string, val interface{}) (bool, error) {
+	if err := n.Put(key, val, 0); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Expire expire key with timeout.
+func (n *Namespaced) Expire(key string, timeout time.Duration) error {
+	return n.c.Expire(n.key(key), timeout)
+}
+
+// IsExist check if cached value exists or not.
+func (n *Namespaced) IsExist(key string) bool {
+	return n.c.IsExist(n.key(key))
+}
+
+// ClearAll delete cached values put through the namespace.
+func (n *Namespaced) ClearAll() error {
+	return n.t.InvalidateTag(n.tag)
+}
+
+// StartAndGC start the underlying adapter.
+func (n *Namespaced) StartAndGC(config Config) error {
+	return n.c.StartAndGC(config)
+}
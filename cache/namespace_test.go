@@ -0,0 +1,34 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespace(t *testing.T) {
+	_, err := cache.Namespace(&errCache{}, "ns")
+	assert.Equal(t, cache.ErrTagNotSupported, err)
+	assert.Equal(t, cache.ErrTagNotSupported, cache.PutWithTags(&errCache{}, "key", "value", time.Minute, "tag"))
+	assert.Equal(t, cache.ErrTagNotSupported, cache.InvalidateTag(&errCache{}, "tag"))
+
+	// tags pass through the instrumentation
+	bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{Instrument: &cache.InstrumentConfig{}})
+	assert.Nil(t, err)
+	ns, err := cache.Namespace(bm, "ns")
+	assert.Nil(t, err)
+
+	assert.Nil(t, ns.Put("key", "value", time.Minute))
+	ok, err := ns.Set("set", "value")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Nil(t, ns.Incr("counter"))
+	assert.Equal(t, []interface{}{"value", "value", int64(1)}, ns.GetMulti([]string{"key", "set", "counter"}))
+	assert.Equal(t, "value", bm.Get("ns:key"))
+
+	// counters are not tagged
+	assert.Nil(t, ns.ClearAll())
+	assert.Equal(t, []interface{}{nil, nil, int64(1)}, ns.GetMulti([]string{"key", "set", "counter"}))
+}
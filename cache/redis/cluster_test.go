@@ -0,0 +1,232 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/dbunion/com/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCluster split the hash slots between two miniredis nodes.
+// Nodes reply MOVED for keys of slots they do not own, and ASK for
+// slots migrating from node 0 to node 1.
+type fakeCluster struct {
+	nodes     [2]*miniredis.Miniredis
+	mutex     sync.Mutex
+	owners    map[int]int
+	migrating map[int]bool
+	asking    map[*server.Peer]bool
+	redirects int
+}
+
+func newFakeCluster(t *testing.T) *fakeCluster {
+	fc := &fakeCluster{
+		owners:    make(map[int]int),
+		migrating: make(map[int]bool),
+		asking:    make(map[*server.Peer]bool),
+	}
+	for i := range fc.nodes {
+		mr, err := miniredis.Run()
+		if err != nil {
+			t.Fatalf("start miniredis error, err:%v", err)
+		}
+		fc.nodes[i] = mr
+	}
+	for i, mr := range fc.nodes {
+		i := i
+		mr.Server().SetPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+			return fc.hook(i, c, cmd, args)
+		})
+	}
+	return fc
+}
+
+func (fc *fakeCluster) Close() {
+	for _, mr := range fc.nodes {
+		mr.Close()
+	}
+}
+
+// owner return the node serving slot s.
+func (fc *fakeCluster) owner(s int) int {
+	if n, ok := fc.owners[s]; ok {
+		return n
+	}
+	if s < slotNumber/2 {
+		return 0
+	}
+	return 1
+}
+
+// keys return the key arguments of cmd.
+func keys(cmd string, args []string) []string {
+	switch cmd {
+	case "MGET":
+		return args
+	case "EVAL", "EVALSHA":
+		n, _ := strconv.Atoi(args[1])
+		return args[2 : 2+n]
+	case "GET", "SET", "DEL", "EXISTS", "INCRBY", "EXPIRE", "SSCAN":
+		return args[:1]
+	case "RENAME":
+		return args[:2]
+	}
+	return nil
+}
+
+func (fc *fakeCluster) hook(node int, c *server.Peer, cmd string, args []string) bool {
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	asking := fc.asking[c]
+	delete(fc.asking, c)
+
+	switch cmd {
+	case "CLUSTER":
+		if strings.ToUpper(args[0]) != "SLOTS" {
+			return false
+		}
+		// the topology reported by nodes never change, like a cluster in the middle of resharding
+		c.WriteLen(len(fc.nodes))
+		for i, mr := range fc.nodes {
+			c.WriteLen(3)
+			c.WriteInt(i * slotNumber / 2)
+			c.WriteInt((i+1)*slotNumber/2 - 1)
+			c.WriteLen(3)
+			c.WriteBulk(mr.Host())
+			c.WriteInt(mr.Server().Addr().Port)
+			c.WriteBulk(fmt.Sprintf("node%d", i))
+		}
+		return true
+	case "ASKING":
+		fc.asking[c] = true
+		c.WriteOK()
+		return true
+	}
+
+	ks := keys(cmd, args)
+	if len(ks) == 0 {
+		return false
+	}
+	s := slot(ks[0])
+	for _, key := range ks[1:] {
+		if slot(key) != s {
+			c.WriteError("CROSSSLOT Keys in request don't hash to the same slot")
+			return true
+		}
+	}
+
+	switch owner := fc.owner(s); {
+	case fc.migrating[s] && node == 1 && asking:
+		return false
+	case fc.migrating[s] && node == 0:
+		fc.redirects++
+		c.WriteError(fmt.Sprintf("ASK %d %s", s, fc.nodes[1].Addr()))
+		return true
+	case owner != node:
+		fc.redirects++
+		c.WriteError(fmt.Sprintf("MOVED %d %s", s, fc.nodes[owner].Addr()))
+		return true
+	}
+	return false
+}
+
+func TestRedisCluster(t *testing.T) {
+	fc := newFakeCluster(t)
+	defer fc.Close()
+
+	bm, err := cache.NewCache(cache.TypeRedisCache, cache.Config{ClusterAddrs: []string{fc.nodes[0].Addr()}})
+	if err != nil {
+		t.Fatalf("create new cache error, err:%v", err)
+	}
+
+	var keys []string
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key%d", i)
+		keys = append(keys, key)
+		assert.Nil(t, bm.Put(key, key, 10*time.Second))
+
+		v, err := fc.nodes[fc.owner(slot("cache:"+key))].Get("cache:" + key)
+		assert.Nil(t, err)
+		assert.Equal(t, key, v)
+	}
+	assert.NotEmpty(t, fc.nodes[0].Keys())
+	assert.NotEmpty(t, fc.nodes[1].Keys())
+
+	// keys of different slots are fetched by one MGET per slot
+	values := bm.GetMulti(append(keys, "missing"))
+	assert.Len(t, values, len(keys)+1)
+	for i, key := range keys {
+		assert.Equal(t, []byte(key), values[i])
+	}
+	assert.Nil(t, values[len(keys)])
+
+	values, err = bm.(cache.StoreProvider).Store().GetMulti(context.Background(), keys)
+	assert.Nil(t, err)
+	assert.Len(t, values, len(keys))
+
+	assert.Nil(t, bm.TryLock("lock", "owner1", 10*time.Second))
+	assert.Equal(t, cache.ErrLockFailure, bm.TryLock("lock", "owner2", 10*time.Second))
+	assert.Nil(t, bm.UnLock("lock", "owner1"))
+
+	// ClearAll scan every master and keep keys of other collections
+	assert.Nil(t, fc.nodes[1].Set("other", "value"))
+	assert.Nil(t, bm.ClearAll())
+	assert.Empty(t, fc.nodes[0].Keys())
+	assert.Equal(t, []string{"other"}, fc.nodes[1].Keys())
+	assert.Nil(t, bm.Get(keys[0]))
+}
+
+func TestRedisClusterRedirect(t *testing.T) {
+	fc := newFakeCluster(t)
+	defer fc.Close()
+
+	bm, err := cache.NewCache(cache.TypeRedisCache, cache.Config{ClusterAddrs: []string{fc.nodes[0].Addr()}})
+	if err != nil {
+		t.Fatalf("create new cache error, err:%v", err)
+	}
+
+	// find a key of node 0
+	key := "key"
+	for i := 0; fc.owner(slot("cache:"+key)) != 0; i++ {
+		key = fmt.Sprintf("key%d", i)
+	}
+	s := slot("cache:" + key)
+
+	// ASK redirect the key of a migrating slot to node 1
+	fc.mutex.Lock()
+	fc.migrating[s] = true
+	fc.mutex.Unlock()
+
+	assert.Nil(t, bm.Put(key, "v1", 10*time.Second))
+	v, err := fc.nodes[1].Get("cache:" + key)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", v)
+	assert.Equal(t, []byte("v1"), bm.Get(key))
+
+	// MOVED redirect the key once the slot is owned by node 1
+	fc.mutex.Lock()
+	delete(fc.migrating, s)
+	fc.owners[s] = 1
+	redirects := fc.redirects
+	fc.mutex.Unlock()
+
+	assert.Nil(t, bm.Put(key, "v2", 10*time.Second))
+	v, err = fc.nodes[1].Get("cache:" + key)
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", v)
+	assert.Equal(t, []byte("v2"), bm.Get(key))
+	assert.False(t, fc.nodes[0].Exists("cache:"+key))
+
+	fc.mutex.Lock()
+	assert.Greater(t, fc.redirects, redirects)
+	fc.mutex.Unlock()
+}
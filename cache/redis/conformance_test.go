@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/cache"
+	"github.com/dbunion/com/cache/cachetest"
+)
+
+func TestRedisConformance(t *testing.T) {
+	mr, cfg := newMiniRedisConfig(t)
+	defer mr.Close()
+
+	bm, err := cache.NewCache(cache.TypeRedisCache, cfg)
+	if err != nil {
+		t.Fatalf("create new cache error, err:%v", err)
+	}
+	cachetest.Run(t, bm, mr.FastForward)
+}
+
+func TestRedisClusterConformance(t *testing.T) {
+	fc := newFakeCluster(t)
+	defer fc.Close()
+
+	bm, err := cache.NewCache(cache.TypeRedisCache, cache.Config{ClusterAddrs: []string{fc.nodes[0].Addr()}})
+	if err != nil {
+		t.Fatalf("create new cache error, err:%v", err)
+	}
+	cachetest.Run(t, bm, func(d time.Duration) {
+		for _, mr := range fc.nodes {
+			mr.FastForward(d)
+		}
+	})
+}
@@ -1,28 +1,42 @@
 package redis
 
 import (
+	"context"
+	"encoding"
 	"errors"
 	"fmt"
-	"github.com/dbunion/com/cache"
-	"github.com/gomodule/redigo/redis"
 	"time"
+
+	"github.com/dbunion/com/cache"
+	"github.com/go-redis/redis/v8"
 )
 
 var (
 	defaultKey = "cache"
+	// keys deleted per round trip of ClearAll
+	scanCount int64 = 1000
 )
 
+var lockScript = redis.NewScript(`
+if redis.call('set',KEYS[1],ARGV[1],'NX','PX',ARGV[2]) then
+   return 1
+end
+return 0`)
+
+var unlockScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1]
+then
+return redis.call('del', KEYS[1])
+else
+return false
+end
+`)
+
 // Cache is Redis cache adapter.
+// It connects to a single server, a sentinel monitored master or a cluster.
 type Cache struct {
-	p         *redis.Pool // redis connection pool
-	server    string
-	connInfo  string
-	port      int64
-	dbNum     int64
-	key       string
-	password  string
-	maxIdle   int64
-	maxActive int64
+	client redis.UniversalClient
+	key    string
 }
 
 // NewRedisCache create new redis cache with default collection name.
@@ -30,28 +44,26 @@ func NewRedisCache() cache.Cache {
 	return &Cache{key: defaultKey}
 }
 
-// actually do the redis cmds, args[0] must be the key name.
-func (rc *Cache) do(commandName string, args ...interface{}) (reply interface{}, err error) {
-	if len(args) < 1 {
-		return nil, errors.New("missing required arguments")
-	}
-	args[0] = rc.associate(args[0])
-	c := rc.p.Get()
-	defer func() {
-		err = c.Close()
-	}()
-
-	return c.Do(commandName, args...)
-}
-
 // associate with config key.
 func (rc *Cache) associate(originKey interface{}) string {
 	return fmt.Sprintf("%s:%s", rc.key, originKey)
 }
 
+// value convert val to a redis argument, values go-redis can not
+// marshal are formatted like fmt.Sprint.
+func value(val interface{}) interface{} {
+	switch val.(type) {
+	case nil, string, []byte, int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64, float32, float64, bool,
+		time.Time, encoding.BinaryMarshaler:
+		return val
+	}
+	return fmt.Sprint(val)
+}
+
 // Get cache from redis.
 func (rc *Cache) Get(key string) interface{} {
-	if v, err := rc.do("GET", key); err == nil {
+	if v, found, err := rc.store().Get(context.Background(), key); err == nil && found {
 		return v
 	}
 	return nil
@@ -59,44 +71,69 @@ func (rc *Cache) Get(key string) interface{} {
 
 // GetMulti get cache from redis.
 func (rc *Cache) GetMulti(keys []string) []interface{} {
-	c := rc.p.Get()
-	defer func() {
-		if err := c.Close(); err != nil {
-			fmt.Println(err)
-		}
-	}()
-
-	var args []interface{}
-	for _, key := range keys {
-		args = append(args, rc.associate(key))
-	}
-	values, err := redis.Values(c.Do("MGET", args...))
+	values, err := rc.mget(context.Background(), keys)
 	if err != nil {
 		return nil
 	}
 	return values
 }
 
-// Put put cache to redis.
-func (rc *Cache) Put(key string, val interface{}, timeout time.Duration) error {
-	var err error
-	if int64(timeout/time.Second) < 1 {
-		_, err = rc.do("SET", key, val)
+// mget get values of keys, nil for missing keys.
+// In cluster mode keys are grouped by hash slot, one MGET per slot.
+func (rc *Cache) mget(ctx context.Context, keys []string) ([]interface{}, error) {
+	values := make([]interface{}, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	groups := make(map[int][]int)
+	if _, ok := rc.client.(*redis.ClusterClient); ok {
+		for i, key := range keys {
+			s := slot(rc.associate(key))
+			groups[s] = append(groups[s], i)
+		}
 	} else {
-		_, err = rc.do("SETEX", key, int64(timeout/time.Second), val)
+		for i := range keys {
+			groups[0] = append(groups[0], i)
+		}
 	}
-	return err
+
+	pipe := rc.client.Pipeline()
+	cmds := make(map[int]*redis.SliceCmd, len(groups))
+	for s, index := range groups {
+		args := make([]string, 0, len(index))
+		for _, i := range index {
+			args = append(args, rc.associate(keys[i]))
+		}
+		cmds[s] = pipe.MGet(ctx, args...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	for s, index := range groups {
+		for j, v := range cmds[s].Val() {
+			if str, ok := v.(string); ok {
+				values[index[j]] = []byte(str)
+			}
+		}
+	}
+	return values, nil
+}
+
+// Put put cache to redis.
+func (rc *Cache) Put(key string, val interface{}, timeout time.Duration) error {
+	return rc.store().Put(context.Background(), key, val, timeout)
 }
 
 // Delete delete cache in redis.
 func (rc *Cache) Delete(key string) error {
-	_, err := rc.do("DEL", key)
-	return err
+	return rc.store().Delete(context.Background(), key)
 }
 
 // IsExist check cache's existence in redis.
 func (rc *Cache) IsExist(key string) bool {
-	v, err := redis.Bool(rc.do("EXISTS", key))
+	v, err := rc.store().IsExist(context.Background(), key)
 	if err != nil {
 		return false
 	}
@@ -105,90 +142,90 @@ func (rc *Cache) IsExist(key string) bool {
 
 // Incr increase counter in redis.
 func (rc *Cache) Incr(key string) error {
-	_, err := redis.Bool(rc.do("INCRBY", key, 1))
+	_, err := rc.store().IncrBy(context.Background(), key, 1)
 	return err
 }
 
 // Decr decrease counter in redis.
 func (rc *Cache) Decr(key string) error {
-	_, err := redis.Bool(rc.do("DECRBY", key, 1))
+	_, err := rc.store().IncrBy(context.Background(), key, -1)
 	return err
 }
 
 // IncrBy increase counter in redis.
 func (rc *Cache) IncrBy(key string) (interface{}, error) {
-	return rc.do("INCRBY", key, 1)
+	return rc.store().IncrBy(context.Background(), key, 1)
 }
 
 // DecrBy decrease counter in redis.
 func (rc *Cache) DecrBy(key string) (interface{}, error) {
-	return rc.do("INCRBY", key, -1)
+	return rc.store().IncrBy(context.Background(), key, -1)
 }
 
 // ClearAll clean all cache in redis. delete this redis collection.
 func (rc *Cache) ClearAll() error {
-	c := rc.p.Get()
-	defer func() {
-		if err := c.Close(); err != nil {
-			fmt.Println(err)
-		}
-	}()
+	return rc.clear(context.Background())
+}
 
-	cachedKeys, err := redis.Strings(c.Do("KEYS", rc.key+":*"))
-	if err != nil {
+// clear delete keys of this collection, in cluster mode every master is scanned.
+func (rc *Cache) clear(ctx context.Context) error {
+	switch c := rc.client.(type) {
+	case *redis.ClusterClient:
+		return c.ForEachMaster(ctx, rc.scanDelete)
+	case *redis.Client:
+		return rc.scanDelete(ctx, c)
+	}
+	return fmt.Errorf("unsupported redis client %T", rc.client)
+}
+
+// scanDelete delete keys of this collection stored on node c.
+func (rc *Cache) scanDelete(ctx context.Context, c *redis.Client) error {
+	del := func(keys []string) error {
+		// keys of one node may still belong to different slots
+		pipe := c.Pipeline()
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		_, err := pipe.Exec(ctx)
 		return err
 	}
-	for _, str := range cachedKeys {
-		if _, err = c.Do("DEL", str); err != nil {
-			return err
+
+	var keys []string
+	iter := c.Scan(ctx, 0, rc.key+":*", scanCount).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if int64(len(keys)) >= scanCount {
+			if err := del(keys); err != nil {
+				return err
+			}
+			keys = keys[:0]
 		}
 	}
-	return err
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return del(keys)
 }
 
 // TryLock ...
 func (rc *Cache) TryLock(key string, val interface{}, timeout time.Duration) error {
-	luaLock := `
-if redis.call('setNx',KEYS[1],ARGV[1])  then
-   if redis.call('get',KEYS[1])==ARGV[1] then
-      return redis.call('expire',KEYS[1],ARGV[2])
-   else
-      return 0
-   end
-end`
-	lua := redis.NewScript(1, luaLock)
-	conn := rc.p.Get()
-	defer func() {
-		if err := conn.Close(); err != nil {
-			fmt.Println(err)
-		}
-	}()
-	_, err := lua.Do(conn, fmt.Sprintf("%v", key), fmt.Sprintf("%v", val), int64(timeout/time.Second))
+	locked, err := lockScript.Run(context.Background(), rc.client, []string{key}, fmt.Sprintf("%v", val), int64(timeout/time.Millisecond)).Bool()
 	if err != nil {
 		return err
 	}
+	if !locked {
+		return cache.ErrLockFailure
+	}
 	return nil
 }
 
 // UnLock ...
 func (rc *Cache) UnLock(key string, val interface{}) error {
-	luaLock := `
-if redis.call('get', KEYS[1]) == ARGV[1]
-then
-return redis.call('del', KEYS[1])
-else
-return false
-end
-`
-	lua := redis.NewScript(1, luaLock)
-	conn := rc.p.Get()
-	defer func() {
-		if err := conn.Close(); err != nil {
-			fmt.Println(err)
-		}
-	}()
-	_, err := lua.Do(conn, key, val)
-	if err != nil {
+	err := unlockScript.Run(context.Background(), rc.client, []string{key}, fmt.Sprintf("%v", val)).Err()
+	if err != nil && err != redis.Nil {
 		return err
 	}
 	return nil
@@ -196,31 +233,36 @@ end
 
 // Set put cache to redis.
 func (rc *Cache) Set(key string, val interface{}) (bool, error) {
-	v, err := redis.Bool(rc.do("SET", key, val))
+	v, err := rc.client.Set(context.Background(), rc.associate(key), value(val), 0).Result()
 	if err != nil {
 		return false, err
 	}
 
-	return v, nil
+	return v == "OK", nil
 }
 
 // Expire key.
 func (rc *Cache) Expire(key string, timeout time.Duration) error {
-	ttl := int(timeout / time.Second)
-	if ttl < 0 {
+	if timeout < 0 {
 		return nil
 	}
 
-	_, err := rc.do("expire", key, ttl)
-	if err != nil {
-		return err
-	}
+	return rc.client.Expire(context.Background(), rc.associate(key), timeout.Truncate(time.Second)).Err()
+}
 
-	return nil
+// Client return the go-redis client of the adapter, e.g. for pub/sub.
+func (rc *Cache) Client() redis.UniversalClient {
+	return rc.client
+}
+
+// Close close connections to redis.
+func (rc *Cache) Close() error {
+	return rc.client.Close()
 }
 
 // StartAndGC start redis cache adapter.
-// config is like {"key":"collection key","conn":"connection info","dbNum":"0"}
+// the topology is sentinel if MasterName is set, cluster if ClusterAddrs is set,
+// otherwise a single server of Server and Port.
 // the cache item in redis are stored forever,
 // so no gc operation.
 func (rc *Cache) StartAndGC(config cache.Config) error {
@@ -229,65 +271,57 @@ func (rc *Cache) StartAndGC(config cache.Config) error {
 		rc.key = config.Key
 	}
 
-	if config.Server == "" {
-		return errors.New("server config is empty")
+	client, err := newClient(config)
+	if err != nil {
+		return err
 	}
 
-	rc.server = config.Server
-	rc.port = config.Port
-	rc.password = config.Password
-	rc.dbNum = config.DBNum
-
-	connInfo := fmt.Sprintf("%s:%d", rc.server, rc.port)
-	rc.connInfo = connInfo
-	rc.maxIdle = config.MaxIdle
-	rc.maxActive = config.MaxActive
-
-	rc.connectInit()
-
-	c := rc.p.Get()
-	defer func() {
-		if err := c.Close(); err != nil {
-			fmt.Println(err)
-		}
-	}()
-
-	return c.Err()
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		_ = client.Close()
+		return err
+	}
+	rc.client = client
+	return nil
 }
 
-// connect to redis.
-func (rc *Cache) connectInit() {
-	dialFunc := func() (c redis.Conn, err error) {
-		c, err = redis.Dial("tcp", rc.connInfo)
-		if err != nil {
-			return nil, err
+// newClient create redis client of the topology described by config.
+// MaxActive limit the connections of each node, go-redis keeps no idle limit.
+func newClient(config cache.Config) (redis.UniversalClient, error) {
+	switch {
+	case config.MasterName != "":
+		if len(config.SentinelAddrs) == 0 {
+			return nil, errors.New("sentinel addrs config is empty")
 		}
-
-		if rc.password != "" {
-			if _, err = c.Do("AUTH", rc.password); err != nil {
-				if cErr := c.Close(); cErr != nil {
-					return nil, fmt.Errorf("authError:%v CloseError:%v", err, cErr)
-				}
-				return nil, err
-			}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       config.MasterName,
+			SentinelAddrs:    config.SentinelAddrs,
+			SentinelPassword: config.SentinelPassword,
+			Password:         config.Password,
+			DB:               int(config.DBNum),
+			PoolSize:         int(config.MaxActive),
+			IdleTimeout:      180 * time.Second,
+		}), nil
+	case len(config.ClusterAddrs) > 0:
+		if config.DBNum != 0 {
+			return nil, errors.New("redis cluster only support db 0")
 		}
-
-		_, selectErr := c.Do("SELECT", rc.dbNum)
-		if selectErr != nil {
-			if cErr := c.Close(); cErr != nil {
-				return nil, fmt.Errorf("selectError:%v CloseError:%v", selectErr, cErr)
-			}
-			return nil, selectErr
-		}
-		return
-	}
-	// initialize a new pool
-	rc.p = &redis.Pool{
-		MaxIdle:     int(rc.maxIdle),
-		MaxActive:   int(rc.maxActive),
-		IdleTimeout: 180 * time.Second,
-		Dial:        dialFunc,
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        config.ClusterAddrs,
+			Password:     config.Password,
+			MaxRedirects: int(config.MaxRedirects),
+			PoolSize:     int(config.MaxActive),
+			IdleTimeout:  180 * time.Second,
+		}), nil
+	case config.Server != "":
+		return redis.NewClient(&redis.Options{
+			Addr:        fmt.Sprintf("%s:%d", config.Server, config.Port),
+			Password:    config.Password,
+			DB:          int(config.DBNum),
+			PoolSize:    int(config.MaxActive),
+			IdleTimeout: 180 * time.Second,
+		}), nil
 	}
+	return nil, errors.New("server config is empty")
 }
 
 func init() {
@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/dbunion/com/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSentinel answer the master address of a miniredis server.
+type fakeSentinel struct {
+	srv    *server.Server
+	name   string
+	mutex  sync.Mutex
+	master *miniredis.Miniredis
+}
+
+func newFakeSentinel(t *testing.T, name string, master *miniredis.Miniredis) *fakeSentinel {
+	srv, err := server.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("start sentinel error, err:%v", err)
+	}
+	fs := &fakeSentinel{srv: srv, name: name, master: master}
+	_ = srv.Register("PING", func(c *server.Peer, cmd string, args []string) {
+		c.WriteInline("PONG")
+	})
+	_ = srv.Register("SENTINEL", fs.sentinel)
+	_ = srv.Register("SUBSCRIBE", func(c *server.Peer, cmd string, args []string) {
+		for i, channel := range args {
+			c.WriteLen(3)
+			c.WriteBulk("subscribe")
+			c.WriteBulk(channel)
+			c.WriteInt(i + 1)
+		}
+	})
+	return fs
+}
+
+func (fs *fakeSentinel) Addr() string {
+	return fs.srv.Addr().String()
+}
+
+func (fs *fakeSentinel) Close() {
+	fs.srv.Close()
+}
+
+// failover promote master.
+func (fs *fakeSentinel) failover(master *miniredis.Miniredis) {
+	fs.mutex.Lock()
+	fs.master = master
+	fs.mutex.Unlock()
+}
+
+func (fs *fakeSentinel) sentinel(c *server.Peer, cmd string, args []string) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	switch strings.ToLower(args[0]) {
+	case "get-master-addr-by-name":
+		if args[1] != fs.name {
+			c.WriteNull()
+			return
+		}
+		c.WriteLen(2)
+		c.WriteBulk(fs.master.Host())
+		c.WriteBulk(strconv.Itoa(fs.master.Server().Addr().Port))
+	case "sentinels":
+		c.WriteLen(0)
+	default:
+		c.WriteError("ERR unknown sentinel subcommand")
+	}
+}
+
+func TestRedisSentinel(t *testing.T) {
+	m1, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	defer m1.Close()
+	m2, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	defer m2.Close()
+
+	fs := newFakeSentinel(t, "mymaster", m1)
+	defer fs.Close()
+
+	_, err = cache.NewCache(cache.TypeRedisCache, cache.Config{MasterName: "mymaster"})
+	assert.NotNil(t, err)
+
+	bm, err := cache.NewCache(cache.TypeRedisCache, cache.Config{
+		MasterName:    "mymaster",
+		SentinelAddrs: []string{fs.Addr()},
+	})
+	if err != nil {
+		t.Fatalf("create new cache error, err:%v", err)
+	}
+
+	assert.Nil(t, bm.Put(cacheKey, "v1", 10*time.Second))
+	v, err := m1.Get("cache:" + cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", v)
+
+	// the new master is discovered once the old one is gone
+	fs.failover(m2)
+	m1.Close()
+	assert.Nil(t, m2.Set("cache:"+cacheKey, "v2"))
+
+	assert.Equal(t, []byte("v2"), bm.Get(cacheKey))
+	assert.Nil(t, bm.Put(cacheKey1, "v3", 10*time.Second))
+	v, err = m2.Get("cache:" + cacheKey1)
+	assert.Nil(t, err)
+	assert.Equal(t, "v3", v)
+}
@@ -0,0 +1,42 @@
+package redis
+
+import "strings"
+
+// number of hash slots of redis cluster
+const slotNumber = 16384
+
+// crc16 (XMODEM) lookup table used by redis cluster.
+var crc16tab = func() [256]uint16 {
+	var tab [256]uint16
+	for i := range tab {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		tab[i] = crc
+	}
+	return tab
+}()
+
+func crc16(key string) uint16 {
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc = crc<<8 ^ crc16tab[byte(crc>>8)^key[i]]
+	}
+	return crc
+}
+
+// slot return the cluster hash slot of key,
+// only the hash tag is hashed if key contains a non-empty {tag}.
+func slot(key string) int {
+	if s := strings.IndexByte(key, '{'); s >= 0 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			key = key[s+1 : s+1+e]
+		}
+	}
+	return int(crc16(key)) % slotNumber
+}
@@ -0,0 +1,86 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dbunion/com/cache"
+	"github.com/go-redis/redis/v8"
+)
+
+// Store is the context-aware view of Redis cache adapter.
+type Store struct {
+	rc *Cache
+}
+
+// Store returns the context-aware view of Redis cache adapter.
+func (rc *Cache) Store() cache.Store {
+	return rc.store()
+}
+
+func (rc *Cache) store() *Store {
+	return &Store{rc: rc}
+}
+
+// Get get cache from redis.
+func (s *Store) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	v, err := s.rc.client.Get(ctx, s.rc.associate(key)).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return v, true, nil
+}
+
+// GetMulti get cache from redis.
+func (s *Store) GetMulti(ctx context.Context, keys []string) ([]interface{}, error) {
+	return s.rc.mget(ctx, keys)
+}
+
+// Put put cache to redis.
+func (s *Store) Put(ctx context.Context, key string, val interface{}, timeout time.Duration) error {
+	if timeout < time.Second {
+		timeout = 0
+	}
+	return s.rc.client.Set(ctx, s.rc.associate(key), value(val), timeout.Truncate(time.Second)).Err()
+}
+
+// Delete delete cache in redis.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	return s.rc.client.Del(ctx, s.rc.associate(key)).Err()
+}
+
+// IncrBy add delta to counter in redis.
+func (s *Store) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	return s.rc.client.IncrBy(ctx, s.rc.associate(key), delta).Result()
+}
+
+// Expire key.
+func (s *Store) Expire(ctx context.Context, key string, timeout time.Duration) error {
+	if timeout < time.Second {
+		return fmt.Errorf("invalid expire timeout %v", timeout)
+	}
+
+	ok, err := s.rc.client.Expire(ctx, s.rc.associate(key), timeout.Truncate(time.Second)).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return cache.ErrNotFound
+	}
+	return nil
+}
+
+// IsExist check cache's existence in redis.
+func (s *Store) IsExist(ctx context.Context, key string) (bool, error) {
+	n, err := s.rc.client.Exists(ctx, s.rc.associate(key)).Result()
+	return n > 0, err
+}
+
+// ClearAll clean all cache in redis. delete this redis collection.
+func (s *Store) ClearAll(ctx context.Context) error {
+	return s.rc.clear(ctx)
+}
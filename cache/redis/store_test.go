@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dbunion/com/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// newMiniRedisConfig start an in-process redis server and return its config.
+func newMiniRedisConfig(t *testing.T) (*miniredis.Miniredis, cache.Config) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	return mr, cache.Config{Server: mr.Host(), Port: int64(mr.Server().Addr().Port)}
+}
+
+func TestRedisStore(t *testing.T) {
+	mr, cfg := newMiniRedisConfig(t)
+	defer mr.Close()
+	s, err := cache.NewStore(cache.TypeRedisCache, cfg)
+	if err != nil {
+		t.Fatalf("create new store error, err:%v", err)
+	}
+	assert.IsType(t, &Store{}, s)
+
+	ctx := context.Background()
+	v, found, err := s.Get(ctx, cacheKey)
+	assert.Nil(t, err)
+	assert.False(t, found)
+	assert.Nil(t, v)
+
+	assert.Nil(t, s.Put(ctx, cacheKey, "author", 10*time.Second))
+	v, found, err = s.Get(ctx, cacheKey)
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("author"), v)
+
+	vv, err := s.GetMulti(ctx, []string{cacheKey, cacheKey1})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{[]byte("author"), nil}, vv)
+
+	n, err := s.IncrBy(ctx, cacheKey1, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), n)
+	n, err = s.IncrBy(ctx, cacheKey1, -2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+
+	assert.Equal(t, cache.ErrNotFound, s.Expire(ctx, "missingKey", time.Second))
+	assert.Nil(t, s.Expire(ctx, cacheKey, time.Second))
+	mr.FastForward(2 * time.Second)
+	exist, err := s.IsExist(ctx, cacheKey)
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	assert.Nil(t, s.Delete(ctx, cacheKey1))
+	assert.Nil(t, s.Delete(ctx, cacheKey1))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _, err = s.Get(canceled, cacheKey)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, s.Put(ctx, cacheKey, "author", 0))
+	assert.Nil(t, s.ClearAll(ctx))
+	assert.Empty(t, mr.Keys())
+
+	// backend failures are reported, not folded into the value
+	mr.Close()
+	_, _, err = s.Get(ctx, cacheKey)
+	assert.NotNil(t, err)
+}
+
+func TestRedisTryLock(t *testing.T) {
+	mr, cfg := newMiniRedisConfig(t)
+	defer mr.Close()
+
+	bm, err := cache.NewCache(cache.TypeRedisCache, cfg)
+	if err != nil {
+		t.Fatalf("create new cache error, err:%v", err)
+	}
+
+	assert.Nil(t, bm.TryLock("lock", "owner1", 10*time.Second))
+	assert.Equal(t, cache.ErrLockFailure, bm.TryLock("lock", "owner2", 10*time.Second))
+
+	// only the owner can unlock
+	assert.Nil(t, bm.UnLock("lock", "owner2"))
+	assert.Equal(t, cache.ErrLockFailure, bm.TryLock("lock", "owner2", 10*time.Second))
+	assert.Nil(t, bm.UnLock("lock", "owner1"))
+	assert.Nil(t, bm.TryLock("lock", "owner2", 10*time.Second))
+}
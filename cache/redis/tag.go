@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// tagScript add KEYS[1] member ARGV[2] and make the set live at least
+// ARGV[1] seconds, 0 means forever.
+var tagScript = redis.NewScript(`
+local ttl = redis.call('ttl', KEYS[1])
+redis.call('sadd', KEYS[1], ARGV[2])
+local timeout = tonumber(ARGV[1])
+if timeout <= 0 then
+   return redis.call('persist', KEYS[1])
+end
+if ttl == -2 or (ttl >= 0 and ttl < timeout) then
+   return redis.call('expire', KEYS[1], timeout)
+end
+return 0`)
+
+// tagKey return the set of keys put with tag, the tag is a hash tag
+// so the set and its renamed copy share a cluster slot.
+func (rc *Cache) tagKey(tag string) string {
+	return rc.associate("__tag__:{" + tag + "}")
+}
+
+// PutWithTags put cache to redis and add key to the set of each tag.
+func (rc *Cache) PutWithTags(key string, val interface{}, timeout time.Duration, tags ...string) error {
+	ctx := context.Background()
+	if timeout < time.Second {
+		timeout = 0
+	}
+	timeout = timeout.Truncate(time.Second)
+
+	pipe := rc.client.Pipeline()
+	pipe.Set(ctx, rc.associate(key), value(val), timeout)
+	for _, tag := range tags {
+		tagScript.Eval(ctx, pipe, []string{rc.tagKey(tag)}, int64(timeout/time.Second), rc.associate(key))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// InvalidateTag delete keys put with tag.
+// The set is renamed first, so keys tagged meanwhile go to a new set.
+func (rc *Cache) InvalidateTag(tag string) error {
+	ctx := context.Background()
+	key := rc.tagKey(tag)
+	tmp := key + ":" + uuid.New().String()
+	if err := rc.client.Rename(ctx, key, tmp).Err(); err != nil {
+		if strings.Contains(err.Error(), "no such key") {
+			return nil
+		}
+		return err
+	}
+
+	del := func(keys []string) error {
+		// members may belong to different cluster slots
+		pipe := rc.client.Pipeline()
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		_, err := pipe.Exec(ctx)
+		return err
+	}
+
+	var keys []string
+	iter := rc.client.SScan(ctx, tmp, 0, "", scanCount).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if int64(len(keys)) >= scanCount {
+			if err := del(keys); err != nil {
+				return err
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	return del(append(keys, tmp))
+}
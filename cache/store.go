@@ -0,0 +1,242 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrNotFound is returned by Store operations that require an existing key.
+var ErrNotFound = errors.New("cache: key not found")
+
+// ErrDeltaUnsupported is returned by IncrBy of a cache which is not a Counter
+// if delta is not 1 or -1.
+var ErrDeltaUnsupported = errors.New("cache: delta not supported")
+
+const (
+	counterLockSuffix  = ":incr_lock"
+	counterLockTimeout = 5 * time.Second
+	counterLockRetry   = 10 * time.Millisecond
+)
+
+// Store is the context-aware version of Cache.
+// Unlike Cache, every call reports failures through its error result,
+// so a cache miss can always be told apart from a backend failure.
+type Store interface {
+	// get cached value by key, found is false if key does not exist.
+	Get(ctx context.Context, key string) (val interface{}, found bool, err error)
+	// GetMulti is a batch version of Get, missing keys yield nil values.
+	GetMulti(ctx context.Context, keys []string) ([]interface{}, error)
+	// set cached value with key and expire time.
+	Put(ctx context.Context, key string, val interface{}, timeout time.Duration) error
+	// delete cached value by key, deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// add delta to cached int value by key and return the new value,
+	// a missing key is treated as zero.
+	IncrBy(ctx context.Context, key string, delta int64) (int64, error)
+	// expire key with timeout, returns ErrNotFound if key does not exist.
+	Expire(ctx context.Context, key string, timeout time.Duration) error
+	// check if cached value exists or not.
+	IsExist(ctx context.Context, key string) (bool, error)
+	// clear all cache.
+	ClearAll(ctx context.Context) error
+}
+
+// Counter is implemented by caches which add any delta to a counter in one
+// step, AsStore use it for IncrBy of caches without a native Store.
+type Counter interface {
+	// add delta to cached int value by key and return the new value,
+	// a missing key is treated as zero.
+	IncrByDelta(ctx context.Context, key string, delta int64) (int64, error)
+}
+
+// StoreProvider is implemented by adapters which have a native Store.
+type StoreProvider interface {
+	// Store returns the context-aware view of the adapter.
+	Store() Store
+}
+
+// NewStore Create a new cache driver by adapter name and config setting,
+// and return its Store view.
+func NewStore(adapterName string, config Config) (Store, error) {
+	adapter, err := NewCache(adapterName, config)
+	if err != nil {
+		return nil, err
+	}
+	return AsStore(adapter), nil
+}
+
+// AsStore returns the Store view of a Cache. Adapters without a native
+// Store are wrapped by a shim built on top of the Cache interface.
+func AsStore(c Cache) Store {
+	if p, ok := c.(StoreProvider); ok {
+		return p.Store()
+	}
+	return &cacheStore{c: c}
+}
+
+// cacheStore adapts a Cache to the Store interface.
+type cacheStore struct {
+	c Cache
+}
+
+// Get get cached value by key.
+func (s *cacheStore) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	switch v := s.c.Get(key).(type) {
+	case nil:
+		return nil, false, nil
+	case error:
+		return nil, false, v
+	default:
+		return v, true, nil
+	}
+}
+
+// GetMulti get cached values by keys.
+func (s *cacheStore) GetMulti(ctx context.Context, keys []string) ([]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	values := s.c.GetMulti(keys)
+	if len(values) != len(keys) {
+		return nil, errors.New("cache: get multi failure")
+	}
+	for _, v := range values {
+		if err, ok := v.(error); ok {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// Put put cache value by key.
+func (s *cacheStore) Put(ctx context.Context, key string, val interface{}, timeout time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.c.Put(key, val, timeout)
+}
+
+// Delete delete cache value by key.
+func (s *cacheStore) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !s.c.IsExist(key) {
+		return nil
+	}
+	return s.c.Delete(key)
+}
+
+// IncrBy add delta to cached int value by key. A Counter adds any delta in
+// one step, other caches only step by one so a larger delta is refused.
+func (s *cacheStore) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if c, ok := s.c.(Counter); ok {
+		return c.IncrByDelta(ctx, key, delta)
+	}
+	if delta != 1 && delta != -1 {
+		return 0, fmt.Errorf("%w: %d", ErrDeltaUnsupported, delta)
+	}
+
+	for {
+		if s.c.IsExist(key) {
+			return s.step(key, delta)
+		}
+
+		// create the missing counter under a lock so concurrent callers
+		// do not overwrite each other
+		token := strconv.FormatInt(time.Now().UnixNano(), 10)
+		if err := s.c.TryLock(key+counterLockSuffix, token, counterLockTimeout); err != nil {
+			if !errors.Is(err, ErrLockFailure) {
+				return 0, err
+			}
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(counterLockRetry):
+			}
+			continue
+		}
+
+		if s.c.IsExist(key) {
+			v, err := s.step(key, delta)
+			_ = s.c.UnLock(key+counterLockSuffix, token)
+			return v, err
+		}
+		err := s.c.Put(key, delta, 0)
+		_ = s.c.UnLock(key+counterLockSuffix, token)
+		if err != nil {
+			return 0, err
+		}
+		return delta, nil
+	}
+}
+
+// step add 1 or -1 to an existing counter.
+func (s *cacheStore) step(key string, delta int64) (int64, error) {
+	step := s.c.IncrBy
+	if delta < 0 {
+		step = s.c.DecrBy
+	}
+	v, err := step(key)
+	if err != nil {
+		return 0, err
+	}
+	return toInt64(v)
+}
+
+// Expire expire key with timeout.
+func (s *cacheStore) Expire(ctx context.Context, key string, timeout time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !s.c.IsExist(key) {
+		return ErrNotFound
+	}
+	return s.c.Expire(key, timeout)
+}
+
+// IsExist check cache's existence.
+func (s *cacheStore) IsExist(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.c.IsExist(key), nil
+}
+
+// ClearAll clear all cache.
+func (s *cacheStore) ClearAll(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.c.ClearAll()
+}
+
+// toInt64 convert a counter value returned by an adapter to int64.
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint32:
+		return int64(n), nil
+	case uint64:
+		return int64(n), nil
+	case []byte:
+		return strconv.ParseInt(string(n), 10, 64)
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	}
+	return 0, fmt.Errorf("cache: value %v is not an integer", v)
+}
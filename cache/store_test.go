@@ -0,0 +1,55 @@
+package cache_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/dbunion/com/cache"
+	_ "github.com/dbunion/com/cache/gocache"
+	"github.com/stretchr/testify/assert"
+)
+
+// legacyCache hide the native Store and Counter of an adapter.
+type legacyCache struct {
+	cache.Cache
+}
+
+func TestAsStoreIncrBy(t *testing.T) {
+	ctx := context.Background()
+	bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{})
+	assert.Nil(t, err)
+
+	s := cache.AsStore(legacyCache{bm})
+	n, err := s.IncrBy(ctx, "counter", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = s.IncrBy(ctx, "counter", -1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), n)
+	_, err = s.IncrBy(ctx, "counter", 1000000)
+	assert.ErrorIs(t, err, cache.ErrDeltaUnsupported)
+
+	// concurrent callers create a missing counter once
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.IncrBy(ctx, "created", 1)
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	n, err = s.IncrBy(ctx, "created", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(21), n)
+
+	// a Counter add any delta in one step
+	ns, err := cache.Namespace(bm, "ns")
+	assert.Nil(t, err)
+	n, err = cache.AsStore(ns).IncrBy(ctx, "counter", 1000000)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1000000), n)
+	assert.Equal(t, true, bm.IsExist("ns:counter"))
+}
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ErrTagNotSupported is returned if the adapter does not implement Tagger.
+var ErrTagNotSupported = errors.New("cache: tags not supported")
+
+// Tagger is implemented by adapters which support tag-based invalidation.
+// a key overwritten without a tag may still be deleted by the tag,
+// invalidation can remove too much but never keeps a stale value.
+type Tagger interface {
+	// put cached value with key, expire time and tags.
+	PutWithTags(key string, val interface{}, timeout time.Duration, tags ...string) error
+	// delete all cached values put with tag.
+	InvalidateTag(tag string) error
+}
+
+// PutWithTags put cached value with tags, c must implement Tagger.
+func PutWithTags(c Cache, key string, val interface{}, timeout time.Duration, tags ...string) error {
+	t, ok := c.(Tagger)
+	if !ok {
+		return ErrTagNotSupported
+	}
+	return t.PutWithTags(key, val, timeout, tags...)
+}
+
+// InvalidateTag delete all cached values put with tag, c must implement Tagger.
+func InvalidateTag(c Cache, tag string) error {
+	t, ok := c.(Tagger)
+	if !ok {
+		return ErrTagNotSupported
+	}
+	return t.InvalidateTag(tag)
+}
+
+// Tagged - value put with the generations of its tags, adapters without
+// sets store it to emulate Tagger by generation counters
+type Tagged struct {
+	Val         interface{}
+	Generations map[string]int64
+}
+
+// tagGenerationTimeout - a negative timeout keep generation counters
+// without expiry in every adapter
+const tagGenerationTimeout = -1
+
+// tagGenerationKey return the key of the generation counter of tag.
+func tagGenerationKey(tag string) string {
+	return "__tag__:" + tag
+}
+
+// TagGenerations return the current generations of tags kept in c,
+// missing counters are seeded by seedTagGeneration.
+func TagGenerations(c Cache, tags []string) (map[string]int64, error) {
+	s := AsStore(c)
+	generations := make(map[string]int64, len(tags))
+	for _, tag := range tags {
+		key := tagGenerationKey(tag)
+		v, found, err := s.Get(context.Background(), key)
+		if err != nil {
+			return nil, err
+		}
+		n, ok := generation(v)
+		if !found || !ok {
+			if n, err = seedTagGeneration(s, key); err != nil {
+				return nil, err
+			}
+		}
+		generations[tag] = n
+	}
+	return generations, nil
+}
+
+// TagGenerationsValid report whether none of the tags were invalidated
+// since generations were read, a lost counter invalidates its tag.
+func TagGenerationsValid(c Cache, generations map[string]int64) bool {
+	if len(generations) == 0 {
+		return true
+	}
+	keys := make([]string, 0, len(generations))
+	tags := make([]string, 0, len(generations))
+	for tag := range generations {
+		keys = append(keys, tagGenerationKey(tag))
+		tags = append(tags, tag)
+	}
+	values := c.GetMulti(keys)
+	if len(values) != len(keys) {
+		return false
+	}
+	for i, v := range values {
+		n, ok := generation(v)
+		if !ok || n != generations[tags[i]] {
+			return false
+		}
+	}
+	return true
+}
+
+// NextTagGeneration invalidate values put with tag by moving its generation.
+func NextTagGeneration(c Cache, tag string) error {
+	s := AsStore(c)
+	key := tagGenerationKey(tag)
+	found, err := s.IsExist(context.Background(), key)
+	if err != nil {
+		return err
+	}
+	if !found {
+		_, err = seedTagGeneration(s, key)
+		return err
+	}
+	n, err := s.IncrBy(context.Background(), key, 1)
+	if err == nil && n == 1 {
+		// the counter was lost after the check
+		_, err = seedTagGeneration(s, key)
+	}
+	return err
+}
+
+// seedTagGeneration create a missing counter from the clock and keep it
+// without expiry. A counter evicted after an invalidation must not come
+// back at a generation read before, so it never restarts from zero.
+func seedTagGeneration(s Store, key string) (int64, error) {
+	n, err := s.IncrBy(context.Background(), key, time.Now().UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	if err := s.Expire(context.Background(), key, tagGenerationTimeout); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// generation convert a counter read from an adapter.
+func generation(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case []byte:
+		i, err := strconv.ParseInt(string(n), 10, 64)
+		return i, err == nil
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,32 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/cache"
+	_ "github.com/dbunion/com/cache/gocache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagGenerationLost(t *testing.T) {
+	bm, err := cache.NewCache(cache.TypeGoCache, cache.Config{Expiration: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("create new cache error, err:%v", err)
+	}
+
+	// an expired counter does not bring invalidated values back
+	assert.Nil(t, cache.PutWithTags(bm, "key1", "value1", 10*time.Second, "tag"))
+	assert.Nil(t, cache.InvalidateTag(bm, "tag"))
+	time.Sleep(200 * time.Millisecond)
+	assert.Nil(t, cache.PutWithTags(bm, "key2", "value2", 10*time.Second, "tag"))
+	assert.Nil(t, bm.Get("key1"))
+	assert.Equal(t, "value2", bm.Get("key2"))
+
+	// nor does a deleted one
+	assert.Nil(t, cache.InvalidateTag(bm, "tag"))
+	assert.Nil(t, bm.Delete("__tag__:tag"))
+	assert.Nil(t, cache.PutWithTags(bm, "key3", "value3", 10*time.Second, "tag"))
+	assert.Nil(t, bm.Get("key2"))
+	assert.Equal(t, "value3", bm.Get("key3"))
+}
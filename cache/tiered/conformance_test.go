@@ -0,0 +1,27 @@
+package tiered
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dbunion/com/cache"
+	"github.com/dbunion/com/cache/cachetest"
+)
+
+func TestTieredConformance(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	defer mr.Close()
+
+	tc := newTiered(t, mr, cache.WriteThrough)
+	defer tc.Close()
+
+	// L1 expire by wall clock, L2 by the miniredis clock
+	cachetest.Run(t, tc, func(d time.Duration) {
+		mr.FastForward(d)
+		time.Sleep(d)
+	})
+}
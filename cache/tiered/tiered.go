@@ -0,0 +1,467 @@
+package tiered
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dbunion/com/cache"
+	// L1 is always go cache
+	_ "github.com/dbunion/com/cache/gocache"
+	cacheredis "github.com/dbunion/com/cache/redis"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+var (
+	defaultLocalExpiration = time.Minute
+	reconnectInterval      = time.Second
+)
+
+// message - L1 invalidation message broadcast over redis pub/sub
+type message struct {
+	Node  string   `json:"node"`
+	Keys  []string `json:"keys,omitempty"`
+	Clear bool     `json:"clear,omitempty"`
+}
+
+// Cache is two-level cache adapter, the in-process go cache (L1)
+// in front of a remote cache (L2).
+type Cache struct {
+	local  cache.Cache
+	remote cache.Cache
+
+	localExpiration  time.Duration
+	remoteExpiration time.Duration
+	writeMode        string
+
+	node    string
+	channel string
+	client  redis.UniversalClient
+	// the redis cache of PubSub, closed with the tiered cache
+	pubSub *cacheredis.Cache
+	ps     *redis.PubSub
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewTieredCache create new tiered cache.
+func NewTieredCache() cache.Cache {
+	return &Cache{
+		node: uuid.New().String(),
+		done: make(chan struct{}),
+	}
+}
+
+// localTimeout return L1 timeout for a put with timeout.
+func (tc *Cache) localTimeout(timeout time.Duration) time.Duration {
+	if timeout > 0 && timeout < tc.localExpiration {
+		return timeout
+	}
+	return tc.localExpiration
+}
+
+// remoteTimeout return L2 timeout for a put with timeout.
+func (tc *Cache) remoteTimeout(timeout time.Duration) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+	return tc.remoteExpiration
+}
+
+// invalidate drop keys from L1 and broadcast to other replicas.
+func (tc *Cache) invalidate(keys ...string) error {
+	for _, key := range keys {
+		if err := tc.local.Delete(key); err != nil {
+			return err
+		}
+	}
+	return tc.publish(message{Node: tc.node, Keys: keys})
+}
+
+// publish broadcast invalidation message.
+func (tc *Cache) publish(msg message) error {
+	if tc.client == nil {
+		return nil
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return tc.client.Publish(context.Background(), tc.channel, data).Err()
+}
+
+// Get get value from L1, fallback to L2 and fill L1.
+func (tc *Cache) Get(key string) interface{} {
+	if v := tc.local.Get(key); v != nil {
+		return v
+	}
+
+	v := tc.remote.Get(key)
+	if v == nil {
+		return nil
+	}
+	if _, ok := v.(error); !ok {
+		_ = tc.local.Put(key, v, tc.localExpiration)
+	}
+	return v
+}
+
+// GetMulti get values from L1, fallback to L2 for missing keys.
+func (tc *Cache) GetMulti(keys []string) []interface{} {
+	values := make([]interface{}, len(keys))
+	var missing []string
+	var index []int
+	for i, key := range keys {
+		if v := tc.local.Get(key); v != nil {
+			values[i] = v
+			continue
+		}
+		missing = append(missing, key)
+		index = append(index, i)
+	}
+
+	if len(missing) == 0 {
+		return values
+	}
+
+	remoteValues := tc.remote.GetMulti(missing)
+	if len(remoteValues) != len(missing) {
+		return nil
+	}
+	for i, v := range remoteValues {
+		values[index[i]] = v
+		if v == nil {
+			continue
+		}
+		if _, ok := v.(error); !ok {
+			_ = tc.local.Put(missing[i], v, tc.localExpiration)
+		}
+	}
+	return values
+}
+
+// Put put value to L2, and to L1 in write through mode. The value is
+// encoded as L2 return it, so Get return the same form on every replica.
+func (tc *Cache) Put(key string, val interface{}, timeout time.Duration) error {
+	data, err := encode(val)
+	if err != nil {
+		return err
+	}
+	if err := tc.remote.Put(key, data, tc.remoteTimeout(timeout)); err != nil {
+		return err
+	}
+
+	if tc.writeMode == cache.WriteAround {
+		return tc.invalidate(key)
+	}
+
+	if err := tc.local.Put(key, data, tc.localTimeout(timeout)); err != nil {
+		return err
+	}
+	return tc.publish(message{Node: tc.node, Keys: []string{key}})
+}
+
+// encode format val as the remote redis and memcache return it.
+func encode(val interface{}) ([]byte, error) {
+	switch v := val.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int8:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int16:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int32:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(nil, v, 10), nil
+	case uint:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint8:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint16:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint32:
+		return strconv.AppendUint(nil, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint(nil, v, 10), nil
+	case float32:
+		return strconv.AppendFloat(nil, float64(v), 'f', -1, 64), nil
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64), nil
+	case bool:
+		if v {
+			return []byte("1"), nil
+		}
+		return []byte("0"), nil
+	case time.Time:
+		return v.AppendFormat(nil, time.RFC3339Nano), nil
+	case encoding.BinaryMarshaler:
+		return v.MarshalBinary()
+	}
+	return []byte(fmt.Sprint(val)), nil
+}
+
+// Delete delete value in both levels.
+func (tc *Cache) Delete(key string) error {
+	if err := tc.remote.Delete(key); err != nil {
+		return err
+	}
+	return tc.invalidate(key)
+}
+
+// Incr increase counter in L2.
+func (tc *Cache) Incr(key string) error {
+	if err := tc.remote.Incr(key); err != nil {
+		return err
+	}
+	return tc.invalidate(key)
+}
+
+// Decr decrease counter in L2.
+func (tc *Cache) Decr(key string) error {
+	if err := tc.remote.Decr(key); err != nil {
+		return err
+	}
+	return tc.invalidate(key)
+}
+
+// IncrBy increase counter in L2 and return value.
+func (tc *Cache) IncrBy(key string) (interface{}, error) {
+	v, err := tc.remote.IncrBy(key)
+	if err != nil {
+		return nil, err
+	}
+	return v, tc.invalidate(key)
+}
+
+// IncrByDelta add delta to counter in L2 and return value.
+func (tc *Cache) IncrByDelta(ctx context.Context, key string, delta int64) (int64, error) {
+	v, err := cache.AsStore(tc.remote).IncrBy(ctx, key, delta)
+	if err != nil {
+		return 0, err
+	}
+	return v, tc.invalidate(key)
+}
+
+// DecrBy decrease counter in L2 and return value.
+func (tc *Cache) DecrBy(key string) (interface{}, error) {
+	v, err := tc.remote.DecrBy(key)
+	if err != nil {
+		return nil, err
+	}
+	return v, tc.invalidate(key)
+}
+
+// TryLock lock key in L2, locks are never cached in L1.
+func (tc *Cache) TryLock(key string, val interface{}, timeout time.Duration) error {
+	return tc.remote.TryLock(key, val, timeout)
+}
+
+// UnLock unlock key in L2.
+func (tc *Cache) UnLock(key string, val interface{}) error {
+	return tc.remote.UnLock(key, val)
+}
+
+// Set put value to L2 without expiration.
+func (tc *Cache) Set(key string, val interface{}) (bool, error) {
+	ok, err := tc.remote.Set(key, val)
+	if err != nil {
+		return ok, err
+	}
+	return ok, tc.invalidate(key)
+}
+
+// Expire expire key in L2.
+func (tc *Cache) Expire(key string, timeout time.Duration) error {
+	if err := tc.remote.Expire(key, timeout); err != nil {
+		return err
+	}
+	return tc.invalidate(key)
+}
+
+// IsExist check value exists in L1 or L2.
+func (tc *Cache) IsExist(key string) bool {
+	return tc.local.IsExist(key) || tc.remote.IsExist(key)
+}
+
+// ClearAll clear both levels.
+func (tc *Cache) ClearAll() error {
+	if err := tc.remote.ClearAll(); err != nil {
+		return err
+	}
+	if err := tc.local.ClearAll(); err != nil {
+		return err
+	}
+	return tc.publish(message{Node: tc.node, Clear: true})
+}
+
+// Close stop the invalidation subscriber and close both levels.
+func (tc *Cache) Close() error {
+	var err error
+	keep := func(e error) {
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	tc.once.Do(func() {
+		if tc.ps != nil {
+			close(tc.done)
+			keep(tc.ps.Close())
+		}
+		if tc.pubSub != nil {
+			keep(tc.pubSub.Close())
+		}
+		keep(closeCache(tc.remote))
+		keep(closeCache(tc.local))
+	})
+	return err
+}
+
+// closeCache close c if it is an io.Closer.
+func closeCache(c cache.Cache) error {
+	if closer, ok := c.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// StartAndGC start tiered cache adapter.
+// config.Tiered describe the remote adapter and the invalidation channel.
+func (tc *Cache) StartAndGC(config cache.Config) (err error) {
+	tiered := config.Tiered
+	if tiered == nil || tiered.Remote == "" {
+		return errors.New("tiered remote config is empty")
+	}
+	if tiered.Remote == cache.TypeTieredCache || tiered.Remote == cache.TypeGoCache {
+		return fmt.Errorf("invalid tiered remote adapter %s", tiered.Remote)
+	}
+
+	switch tiered.WriteMode {
+	case "":
+		tc.writeMode = cache.WriteThrough
+	case cache.WriteThrough, cache.WriteAround:
+		tc.writeMode = tiered.WriteMode
+	default:
+		return fmt.Errorf("unknown write mode %s", tiered.WriteMode)
+	}
+
+	tc.localExpiration = defaultLocalExpiration
+	if tiered.LocalExpiration > 0 {
+		tc.localExpiration = tiered.LocalExpiration
+	}
+	tc.remoteExpiration = tiered.RemoteExpiration
+
+	// close what was started if any step below fail
+	defer func() {
+		if err != nil {
+			_ = tc.Close()
+		}
+	}()
+	if tc.local, err = cache.NewCache(cache.TypeGoCache, cache.Config{Expiration: tc.localExpiration}); err != nil {
+		return err
+	}
+	if tc.remote, err = cache.NewCache(tiered.Remote, tiered.RemoteConfig); err != nil {
+		return err
+	}
+
+	if tiered.Channel == "" {
+		return nil
+	}
+
+	// publish and subscribe through the go-redis client of the redis
+	// remote, which follow its sentinel or cluster topology
+	if tiered.PubSub != nil {
+		var pubSub cache.Cache
+		if pubSub, err = cache.NewCache(cache.TypeRedisCache, *tiered.PubSub); err != nil {
+			return err
+		}
+		tc.pubSub = pubSub.(*cacheredis.Cache)
+		tc.client = tc.pubSub.Client()
+	} else if rc, ok := unwrap(tc.remote).(*cacheredis.Cache); ok {
+		tc.client = rc.Client()
+	} else {
+		return errors.New("tiered pub/sub config is empty")
+	}
+	tc.channel = tiered.Channel
+
+	ps := tc.client.Subscribe(context.Background(), tc.channel)
+	if _, err = ps.Receive(context.Background()); err != nil {
+		_ = ps.Close()
+		return err
+	}
+	tc.ps = ps
+	go tc.receive()
+	return nil
+}
+
+// unwrap return the adapter of an instrumented cache.
+func unwrap(c cache.Cache) cache.Cache {
+	for {
+		w, ok := c.(interface{ Unwrap() cache.Cache })
+		if !ok {
+			return c
+		}
+		c = w.Unwrap()
+	}
+}
+
+// receive handle invalidation messages. go-redis resubscribe after a
+// connection failure, L1 is flushed then since messages may have been lost.
+func (tc *Cache) receive() {
+	lost := false
+	for {
+		msg, err := tc.ps.Receive(context.Background())
+		if err != nil {
+			select {
+			case <-tc.done:
+				return
+			case <-time.After(reconnectInterval):
+			}
+			lost = true
+			continue
+		}
+
+		switch v := msg.(type) {
+		case *redis.Subscription:
+			if lost {
+				_ = tc.local.ClearAll()
+				lost = false
+			}
+		case *redis.Message:
+			tc.handle([]byte(v.Payload))
+		}
+	}
+}
+
+// handle apply an invalidation message to L1.
+func (tc *Cache) handle(data []byte) {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Node == tc.node {
+		return
+	}
+
+	if msg.Clear {
+		_ = tc.local.ClearAll()
+		return
+	}
+	for _, key := range msg.Keys {
+		_ = tc.local.Delete(key)
+	}
+}
+
+func init() {
+	cache.Register(cache.TypeTieredCache, NewTieredCache)
+}
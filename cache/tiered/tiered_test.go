@@ -0,0 +1,258 @@
+package tiered
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/dbunion/com/cache"
+	_ "github.com/dbunion/com/cache/memcache"
+	cacheredis "github.com/dbunion/com/cache/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	cacheKey  = "tieredCacheKey"
+	cacheKey1 = "tieredCacheKey1"
+)
+
+func newTiered(t *testing.T, mr *miniredis.Miniredis, writeMode string) *Cache {
+	bm, err := cache.NewCache(cache.TypeTieredCache, cache.Config{
+		Tiered: &cache.TieredConfig{
+			Remote:          cache.TypeRedisCache,
+			RemoteConfig:    cache.Config{Server: mr.Host(), Port: int64(mr.Server().Addr().Port)},
+			LocalExpiration: time.Minute,
+			WriteMode:       writeMode,
+			Channel:         "tiered",
+		},
+	})
+	if err != nil {
+		t.Fatalf("create tiered cache error, err:%v", err)
+	}
+	return bm.(*Cache)
+}
+
+func TestTieredCache(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	defer mr.Close()
+
+	a := newTiered(t, mr, cache.WriteThrough)
+	defer a.Close()
+	b := newTiered(t, mr, cache.WriteThrough)
+	defer b.Close()
+
+	// write through fill L1 of the writer
+	assert.Nil(t, a.Put(cacheKey, "author", 10*time.Second))
+	assert.True(t, a.local.IsExist(cacheKey))
+	assert.Equal(t, []byte("author"), a.Get(cacheKey))
+
+	// read fill L1 of the other replica
+	assert.False(t, b.local.IsExist(cacheKey))
+	assert.Equal(t, []byte("author"), b.Get(cacheKey))
+	assert.True(t, b.local.IsExist(cacheKey))
+
+	// a write on one replica invalidate L1 of the other
+	assert.Nil(t, a.Put(cacheKey, "author1", 10*time.Second))
+	assert.Eventually(t, func() bool {
+		return !b.local.IsExist(cacheKey)
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []byte("author1"), b.Get(cacheKey))
+
+	// get multi mix both levels
+	assert.Nil(t, mr.Set("cache:"+cacheKey1, "author2"))
+	vv := b.GetMulti([]string{cacheKey, cacheKey1, "missingKey"})
+	assert.Equal(t, []interface{}{[]byte("author1"), []byte("author2"), nil}, vv)
+
+	// delete invalidate both levels everywhere
+	assert.Nil(t, a.Delete(cacheKey))
+	assert.Nil(t, a.Get(cacheKey))
+	assert.Eventually(t, func() bool {
+		return !b.local.IsExist(cacheKey)
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, a.ClearAll())
+	assert.Eventually(t, func() bool {
+		return !b.local.IsExist(cacheKey1)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTieredCacheValueForm(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	defer mr.Close()
+
+	a := newTiered(t, mr, cache.WriteThrough)
+	defer a.Close()
+	b := newTiered(t, mr, cache.WriteThrough)
+	defer b.Close()
+
+	// the writer get from L1 what the other replica get from L2
+	values := map[string]interface{}{
+		"string": "author",
+		"bytes":  []byte("author"),
+		"int":    42,
+		"float":  float32(1.5),
+		"bool":   true,
+		"time":   time.Date(2021, 1, 2, 3, 4, 5, 6, time.UTC),
+	}
+	for key, val := range values {
+		assert.Nil(t, a.Put(key, val, 10*time.Second))
+		assert.True(t, a.local.IsExist(key))
+		fromL1 := a.Get(key)
+		fromL2 := b.Get(key)
+		assert.IsType(t, []byte{}, fromL1, key)
+		assert.Equal(t, fromL2, fromL1, key)
+	}
+}
+
+func TestTieredCacheWriteAround(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	defer mr.Close()
+
+	bm := newTiered(t, mr, cache.WriteAround)
+	defer bm.Close()
+
+	assert.Nil(t, bm.Put(cacheKey, "author", 10*time.Second))
+	assert.False(t, bm.local.IsExist(cacheKey))
+	assert.Equal(t, []byte("author"), bm.Get(cacheKey))
+	assert.True(t, bm.local.IsExist(cacheKey))
+
+	assert.Nil(t, bm.Put(cacheKey, "author1", 10*time.Second))
+	assert.False(t, bm.local.IsExist(cacheKey))
+
+	// counters live in L2
+	assert.Nil(t, bm.Put(cacheKey1, 1, 10*time.Second))
+	assert.Nil(t, bm.Incr(cacheKey1))
+	assert.Equal(t, []byte("2"), bm.Get(cacheKey1))
+}
+
+// newSentinel start a sentinel which answer the address of master.
+func newSentinel(t *testing.T, master *miniredis.Miniredis) *server.Server {
+	srv, err := server.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("start sentinel error, err:%v", err)
+	}
+	_ = srv.Register("PING", func(c *server.Peer, cmd string, args []string) {
+		c.WriteInline("PONG")
+	})
+	_ = srv.Register("SENTINEL", func(c *server.Peer, cmd string, args []string) {
+		if len(args) < 1 || args[0] != "get-master-addr-by-name" {
+			c.WriteLen(0)
+			return
+		}
+		c.WriteLen(2)
+		c.WriteBulk(master.Host())
+		c.WriteBulk(strconv.Itoa(master.Server().Addr().Port))
+	})
+	_ = srv.Register("SUBSCRIBE", func(c *server.Peer, cmd string, args []string) {
+		for i, channel := range args {
+			c.WriteLen(3)
+			c.WriteBulk("subscribe")
+			c.WriteBulk(channel)
+			c.WriteInt(i + 1)
+		}
+	})
+	return srv
+}
+
+func TestTieredCacheSentinel(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	defer mr.Close()
+	sentinel := newSentinel(t, mr)
+	defer sentinel.Close()
+
+	// invalidations go through the sentinel monitored master
+	replicas := make([]*Cache, 2)
+	for i := range replicas {
+		bm, err := cache.NewCache(cache.TypeTieredCache, cache.Config{
+			Tiered: &cache.TieredConfig{
+				Remote: cache.TypeRedisCache,
+				RemoteConfig: cache.Config{
+					MasterName:    "mymaster",
+					SentinelAddrs: []string{sentinel.Addr().String()},
+				},
+				Channel: "tiered",
+			},
+		})
+		if err != nil {
+			t.Fatalf("create tiered cache error, err:%v", err)
+		}
+		replicas[i] = bm.(*Cache)
+		defer replicas[i].Close()
+	}
+	a, b := replicas[0], replicas[1]
+
+	assert.Nil(t, a.Put(cacheKey, "author", 10*time.Second))
+	assert.Equal(t, []byte("author"), b.Get(cacheKey))
+	assert.Nil(t, a.Put(cacheKey, "author1", 10*time.Second))
+	assert.Eventually(t, func() bool {
+		return !b.local.IsExist(cacheKey)
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []byte("author1"), b.Get(cacheKey))
+}
+
+func TestTieredCacheTimeout(t *testing.T) {
+	bm := &Cache{localExpiration: time.Minute, remoteExpiration: time.Hour}
+	assert.Equal(t, time.Second, bm.localTimeout(time.Second))
+	assert.Equal(t, time.Minute, bm.localTimeout(time.Hour))
+	assert.Equal(t, time.Minute, bm.localTimeout(0))
+	assert.Equal(t, time.Second, bm.remoteTimeout(time.Second))
+	assert.Equal(t, time.Hour, bm.remoteTimeout(0))
+}
+
+func TestTieredCacheConfig(t *testing.T) {
+	_, err := cache.NewCache(cache.TypeTieredCache, cache.Config{})
+	assert.NotNil(t, err)
+
+	_, err = cache.NewCache(cache.TypeTieredCache, cache.Config{
+		Tiered: &cache.TieredConfig{Remote: cache.TypeGoCache},
+	})
+	assert.NotNil(t, err)
+
+	_, err = cache.NewCache(cache.TypeTieredCache, cache.Config{
+		Tiered: &cache.TieredConfig{Remote: cache.TypeRedisCache, WriteMode: "unknown"},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestTieredCacheClose(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	defer mr.Close()
+	remote := cache.Config{Server: mr.Host(), Port: int64(mr.Server().Addr().Port)}
+
+	// both levels are closed with the tiered cache
+	bm := newTiered(t, mr, cache.WriteThrough)
+	assert.Nil(t, bm.Close())
+	assert.Nil(t, bm.Close())
+	assert.NotNil(t, bm.remote.(*cacheredis.Cache).Client().Ping(context.Background()).Err())
+
+	// and when it fail to start
+	bm = NewTieredCache().(*Cache)
+	err = bm.StartAndGC(cache.Config{
+		Tiered: &cache.TieredConfig{
+			Remote:       cache.TypeRedisCache,
+			RemoteConfig: remote,
+			Channel:      "tiered",
+			PubSub:       &cache.Config{Server: "127.0.0.1", Port: 1},
+		},
+	})
+	assert.NotNil(t, err)
+	assert.NotNil(t, bm.remote.(*cacheredis.Cache).Client().Ping(context.Background()).Err())
+}
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Typed is a generic wrapper over a Store which serializes values of type T
+// with a Codec, so every adapter stores and returns the same bytes.
+// usage:
+//
+//	users, err := cache.NewTyped[User](store, cache.CodecJSON)
+//	user, found, err := users.Get(ctx, "user:1")
+type Typed[T any] struct {
+	store Store
+	codec Codec
+}
+
+// NewTyped create a typed wrapper over store using the codec registered by codecName.
+func NewTyped[T any](store Store, codecName string) (*Typed[T], error) {
+	codec, err := GetCodec(codecName)
+	if err != nil {
+		return nil, err
+	}
+	return NewTypedWithCodec[T](store, codec), nil
+}
+
+// NewTypedWithCodec create a typed wrapper over store using codec.
+func NewTypedWithCodec[T any](store Store, codec Codec) *Typed[T] {
+	return &Typed[T]{store: store, codec: codec}
+}
+
+// NewTypedCache Create a new cache driver by adapter name and config setting,
+// and wrap it with the codec registered by codecName.
+func NewTypedCache[T any](adapterName string, config Config, codecName string) (*Typed[T], error) {
+	store, err := NewStore(adapterName, config)
+	if err != nil {
+		return nil, err
+	}
+	return NewTyped[T](store, codecName)
+}
+
+// Store returns the underlying store.
+func (t *Typed[T]) Store() Store {
+	return t.store
+}
+
+// Get get cached value by key, found is false if key does not exist.
+func (t *Typed[T]) Get(ctx context.Context, key string) (val T, found bool, err error) {
+	v, found, err := t.store.Get(ctx, key)
+	if err != nil || !found {
+		return val, false, err
+	}
+	if val, err = t.decode(v); err != nil {
+		return val, false, fmt.Errorf("cache: decode key %s err:%v", key, err)
+	}
+	return val, true, nil
+}
+
+// GetMulti is a batch version of Get, missing keys are absent from the result.
+func (t *Typed[T]) GetMulti(ctx context.Context, keys []string) (map[string]T, error) {
+	values, err := t.store.GetMulti(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]T, len(values))
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		val, err := t.decode(v)
+		if err != nil {
+			return nil, fmt.Errorf("cache: decode key %s err:%v", keys[i], err)
+		}
+		result[keys[i]] = val
+	}
+	return result, nil
+}
+
+// Put set cached value with key and expire time.
+func (t *Typed[T]) Put(ctx context.Context, key string, val T, timeout time.Duration) error {
+	data, err := t.codec.Marshal(val)
+	if err != nil {
+		return fmt.Errorf("cache: encode key %s err:%v", key, err)
+	}
+	return t.store.Put(ctx, key, data, timeout)
+}
+
+// Delete delete cached value by key.
+func (t *Typed[T]) Delete(ctx context.Context, key string) error {
+	return t.store.Delete(ctx, key)
+}
+
+// IsExist check if cached value exists or not.
+func (t *Typed[T]) IsExist(ctx context.Context, key string) (bool, error) {
+	return t.store.IsExist(ctx, key)
+}
+
+// decode convert the raw value returned by an adapter to T.
+func (t *Typed[T]) decode(v interface{}) (val T, err error) {
+	var data []byte
+	switch raw := v.(type) {
+	case []byte:
+		data = raw
+	case string:
+		data = []byte(raw)
+	default:
+		return val, fmt.Errorf("unexpected raw value type %T", v)
+	}
+	err = t.codec.Unmarshal(data, &val)
+	return val, err
+}
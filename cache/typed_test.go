@@ -0,0 +1,111 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dbunion/com/cache"
+	_ "github.com/dbunion/com/cache/codec/msgpack"
+	_ "github.com/dbunion/com/cache/codec/protobuf"
+	_ "github.com/dbunion/com/cache/gocache"
+	_ "github.com/dbunion/com/cache/redis"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type user struct {
+	Name  string
+	Age   int
+	Tags  []string
+	Admin bool
+}
+
+func testTypedStruct(t *testing.T, s cache.Store, codecName string) {
+	ctx := context.Background()
+	users, err := cache.NewTyped[user](s, codecName)
+	if err != nil {
+		t.Fatalf("create typed cache error, err:%v", err)
+	}
+
+	expect := user{Name: "author", Age: 18, Tags: []string{"a", "b"}, Admin: true}
+	assert.Nil(t, users.Put(ctx, "user:1", expect, 10*time.Second))
+
+	v, found, err := users.Get(ctx, "user:1")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Equal(t, expect, v)
+
+	_, found, err = users.Get(ctx, "user:2")
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	vv, err := users.GetMulti(ctx, []string{"user:1", "user:2"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]user{"user:1": expect}, vv)
+
+	assert.Nil(t, users.Delete(ctx, "user:1"))
+	exist, err := users.IsExist(ctx, "user:1")
+	assert.Nil(t, err)
+	assert.False(t, exist)
+}
+
+func testTypedProto(t *testing.T, s cache.Store) {
+	ctx := context.Background()
+	stamps, err := cache.NewTyped[*timestamppb.Timestamp](s, cache.CodecProtobuf)
+	if err != nil {
+		t.Fatalf("create typed cache error, err:%v", err)
+	}
+
+	expect := timestamppb.New(time.Unix(1600000000, 42))
+	assert.Nil(t, stamps.Put(ctx, "stamp", expect, 10*time.Second))
+
+	v, found, err := stamps.Get(ctx, "stamp")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.True(t, proto.Equal(expect, v))
+}
+
+func testTyped(t *testing.T, s cache.Store) {
+	for _, codecName := range []string{cache.CodecJSON, cache.CodecGob, cache.CodecMsgpack} {
+		t.Run(codecName, func(t *testing.T) {
+			testTypedStruct(t, s, codecName)
+		})
+	}
+	t.Run(cache.CodecProtobuf, func(t *testing.T) {
+		testTypedProto(t, s)
+	})
+}
+
+func TestTypedGoCache(t *testing.T) {
+	s, err := cache.NewStore(cache.TypeGoCache, cache.Config{})
+	if err != nil {
+		t.Fatalf("create store error, err:%v", err)
+	}
+	testTyped(t, s)
+}
+
+func TestTypedRedis(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	defer mr.Close()
+
+	s, err := cache.NewStore(cache.TypeRedisCache, cache.Config{Server: mr.Host(), Port: int64(mr.Server().Addr().Port)})
+	if err != nil {
+		t.Fatalf("create store error, err:%v", err)
+	}
+	testTyped(t, s)
+}
+
+func TestUnknownCodec(t *testing.T) {
+	s, err := cache.NewStore(cache.TypeGoCache, cache.Config{})
+	if err != nil {
+		t.Fatalf("create store error, err:%v", err)
+	}
+	_, err = cache.NewTyped[user](s, "unknown")
+	assert.NotNil(t, err)
+}
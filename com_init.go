@@ -2,13 +2,30 @@ package com
 
 import (
 	// package init
+	_ "github.com/dbunion/com/cache/bounded"
+	_ "github.com/dbunion/com/cache/codec/msgpack"
+	_ "github.com/dbunion/com/cache/codec/protobuf"
 	_ "github.com/dbunion/com/cache/gocache"
 	_ "github.com/dbunion/com/cache/memcache"
 	_ "github.com/dbunion/com/cache/redis"
+	_ "github.com/dbunion/com/cache/tiered"
+	_ "github.com/dbunion/com/config/consul"
+	_ "github.com/dbunion/com/config/env"
+	_ "github.com/dbunion/com/config/etcd"
 	_ "github.com/dbunion/com/config/file"
+	_ "github.com/dbunion/com/config/flags"
+	_ "github.com/dbunion/com/lock/memory"
+	_ "github.com/dbunion/com/lock/mysql"
+	_ "github.com/dbunion/com/lock/redis"
 	_ "github.com/dbunion/com/log/logrus"
+	_ "github.com/dbunion/com/log/slog"
+	_ "github.com/dbunion/com/log/zap"
 	_ "github.com/dbunion/com/log/zssky"
 	_ "github.com/dbunion/com/uid/mysql"
 	_ "github.com/dbunion/com/uid/redis"
+	_ "github.com/dbunion/com/uid/shortcode"
 	_ "github.com/dbunion/com/uid/snowflake"
+	_ "github.com/dbunion/com/uid/sonyflake"
+	_ "github.com/dbunion/com/uid/ulid"
+	_ "github.com/dbunion/com/uid/uuidv7"
 )
@@ -0,0 +1,302 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// tags of bound structs, e.g.
+//
+//	type DB struct {
+//		Host    string        `config:"host" validate:"required"`
+//		Port    int           `config:"port" default:"3306" validate:"min=1,max=65535"`
+//		Mode    string        `config:"mode" default:"rw" validate:"oneof=rw ro"`
+//		Timeout time.Duration `config:"timeout" default:"3s" validate:"min=1ms"`
+//	}
+const (
+	// TagName - name of the key of a field, the field name if empty
+	TagName = "config"
+	// TagDefault - value of a field whose key is not set
+	TagDefault = "default"
+	// TagValidate - comma separated rules, required, min=x, max=x and oneof=a b c,
+	// min and max are the length of strings, slices and maps
+	TagValidate = "validate"
+)
+
+// FieldError - a field which failed validation
+type FieldError struct {
+	Key     string
+	Rule    string
+	Message string
+}
+
+// Error implement error.
+func (e FieldError) Error() string {
+	return e.Key + " " + e.Message
+}
+
+// ValidationError - all fields of a struct which failed validation
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error implement error.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Error())
+	}
+	return "config: invalid " + strings.Join(msgs, ", ")
+}
+
+// newDecoder create a decoder into out, strings are converted to the
+// type of the field, e.g. "3s" into time.Duration and "a,b" into []string.
+func newDecoder(out interface{}, meta *mapstructure.Metadata) (*mapstructure.Decoder, error) {
+	return mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		WeaklyTypedInput: true,
+		Metadata:         meta,
+		Result:           out,
+		TagName:          TagName,
+	})
+}
+
+// Decode bind input, a value of Get or AllSettings, into the struct out
+// point to. Fields whose key is not in input get their default, then the
+// struct is validated and all failed fields are returned in a *ValidationError.
+func Decode(input interface{}, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: unmarshal into %T, want a pointer to struct", out)
+	}
+
+	meta := &mapstructure.Metadata{}
+	decoder, err := newDecoder(out, meta)
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(input); err != nil {
+		return fmt.Errorf("config: unmarshal err:%v", err)
+	}
+	set := make(map[string]bool, len(meta.Keys))
+	for _, key := range meta.Keys {
+		set[strings.ToLower(key)] = true
+	}
+	if err := setDefaults("", v.Elem(), set); err != nil {
+		return err
+	}
+
+	var fields []FieldError
+	validate("", v.Elem(), &fields)
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// fieldKey return the key of a struct field.
+func fieldKey(prefix string, field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get(TagName), ",")[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// setDefaults set the default of the fields whose key is not in set,
+// nested structs included.
+func setDefaults(prefix string, v reflect.Value, set map[string]bool) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		key := fieldKey(prefix, field)
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}) {
+			if err := setDefaults(key, fv, set); err != nil {
+				return err
+			}
+			continue
+		}
+		def, ok := field.Tag.Lookup(TagDefault)
+		if !ok || set[key] {
+			continue
+		}
+		decoder, err := newDecoder(fv.Addr().Interface(), nil)
+		if err != nil {
+			return err
+		}
+		if err := decoder.Decode(def); err != nil {
+			return fmt.Errorf("config: invalid default %q of %s err:%v", def, key, err)
+		}
+	}
+	return nil
+}
+
+// validate check the rules of the fields of v, nested structs included.
+func validate(prefix string, v reflect.Value, fields *[]FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		key := fieldKey(prefix, field)
+		if rules := field.Tag.Get(TagValidate); rules != "" {
+			for _, rule := range strings.Split(rules, ",") {
+				name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+				if msg := check(fv, name, param); msg != "" {
+					*fields = append(*fields, FieldError{Key: key, Rule: name, Message: msg})
+				}
+			}
+		}
+
+		if fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}) {
+			validate(key, fv, fields)
+		}
+	}
+}
+
+// check a rule on v, return why it failed or "".
+func check(v reflect.Value, rule, param string) string {
+	switch rule {
+	case "required":
+		if v.IsZero() {
+			return "is required"
+		}
+	case "min", "max":
+		limit, err := parseLimit(v, param)
+		if err != nil {
+			return fmt.Sprintf("has invalid %s %q", rule, param)
+		}
+		n, ok := size(v)
+		if !ok {
+			return fmt.Sprintf("does not support %s", rule)
+		}
+		if rule == "min" && n < limit {
+			return fmt.Sprintf("must be at least %s", param)
+		}
+		if rule == "max" && n > limit {
+			return fmt.Sprintf("must be at most %s", param)
+		}
+	case "oneof":
+		val := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(param) {
+			if val == option {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of [%s]", param)
+	default:
+		return fmt.Sprintf("has unknown rule %q", rule)
+	}
+	return ""
+}
+
+// parseLimit parse the param of min or max, a duration for durations.
+func parseLimit(v reflect.Value, param string) (float64, error) {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(param)
+		return float64(d), err
+	}
+	return strconv.ParseFloat(param, 64)
+}
+
+// size return the number of v or the length of strings, slices and maps.
+func size(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	}
+	return 0, false
+}
+
+// Binding - a struct bound to a key of a config. A reload which changed
+// the key decode a new struct and swap it in, so readers of Load always
+// see a whole one. A reload which fail to decode keep the last struct.
+type Binding struct {
+	cfg   Config
+	key   string
+	typ   reflect.Type
+	value atomic.Value
+
+	mutex sync.Mutex
+	err   error
+}
+
+// Bind unmarshal key, or all settings if key is empty, into out and
+// keep a copy of it up to date. out is a pointer to struct.
+func Bind(cfg Config, key string, out interface{}) (*Binding, error) {
+	b := &Binding{cfg: cfg, key: key, typ: reflect.TypeOf(out)}
+	if err := b.unmarshal(out); err != nil {
+		return nil, err
+	}
+	current := reflect.New(b.typ.Elem())
+	current.Elem().Set(reflect.ValueOf(out).Elem())
+	b.value.Store(current.Interface())
+
+	if key == "" {
+		cfg.OnChange(func([]Change) { b.reload() })
+	} else {
+		cfg.Watch(key, func(old, new interface{}) { b.reload() })
+	}
+	return b, nil
+}
+
+// unmarshal the bound key into out.
+func (b *Binding) unmarshal(out interface{}) error {
+	if b.key == "" {
+		return b.cfg.UnmarshalAll(out)
+	}
+	return b.cfg.Unmarshal(b.key, out)
+}
+
+// reload decode the changed key into a new struct.
+func (b *Binding) reload() {
+	out := reflect.New(b.typ.Elem()).Interface()
+	err := b.unmarshal(out)
+	if err == nil {
+		b.value.Store(out)
+	}
+
+	b.mutex.Lock()
+	b.err = err
+	b.mutex.Unlock()
+}
+
+// Load return the current struct, a pointer of the type given to Bind.
+// It must not be modified.
+func (b *Binding) Load() interface{} {
+	return b.value.Load()
+}
+
+// Err return the error of the last reload, nil if it succeeded.
+func (b *Binding) Err() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.err
+}
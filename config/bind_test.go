@@ -0,0 +1,77 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testDB struct {
+	Host    string        `config:"host" validate:"required"`
+	Port    int           `config:"port" default:"3306" validate:"min=1,max=65535"`
+	Mode    string        `config:"mode" default:"rw" validate:"oneof=rw ro"`
+	Timeout time.Duration `config:"timeout" default:"3s" validate:"min=1ms"`
+	Tags    []string      `config:"tags" default:"a,b" validate:"max=3"`
+}
+
+type testApp struct {
+	Name string `validate:"required,min=2"`
+	DB   testDB `config:"db"`
+}
+
+func TestDecode(t *testing.T) {
+	var app testApp
+	err := Decode(map[string]interface{}{
+		"name": "test",
+		"db":   map[string]interface{}{"host": "127.0.0.1", "port": "3307", "timeout": "1s"},
+	}, &app)
+	assert.Nil(t, err)
+	assert.Equal(t, testApp{Name: "test", DB: testDB{
+		Host:    "127.0.0.1",
+		Port:    3307,
+		Mode:    "rw",
+		Timeout: time.Second,
+		Tags:    []string{"a", "b"},
+	}}, app)
+
+	// a key set to zero override the default
+	var db testDB
+	assert.Nil(t, Decode(map[string]interface{}{"host": "h", "mode": "ro", "tags": []string{}}, &db))
+	assert.Equal(t, "ro", db.Mode)
+	assert.Empty(t, db.Tags)
+}
+
+func TestDecodeValidation(t *testing.T) {
+	var app testApp
+	err := Decode(map[string]interface{}{
+		"name": "t",
+		"db": map[string]interface{}{
+			"port":    70000,
+			"mode":    "wo",
+			"timeout": "0s",
+			"tags":    "a,b,c,d",
+		},
+	}, &app)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("want validation error, err:%v", err)
+	}
+	assert.Equal(t, []FieldError{
+		{Key: "name", Rule: "min", Message: "must be at least 2"},
+		{Key: "db.host", Rule: "required", Message: "is required"},
+		{Key: "db.port", Rule: "max", Message: "must be at most 65535"},
+		{Key: "db.mode", Rule: "oneof", Message: "must be one of [rw ro]"},
+		{Key: "db.timeout", Rule: "min", Message: "must be at least 1ms"},
+		{Key: "db.tags", Rule: "max", Message: "must be at most 3"},
+	}, verr.Fields)
+	assert.Contains(t, err.Error(), "config: invalid name must be at least 2, db.host is required")
+
+	// decode errors
+	assert.NotNil(t, Decode(map[string]interface{}{"port": "abc"}, &testDB{}))
+	assert.NotNil(t, Decode(nil, testDB{}))
+	assert.NotNil(t, Decode(nil, &struct {
+		Port int `default:"abc"`
+	}{}))
+}
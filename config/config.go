@@ -1,7 +1,10 @@
 package config
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"time"
 )
 
@@ -12,6 +15,12 @@ const (
 	TypeETCD = "etcd"
 	// TypeConsul - config type consul
 	TypeConsul = "consul"
+	// TypeEnv - config type environment variables
+	TypeEnv = "env"
+	// TypeFlag - config type command line flags
+	TypeFlag = "flag"
+	// TypeLayered - config type layered
+	TypeLayered = "layered"
 )
 
 // Param - config param
@@ -25,12 +34,41 @@ type Param struct {
 	Password string `json:"password"`
 	Prefix   string `json:"prefix"`
 	Port     int64  `json:"port"`
+	// host:port of every node, Server and Port are used if empty
+	Endpoints []string `json:"endpoints"`
+	// tls is enabled if any file is set
+	CAFile   string `json:"ca_file"`
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	// acl token and datacenter of consul
+	Token      string `json:"token"`
+	Datacenter string `json:"datacenter"`
+	// format of values, json, yaml or raw, default raw
+	Format string `json:"format"`
 
 	// file param
 	Path string `json:"path"`
 	Name string `json:"name"`
 	File string `json:"file"`
 
+	// env and flag param, Prefix is the prefix of env names,
+	// names in Mapping are mapped to their key instead of by name
+	Mapping map[string]string `json:"mapping"`
+	// flags set on the command line override, parsed by the caller
+	FlagSet *flag.FlagSet `json:"-"`
+
+	// versions of writable adapters, a file for file and a key prefix for
+	// etcd and consul, the default is next to the config
+	History string `json:"history"`
+
+	// layered param, from the lowest priority to the highest
+	Layers []Layer `json:"layers"`
+
+	// resolve the secret references of values, needs package secret,
+	// enc values are decrypted with the keys of the Keyring file
+	Secrets bool   `json:"secrets"`
+	Keyring string `json:"keyring"`
+
 	// Extend fields
 	// Extended fields can be used if there is a special implementation
 	Extend1 string `json:"extend_1"`
@@ -67,15 +105,41 @@ type Config interface {
 	IsExist(key string) bool
 	// get all config value
 	AllSettings() map[string]interface{}
+	// bind the value of key into a struct, see Decode
+	Unmarshal(key string, out interface{}) error
+	// bind all config value into a struct, see Decode
+	UnmarshalAll(out interface{}) error
+	// call fn with the old and new value when key or any key below it changed
+	Watch(key string, fn func(old, new interface{}))
+	// call fn with the changed keys of every reload
+	OnChange(fn func(changes []Change))
 	// start gc routine based on config param settings.
 	StartAndGC(param Param) error
 }
 
+// ErrorNotifier is implemented by adapters which reload in the background,
+// a failed reload keep the last settings and is reported to fn.
+type ErrorNotifier interface {
+	OnError(fn func(err error))
+}
+
 // Instance is a function create a new Config Instance
 type Instance func() Config
 
 var adapters = make(map[string]Instance)
 
+// secrets wrap a config which resolve secret references, see RegisterSecrets
+var secrets func(c Config, param Param) (Config, error)
+
+// RegisterSecrets makes the resolver of secret references available to
+// NewConfig, it is called by package secret.
+func RegisterSecrets(wrap func(c Config, param Param) (Config, error)) {
+	if wrap == nil {
+		panic("config: RegisterSecrets wrap is nil")
+	}
+	secrets = wrap
+}
+
 // Register makes a Config adapter available by the adapter name.
 // If Register is called twice with the same name or if driver is nil,
 // it panics.
@@ -90,16 +154,32 @@ func Register(name string, adapter Instance) {
 }
 
 // NewConfig Create a new config driver by adapter name and config string.
+// The secret references of values are resolved if config.Secrets is set.
 func NewConfig(adapterName string, config Param) (adapter Config, err error) {
 	instanceFunc, ok := adapters[adapterName]
 	if !ok {
 		err = fmt.Errorf("config: unknown adapter name %q (forgot to import?)", adapterName)
 		return
 	}
+	if config.Secrets && secrets == nil {
+		err = errors.New("config: secrets are not supported (forgot to import secret?)")
+		return
+	}
 	adapter = instanceFunc()
 	err = adapter.StartAndGC(config)
 	if err != nil {
 		adapter = nil
+		return
+	}
+	if config.Secrets {
+		wrapped, err := secrets(adapter, config)
+		if err != nil {
+			if closer, ok := adapter.(io.Closer); ok {
+				_ = closer.Close()
+			}
+			return nil, err
+		}
+		adapter = wrapped
 	}
 	return
 }
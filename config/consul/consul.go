@@ -0,0 +1,397 @@
+package consul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dbunion/com/config"
+	"github.com/dbunion/com/config/kv"
+)
+
+const (
+	defaultPort  = 8500
+	defaultWait  = 5 * time.Minute
+	defaultRetry = time.Second
+	// at most 64 operations in a transaction
+	maxTxnOps      = 64
+	defaultHistory = "config_history"
+)
+
+// pair - an entry of the consul kv api, Value is base64 decoded by json
+type pair struct {
+	Key   string
+	Value []byte
+}
+
+// Config is consul kv config adapter. The tree under Param.Prefix is
+// loaded, /prefix/db/host is db.host and values are decoded by Param.Format.
+// Changes are picked up by blocking queries on the tree. Writes are done
+// in transactions, one per 64 keys, with the versions kept under
+// Param.History, config_history/prefix/ if empty.
+type Config struct {
+	*kv.Store
+	*config.Versions
+
+	address    string
+	prefix     string
+	token      string
+	datacenter string
+	format     string
+	history    string
+	client     *http.Client
+	wait       time.Duration
+	retry      time.Duration
+
+	// X-Consul-Index of the loaded tree
+	index uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewConsulConfig create new consul config.
+func NewConsulConfig() config.Config {
+	return &Config{
+		Store: kv.NewStore(),
+		wait:  defaultWait,
+		retry: defaultRetry,
+	}
+}
+
+// do send a request of the http api, path is like /v1/kv/app.
+func (c *Config) do(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Response, error) {
+	if c.datacenter != "" {
+		query.Set("dc", c.datacenter)
+	}
+	u := fmt.Sprintf("%s%s?%s", c.address, path, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	return c.client.Do(req)
+}
+
+// fetch read the tree, blocking until it changes after index if index is not 0.
+func (c *Config) fetch(ctx context.Context, index uint64) (map[string]interface{}, uint64, error) {
+	query := url.Values{}
+	query.Set("recurse", "true")
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", fmt.Sprintf("%dms", c.wait.Milliseconds()))
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/v1/kv/"+c.prefix, query, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	var pairs []pair
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := json.NewDecoder(resp.Body).Decode(&pairs); err != nil {
+			return nil, 0, err
+		}
+	case http.StatusNotFound:
+		// empty tree
+	default:
+		return nil, 0, fmt.Errorf("consul kv %s error, status:%s", c.prefix, resp.Status)
+	}
+
+	next, err := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid consul index %q", resp.Header.Get("X-Consul-Index"))
+	}
+
+	values := make(map[string]interface{}, len(pairs))
+	for _, p := range pairs {
+		if strings.HasSuffix(p.Key, "/") {
+			// folder
+			continue
+		}
+		val, err := kv.Decode(c.format, p.Value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("decode consul key %s err:%v", p.Key, err)
+		}
+		values[kv.Key(c.prefix, p.Key)] = val
+	}
+	return values, next, nil
+}
+
+// watch follow changes of the tree until closed.
+func (c *Config) watch() {
+	defer c.wg.Done()
+	for {
+		values, index, err := c.fetch(c.ctx, c.index)
+		if err == nil {
+			switch {
+			case index < c.index:
+				// the index went backwards, e.g. a restored snapshot, load again
+				c.index = 0
+			case index > c.index:
+				c.Store.Reset(values)
+				c.index = index
+			}
+			continue
+		}
+
+		if c.ctx.Err() == nil {
+			c.NotifyError(fmt.Errorf("config: watch consul config err:%v", err))
+		}
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(c.retry):
+		}
+	}
+}
+
+// path return the consul key of a dotted key.
+func (c *Config) path(key string) string {
+	return c.prefix + strings.ReplaceAll(key, ".", "/")
+}
+
+// txnOp - an operation of a transaction
+type txnOp struct {
+	KV txnKV
+}
+
+// txnKV - a kv operation, Value is base64 encoded by json
+type txnKV struct {
+	Verb  string
+	Key   string
+	Value []byte `json:",omitempty"`
+}
+
+// write apply a change in transactions of at most 64 keys.
+func (c *Config) write(ctx context.Context, w config.Write) error {
+	puts, deletes := c.Store.Plan(w.Puts, w.Deletes)
+	ops := make([]txnOp, 0, len(puts)+len(deletes))
+	for _, key := range deletes {
+		ops = append(ops, txnOp{KV: txnKV{Verb: "delete", Key: c.path(key)}})
+	}
+	for key, val := range puts {
+		data, err := kv.Encode(c.format, val)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, txnOp{KV: txnKV{Verb: "set", Key: c.path(key), Value: data}})
+		// as loaded from consul
+		if puts[key], err = kv.Decode(c.format, data); err != nil {
+			return err
+		}
+	}
+
+	for len(ops) > 0 {
+		n := len(ops)
+		if n > maxTxnOps {
+			n = maxTxnOps
+		}
+		body, err := json.Marshal(ops[:n])
+		if err != nil {
+			return err
+		}
+		resp, err := c.do(ctx, http.MethodPut, "/v1/txn", url.Values{}, body)
+		if err != nil {
+			return fmt.Errorf("write consul config err:%v", err)
+		}
+		msg, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("write consul config error, status:%s %s", resp.Status, msg)
+		}
+		ops = ops[n:]
+	}
+	// read your writes, the watch bring the same change later
+	c.Store.Apply(puts, deletes)
+	return nil
+}
+
+// history keep versions under a prefix, a key per version.
+type history struct {
+	c      *Config
+	prefix string
+}
+
+// Append record v and set its ID, retried if another writer took the ID.
+func (h *history) Append(ctx context.Context, v *config.Version) error {
+	for {
+		query := url.Values{}
+		query.Set("keys", "true")
+		resp, err := h.c.do(ctx, http.MethodGet, "/v1/kv/"+h.prefix, query, nil)
+		if err != nil {
+			return err
+		}
+		var keys []string
+		if resp.StatusCode == http.StatusOK {
+			err = json.NewDecoder(resp.Body).Decode(&keys)
+		} else if resp.StatusCode != http.StatusNotFound {
+			err = fmt.Errorf("consul kv %s error, status:%s", h.prefix, resp.Status)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		v.ID = 1
+		for _, key := range keys {
+			if id, err := strconv.ParseInt(strings.TrimPrefix(key, h.prefix), 10, 64); err == nil && id >= v.ID {
+				v.ID = id + 1
+			}
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		// cas=0 only create the key
+		query = url.Values{}
+		query.Set("cas", "0")
+		resp, err = h.c.do(ctx, http.MethodPut, fmt.Sprintf("/v1/kv/%s%020d", h.prefix, v.ID), query, data)
+		if err != nil {
+			return err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("consul kv %s error, status:%s", h.prefix, resp.Status)
+		}
+		if strings.TrimSpace(string(body)) == "true" {
+			return nil
+		}
+	}
+}
+
+// List get all versions, the oldest first.
+func (h *history) List(ctx context.Context) ([]config.Version, error) {
+	query := url.Values{}
+	query.Set("recurse", "true")
+	resp, err := h.c.do(ctx, http.MethodGet, "/v1/kv/"+h.prefix, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul kv %s error, status:%s", h.prefix, resp.Status)
+	}
+	var pairs []pair
+	if err := json.NewDecoder(resp.Body).Decode(&pairs); err != nil {
+		return nil, err
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+
+	versions := make([]config.Version, 0, len(pairs))
+	for _, p := range pairs {
+		var v config.Version
+		if err := json.Unmarshal(p.Value, &v); err != nil {
+			return nil, fmt.Errorf("invalid config version %s err:%v", p.Key, err)
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// start load the tree and start watching it.
+func (c *Config) start() error {
+	// keys have no leading slash in consul
+	c.prefix = strings.TrimPrefix(kv.Prefix(c.prefix), "/")
+	if c.history == "" {
+		c.history = strings.TrimSuffix(defaultHistory+"/"+strings.Trim(c.prefix, "/"), "/") + "/"
+	}
+	if strings.HasPrefix(c.history, c.prefix) {
+		return errors.New("consul config history can not be under prefix")
+	}
+	c.Versions = config.NewVersions(&history{c: c, prefix: c.history}, c.AllSettings, c.write)
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	values, index, err := c.fetch(c.ctx, 0)
+	if err != nil {
+		c.cancel()
+		return fmt.Errorf("load consul config err:%v", err)
+	}
+	c.Store.Reset(values)
+	c.index = index
+
+	c.wg.Add(1)
+	go c.watch()
+	return nil
+}
+
+// Close stop watching.
+func (c *Config) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.wg.Wait()
+	return nil
+}
+
+// StartAndGC start consul config adapter.
+// config is like {"server":"127.0.0.1", "port":8500, "prefix":"app", "token":"xxx", "datacenter":"dc1", "format":"json", "history":"config_history/app/"}
+func (c *Config) StartAndGC(param config.Param) error {
+	if param.Prefix == "" {
+		return errors.New("consul config prefix can not be empty")
+	}
+	if err := kv.CheckFormat(param.Format); err != nil {
+		return err
+	}
+	c.prefix = param.Prefix
+	c.token = param.Token
+	c.datacenter = param.Datacenter
+	c.format = param.Format
+	c.history = strings.TrimPrefix(param.History, "/")
+
+	address := ""
+	if len(param.Endpoints) > 0 {
+		address = param.Endpoints[0]
+	} else {
+		if param.Server == "" {
+			return errors.New("consul config server can not be empty")
+		}
+		port := param.Port
+		if port == 0 {
+			port = defaultPort
+		}
+		address = fmt.Sprintf("%s:%d", param.Server, port)
+	}
+
+	tlsConfig, err := param.TLSConfig()
+	if err != nil {
+		return err
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	if !strings.Contains(address, "://") {
+		address = scheme + "://" + address
+	}
+	c.address = strings.TrimSuffix(address, "/")
+	c.client = &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	}}
+
+	return c.start()
+}
+
+func init() {
+	config.Register(config.TypeConsul, NewConsulConfig)
+}
@@ -0,0 +1,285 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConsul serve the kv api of a tree, blocking queries wait for a change.
+type fakeConsul struct {
+	mutex   sync.Mutex
+	index   uint64
+	kvs     map[string]string
+	changed chan struct{}
+	queries []string
+	txns    int
+}
+
+func newFakeConsul(kvs map[string]string) *fakeConsul {
+	return &fakeConsul{index: 10, kvs: kvs, changed: make(chan struct{})}
+}
+
+// set change the tree, an empty value delete the key.
+func (f *fakeConsul) set(key, value string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if value == "" {
+		delete(f.kvs, key)
+	} else {
+		f.kvs[key] = value
+	}
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Consul-Token") != "secret" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodPut {
+		f.write(w, r)
+		return
+	}
+	prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+
+	f.mutex.Lock()
+	f.queries = append(f.queries, r.URL.RawQuery)
+	index, changed := f.index, f.changed
+	f.mutex.Unlock()
+	if i, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); i > 0 && i >= index {
+		wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var pairs []map[string]interface{}
+	var keys []string
+	for k, v := range f.kvs {
+		if strings.HasPrefix(k, prefix) {
+			pairs = append(pairs, map[string]interface{}{"Key": k, "Value": []byte(v)})
+			keys = append(keys, k)
+		}
+	}
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+	if len(pairs) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.URL.Query().Get("keys") != "" {
+		_ = json.NewEncoder(w).Encode(keys)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(pairs)
+}
+
+// write serve transactions and cas puts.
+func (f *fakeConsul) write(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if r.URL.Path == "/v1/txn" {
+		var ops []txnOp
+		if err := json.NewDecoder(r.Body).Decode(&ops); err != nil || len(ops) > maxTxnOps {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.txns++
+		for _, op := range ops {
+			switch op.KV.Verb {
+			case "set":
+				f.kvs[op.KV.Key] = string(op.KV.Value)
+			case "delete":
+				delete(f.kvs, op.KV.Key)
+			}
+		}
+	} else {
+		key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		if _, ok := f.kvs[key]; ok && r.URL.Query().Get("cas") == "0" {
+			_, _ = w.Write([]byte("false"))
+			return
+		}
+		value, _ := io.ReadAll(r.Body)
+		f.kvs[key] = string(value)
+	}
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+	_, _ = w.Write([]byte("true"))
+}
+
+// newTestConfig start a config on a fake consul.
+func newTestConfig(t *testing.T, f *fakeConsul, format string) (*Config, *httptest.Server) {
+	server := httptest.NewServer(f)
+	c := NewConsulConfig().(*Config)
+	c.wait = time.Second
+	c.retry = 10 * time.Millisecond
+	err := c.StartAndGC(config.Param{
+		Endpoints:  []string{server.URL},
+		Prefix:     "app",
+		Token:      "secret",
+		Datacenter: "dc1",
+		Format:     format,
+	})
+	if err != nil {
+		t.Fatalf("start consul config error, err:%v", err)
+	}
+	return c, server
+}
+
+func TestConsulConfig(t *testing.T) {
+	f := newFakeConsul(map[string]string{
+		"app/":           "",
+		"app/name":       "test",
+		"app/db/port":    "3306",
+		"app/db/timeout": "3s",
+		"other/key":      "other",
+	})
+	c, server := newTestConfig(t, f, "")
+	defer server.Close()
+	defer c.Close()
+
+	assert.Equal(t, "test", c.GetString("name"))
+	assert.Equal(t, 3306, c.GetInt("db.port"))
+	assert.Equal(t, 3*time.Second, c.GetDuration("db.timeout"))
+	assert.False(t, c.IsExist("key"))
+
+	// blocking queries pick up changes
+	f.set("app/db/port", "3307")
+	assert.Eventually(t, func() bool { return c.GetInt("db.port") == 3307 }, time.Second, time.Millisecond)
+	f.set("app/name", "")
+	assert.Eventually(t, func() bool { return !c.IsExist("name") }, time.Second, time.Millisecond)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	assert.Contains(t, f.queries[0], "dc=dc1")
+	assert.Contains(t, f.queries[0], "recurse=true")
+	assert.Contains(t, f.queries[1], "index=10")
+}
+
+func TestConsulConfigSibling(t *testing.T) {
+	f := newFakeConsul(map[string]string{
+		"app/name":         "test",
+		"application/name": "sibling",
+		"apps":             "sibling",
+	})
+	c, server := newTestConfig(t, f, "")
+	defer server.Close()
+	defer c.Close()
+
+	// keys of a sibling sharing the prefix are not loaded
+	assert.Equal(t, map[string]interface{}{"name": "test"}, c.AllSettings())
+}
+
+func TestConsulConfigFormat(t *testing.T) {
+	f := newFakeConsul(map[string]string{"app/db": `{"host": "127.0.0.1", "port": 3306}`})
+	c, server := newTestConfig(t, f, "json")
+	assert.Equal(t, "127.0.0.1", c.GetString("db.host"))
+	assert.Equal(t, 3306, c.GetInt("db.port"))
+
+	// a value which can not be decoded is reported and the last settings are kept
+	errs := make(chan error, 10)
+	c.OnError(func(err error) { errs <- err })
+	f.set("app/db", "not json")
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "config: watch consul config")
+	case <-time.After(time.Second):
+		t.Fatalf("watch error not notified")
+	}
+	assert.Equal(t, 3306, c.GetInt("db.port"))
+	assert.Nil(t, c.Close())
+	server.Close()
+
+	f = newFakeConsul(map[string]string{"app/cache": "ttl: 10s\nsize: 100\n"})
+	c, server = newTestConfig(t, f, "yaml")
+	defer server.Close()
+	defer c.Close()
+	assert.Equal(t, 10*time.Second, c.GetDuration("cache.ttl"))
+	assert.Equal(t, 100, c.GetInt("cache.size"))
+}
+
+func TestConsulConfigError(t *testing.T) {
+	f := newFakeConsul(map[string]string{"app/db": "not json"})
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	for _, param := range []config.Param{
+		{Endpoints: []string{server.URL}, Prefix: "app", Token: "secret", Format: "json"},
+		{Endpoints: []string{server.URL}, Prefix: "app", Token: "wrong"},
+		{Endpoints: []string{server.URL}, Prefix: "app", Format: "xml"},
+		{Endpoints: []string{server.URL}},
+	} {
+		_, err := config.NewConfig(config.TypeConsul, param)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestConsulConfigWrite(t *testing.T) {
+	f := newFakeConsul(map[string]string{
+		"app/name": `"test"`,
+		"app/db":   `{"host": "127.0.0.1", "port": 3306}`,
+	})
+	c, server := newTestConfig(t, f, "json")
+	defer server.Close()
+	defer c.Close()
+	ctx := config.WithAuthor(context.Background(), "bob")
+
+	first, err := c.Snapshot(ctx)
+	assert.Nil(t, err)
+	// a key of a document rewrite the document
+	assert.Nil(t, c.Set(ctx, "db.port", 3307))
+	assert.Equal(t, 3307, c.GetInt("db.port"))
+	assert.Nil(t, c.Set(ctx, "feature.beta", true))
+	assert.Nil(t, c.Delete(ctx, "name"))
+
+	f.mutex.Lock()
+	assert.JSONEq(t, `{"host": "127.0.0.1", "port": 3307}`, f.kvs["app/db"])
+	assert.Equal(t, "true", f.kvs["app/feature/beta"])
+	assert.NotContains(t, f.kvs, "app/name")
+	assert.Contains(t, f.kvs, "config_history/app/00000000000000000004")
+	f.mutex.Unlock()
+
+	versions, err := c.History(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 4)
+	assert.Equal(t, "bob", versions[3].Author)
+	assert.Equal(t, config.ActionDelete, versions[3].Action)
+
+	assert.Nil(t, c.Rollback(ctx, first.ID))
+	assert.Equal(t, "test", c.GetString("name"))
+	assert.Equal(t, 3306, c.GetInt("db.port"))
+	assert.False(t, c.IsExist("feature"))
+
+	// large changes are split into transactions
+	values := make(map[string]interface{})
+	for i := 0; i < 100; i++ {
+		values[fmt.Sprintf("k%d", i)] = i
+	}
+	f.mutex.Lock()
+	txns := f.txns
+	f.mutex.Unlock()
+	assert.Nil(t, c.Set(ctx, "many", values))
+	assert.Equal(t, 99, c.GetInt("many.k99"))
+	f.mutex.Lock()
+	assert.Equal(t, txns+2, f.txns)
+	f.mutex.Unlock()
+}
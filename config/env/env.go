@@ -0,0 +1,69 @@
+package env
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/dbunion/com/config"
+	"github.com/dbunion/com/config/kv"
+)
+
+// Config is environment variables config adapter. Variables starting
+// with Param.Prefix are loaded, APP_DB_HOST is db.host with prefix APP_,
+// and variables in Param.Mapping are loaded as their mapped key, e.g.
+// {"APP_MAX_CONNS":"db.max_conns"}. The environment is read once at start.
+type Config struct {
+	*kv.Store
+
+	prefix  string
+	mapping map[string]string
+}
+
+// NewEnvConfig create new env config.
+func NewEnvConfig() config.Config {
+	return &Config{Store: kv.NewStore()}
+}
+
+// key return the config key of an env name, "" if it is not loaded.
+func (c *Config) key(name string) string {
+	if key, ok := c.mapping[name]; ok {
+		return key
+	}
+	if c.prefix == "" || !strings.HasPrefix(name, c.prefix) {
+		return ""
+	}
+	name = strings.Trim(strings.TrimPrefix(name, c.prefix), "_")
+	return strings.ToLower(strings.ReplaceAll(name, "_", "."))
+}
+
+// load read the environment.
+func (c *Config) load(environ []string) {
+	values := make(map[string]interface{})
+	for _, entry := range environ {
+		name, val, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		if key := c.key(name); key != "" {
+			values[key] = val
+		}
+	}
+	c.Store.Reset(values)
+}
+
+// StartAndGC start env config adapter.
+// config is like {"prefix":"APP_", "mapping":{"APP_MAX_CONNS":"db.max_conns"}}
+func (c *Config) StartAndGC(param config.Param) error {
+	if param.Prefix == "" && len(param.Mapping) == 0 {
+		return errors.New("env config prefix and mapping can not both be empty")
+	}
+	c.prefix = param.Prefix
+	c.mapping = param.Mapping
+	c.load(os.Environ())
+	return nil
+}
+
+func init() {
+	config.Register(config.TypeEnv, NewEnvConfig)
+}
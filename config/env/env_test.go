@@ -0,0 +1,68 @@
+package env
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/dbunion/com/config"
+	_ "github.com/dbunion/com/config/flags"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvConfig(t *testing.T) {
+	t.Setenv("APP_DB_HOST", "127.0.0.1")
+	t.Setenv("APP_NAME", "test")
+	t.Setenv("APP_MAX_CONNS", "10")
+	t.Setenv("OTHER_NAME", "other")
+
+	c, err := config.NewConfig(config.TypeEnv, config.Param{
+		Prefix:  "APP_",
+		Mapping: map[string]string{"APP_MAX_CONNS": "db.max_conns", "OTHER_NAME": "other"},
+	})
+	if err != nil {
+		t.Fatalf("create env config error, err:%v", err)
+	}
+	assert.Equal(t, "127.0.0.1", c.GetString("db.host"))
+	assert.Equal(t, "test", c.GetString("name"))
+	assert.Equal(t, 10, c.GetInt("db.max_conns"))
+	assert.False(t, c.IsExist("max.conns"))
+	assert.Equal(t, "other", c.GetString("other"))
+
+	_, err = config.NewConfig(config.TypeEnv, config.Param{})
+	assert.NotNil(t, err)
+}
+
+func TestLayeredOverride(t *testing.T) {
+	t.Setenv("APP_DB_PORT", "3307")
+	t.Setenv("APP_DB_USER", "env")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("db.port", 3306, "")
+	fs.String("user", "flag", "")
+	fs.Bool("debug", false, "")
+	assert.Nil(t, fs.Parse([]string{"-db.port=3308", "-debug"}))
+
+	c, err := config.NewLayered(
+		config.Layer{Name: "defaults", Config: config.NewStatic(map[string]interface{}{
+			"db": map[string]interface{}{"host": "127.0.0.1", "port": 3306, "user": "root"},
+		})},
+		config.Layer{Adapter: config.TypeEnv, Param: config.Param{Prefix: "APP_"}},
+		config.Layer{Adapter: config.TypeFlag, Param: config.Param{FlagSet: fs, Mapping: map[string]string{"user": "db.user"}}},
+	)
+	if err != nil {
+		t.Fatalf("create layered config error, err:%v", err)
+	}
+	defer c.Close()
+
+	assert.Equal(t, "127.0.0.1", c.GetString("db.host"))
+	assert.Equal(t, 3308, c.GetInt("db.port"))
+	// flags not set on the command line do not override
+	assert.Equal(t, "env", c.GetString("db.user"))
+	assert.True(t, c.GetBool("debug"))
+	assert.Equal(t, map[string]string{
+		"db.host": "defaults",
+		"db.port": "flag",
+		"db.user": "env",
+		"debug":   "flag",
+	}, c.Sources())
+}
@@ -0,0 +1,291 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dbunion/com/config"
+	"github.com/dbunion/com/config/kv"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	defaultPort        = 2379
+	defaultDialTimeout = 5 * time.Second
+	defaultRetry       = time.Second
+	defaultHistory     = "/config_history"
+)
+
+// Config is etcd config adapter. All keys under Param.Prefix are loaded,
+// /prefix/db/host is db.host, and the prefix is watched from the loaded
+// revision. A broken watch is resumed from the last seen revision, and
+// the keys are loaded again if that revision was compacted. Writes are
+// done in a transaction, with the versions kept under Param.History,
+// /config_history/prefix/ if empty.
+type Config struct {
+	*kv.Store
+	*config.Versions
+
+	prefix  string
+	client  *clientv3.Client
+	kv      clientv3.KV
+	watcher clientv3.Watcher
+	retry   time.Duration
+
+	// last revision applied
+	revision int64
+	// prefix of versions
+	history string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewEtcdConfig create new etcd config.
+func NewEtcdConfig() config.Config {
+	return &Config{Store: kv.NewStore(), retry: defaultRetry}
+}
+
+// load read all keys under prefix.
+func (c *Config) load(ctx context.Context) error {
+	resp, err := c.kv.Get(ctx, c.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+	values := make(map[string]interface{}, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		values[kv.Key(c.prefix, string(item.Key))] = string(item.Value)
+	}
+	c.Store.Reset(values)
+	c.revision = resp.Header.Revision
+	return nil
+}
+
+// apply the events of a watch response.
+func (c *Config) apply(resp clientv3.WatchResponse) {
+	puts := make(map[string]interface{})
+	var deletes []string
+	for _, ev := range resp.Events {
+		key := kv.Key(c.prefix, string(ev.Kv.Key))
+		switch ev.Type {
+		case clientv3.EventTypePut:
+			puts[key] = string(ev.Kv.Value)
+		case clientv3.EventTypeDelete:
+			delete(puts, key)
+			deletes = append(deletes, key)
+		}
+	}
+	if len(puts) > 0 || len(deletes) > 0 {
+		c.Store.Apply(puts, deletes)
+	}
+	if resp.Header.Revision > c.revision {
+		c.revision = resp.Header.Revision
+	}
+}
+
+// watch follow changes of prefix until closed.
+func (c *Config) watch() {
+	defer c.wg.Done()
+	for {
+		ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(c.ctx))
+		ch := c.watcher.Watch(ctx, c.prefix, clientv3.WithPrefix(), clientv3.WithRev(c.revision+1))
+		for resp := range ch {
+			if resp.CompactRevision != 0 {
+				// changes since revision are lost, start over
+				if err := c.load(c.ctx); err != nil {
+					c.NotifyError(fmt.Errorf("config: reload etcd config err:%v", err))
+				}
+				break
+			}
+			if err := resp.Err(); err != nil {
+				c.NotifyError(fmt.Errorf("config: watch etcd config err:%v", err))
+				break
+			}
+			c.apply(resp)
+		}
+		cancel()
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(c.retry):
+		}
+	}
+}
+
+// path return the etcd key of a dotted key.
+func (c *Config) path(key string) string {
+	return c.prefix + strings.ReplaceAll(key, ".", "/")
+}
+
+// write apply a change in one transaction.
+func (c *Config) write(ctx context.Context, w config.Write) error {
+	puts, deletes := c.Store.Plan(w.Puts, w.Deletes)
+	ops := make([]clientv3.Op, 0, len(puts)+len(deletes))
+	for _, key := range deletes {
+		ops = append(ops, clientv3.OpDelete(c.path(key)))
+	}
+	for key, val := range puts {
+		data, err := kv.Encode(kv.FormatRaw, val)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, clientv3.OpPut(c.path(key), string(data)))
+		// as loaded from etcd
+		puts[key] = string(data)
+	}
+	if _, err := c.kv.Txn(ctx).Then(ops...).Commit(); err != nil {
+		return fmt.Errorf("write etcd config err:%v", err)
+	}
+	// read your writes, the watch bring the same change later
+	c.Store.Apply(puts, deletes)
+	return nil
+}
+
+// history keep versions under a prefix, a key per version.
+type history struct {
+	kv     clientv3.KV
+	prefix string
+}
+
+// Append record v and set its ID, retried if another writer took the ID.
+func (h *history) Append(ctx context.Context, v *config.Version) error {
+	for {
+		resp, err := h.kv.Get(ctx, h.prefix, append(clientv3.WithLastKey(), clientv3.WithKeysOnly())...)
+		if err != nil {
+			return err
+		}
+		v.ID = 1
+		for _, item := range resp.Kvs {
+			if id, err := strconv.ParseInt(strings.TrimPrefix(string(item.Key), h.prefix), 10, 64); err == nil && id >= v.ID {
+				v.ID = id + 1
+			}
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("%s%020d", h.prefix, v.ID)
+		txn, err := h.kv.Txn(ctx).
+			If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+			Then(clientv3.OpPut(key, string(data))).
+			Commit()
+		if err != nil {
+			return err
+		}
+		if txn.Succeeded {
+			return nil
+		}
+	}
+}
+
+// List get all versions, the oldest first.
+func (h *history) List(ctx context.Context) ([]config.Version, error) {
+	resp, err := h.kv.Get(ctx, h.prefix, clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]config.Version, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		var v config.Version
+		if err := json.Unmarshal(item.Value, &v); err != nil {
+			return nil, fmt.Errorf("invalid config version %s err:%v", item.Key, err)
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// start load the keys and start watching them.
+func (c *Config) start(store clientv3.KV, watcher clientv3.Watcher) error {
+	c.kv, c.watcher = store, watcher
+	c.prefix = kv.Prefix(c.prefix)
+	if c.history == "" {
+		c.history = strings.TrimSuffix(defaultHistory+"/"+strings.Trim(c.prefix, "/"), "/") + "/"
+	}
+	if strings.HasPrefix(c.history, c.prefix) {
+		return errors.New("etcd config history can not be under prefix")
+	}
+	c.Versions = config.NewVersions(&history{kv: store, prefix: c.history}, c.AllSettings, c.write)
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	ctx, cancel := context.WithTimeout(c.ctx, defaultDialTimeout)
+	defer cancel()
+	if err := c.load(ctx); err != nil {
+		c.cancel()
+		return fmt.Errorf("load etcd config err:%v", err)
+	}
+
+	c.wg.Add(1)
+	go c.watch()
+	return nil
+}
+
+// Close stop watching and close the connection.
+func (c *Config) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.wg.Wait()
+	if c.client != nil {
+		return c.client.Close()
+	}
+	return nil
+}
+
+// StartAndGC start etcd config adapter.
+// config is like {"server":"127.0.0.1", "port":2379, "prefix":"/app", "user":"xxx", "password":"xxx", "history":"/config_history/app/"}
+func (c *Config) StartAndGC(param config.Param) error {
+	if param.Prefix == "" {
+		return errors.New("etcd config prefix can not be empty")
+	}
+	c.prefix = param.Prefix
+	c.history = param.History
+
+	endpoints := param.Endpoints
+	if len(endpoints) == 0 {
+		if param.Server == "" {
+			return errors.New("etcd config server can not be empty")
+		}
+		port := param.Port
+		if port == 0 {
+			port = defaultPort
+		}
+		endpoints = []string{fmt.Sprintf("%s:%d", param.Server, port)}
+	}
+
+	tlsConfig, err := param.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: defaultDialTimeout,
+		TLS:         tlsConfig,
+		Username:    param.User,
+		Password:    param.Password,
+	})
+	if err != nil {
+		return fmt.Errorf("create etcd client err:%v", err)
+	}
+	c.client = client
+
+	if err := c.start(client.KV, client.Watcher); err != nil {
+		_ = client.Close()
+		return err
+	}
+	return nil
+}
+
+func init() {
+	config.Register(config.TypeETCD, NewEtcdConfig)
+}
@@ -0,0 +1,313 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/config"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeEtcd serve Get from a map and hand out watch channels to the test.
+type fakeEtcd struct {
+	clientv3.KV
+	clientv3.Watcher
+
+	mutex    sync.Mutex
+	revision int64
+	kvs      map[string]string
+	gets     int
+	watches  chan watch
+}
+
+// watch - a watch request of the adapter
+type watch struct {
+	rev int64
+	ch  chan clientv3.WatchResponse
+}
+
+func newFakeEtcd(kvs map[string]string, revision int64) *fakeEtcd {
+	return &fakeEtcd{kvs: kvs, revision: revision, watches: make(chan watch, 10)}
+}
+
+// match check if k is in the range of op.
+func match(op clientv3.Op, k string) bool {
+	key, end := string(op.KeyBytes()), string(op.RangeBytes())
+	if end == "" {
+		return k == key
+	}
+	return k >= key && (end == "\x00" || k < end)
+}
+
+func (f *fakeEtcd) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.gets++
+	op := clientv3.OpGet(key, opts...)
+	resp := &clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: f.revision}}
+	var keys []string
+	for k := range f.kvs {
+		if match(op, k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(f.kvs[k])})
+	}
+	return resp, nil
+}
+
+func (f *fakeEtcd) Txn(ctx context.Context) clientv3.Txn {
+	return &fakeTxn{f: f}
+}
+
+// fakeTxn support create revision compares, puts and deletes.
+type fakeTxn struct {
+	f    *fakeEtcd
+	cmps []clientv3.Cmp
+	ops  []clientv3.Op
+}
+
+func (t *fakeTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
+	t.cmps = append(t.cmps, cs...)
+	return t
+}
+
+func (t *fakeTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	t.ops = append(t.ops, ops...)
+	return t
+}
+
+func (t *fakeTxn) Else(ops ...clientv3.Op) clientv3.Txn {
+	return t
+}
+
+func (t *fakeTxn) Commit() (*clientv3.TxnResponse, error) {
+	f := t.f
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, cmp := range t.cmps {
+		if _, ok := f.kvs[string(cmp.KeyBytes())]; ok {
+			return &clientv3.TxnResponse{Succeeded: false}, nil
+		}
+	}
+	for _, op := range t.ops {
+		switch {
+		case op.IsPut():
+			f.kvs[string(op.KeyBytes())] = string(op.ValueBytes())
+		case op.IsDelete():
+			for k := range f.kvs {
+				if match(op, k) {
+					delete(f.kvs, k)
+				}
+			}
+		}
+	}
+	f.revision++
+	return &clientv3.TxnResponse{Succeeded: true}, nil
+}
+
+func (f *fakeEtcd) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	in := make(chan clientv3.WatchResponse)
+	out := make(chan clientv3.WatchResponse)
+	f.watches <- watch{rev: clientv3.OpGet(key, opts...).Rev(), ch: in}
+	// like the client, the channel is closed once ctx is done
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case resp, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- resp:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// event build a put event, an empty value is a delete.
+func event(key, value string) *clientv3.Event {
+	ev := &clientv3.Event{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value)}}
+	if value == "" {
+		ev.Type = clientv3.EventTypeDelete
+	}
+	return ev
+}
+
+// nextWatch wait for the adapter to watch.
+func nextWatch(t *testing.T, f *fakeEtcd) watch {
+	select {
+	case w := <-f.watches:
+		return w
+	case <-time.After(time.Second):
+		t.Fatalf("watch not started")
+	}
+	return watch{}
+}
+
+// eventually wait for cond.
+func eventually(t *testing.T, cond func() bool) {
+	assert.Eventually(t, cond, time.Second, time.Millisecond)
+}
+
+func TestEtcdConfig(t *testing.T) {
+	f := newFakeEtcd(map[string]string{
+		"/app/name":         "test",
+		"/app/db/host":      "127.0.0.1",
+		"/app/db/port":      "3306",
+		"/app/db/timeout":   "3s",
+		"/app/feature/beta": "true",
+	}, 5)
+	c := &Config{Store: NewEtcdConfig().(*Config).Store, prefix: "/app", retry: time.Millisecond}
+	assert.Nil(t, c.start(f, f))
+	defer c.Close()
+
+	assert.Equal(t, "test", c.GetString("name"))
+	assert.Equal(t, 3306, c.GetInt("db.port"))
+	assert.Equal(t, 3*time.Second, c.GetDuration("db.timeout"))
+	assert.True(t, c.GetBool("feature.beta"))
+	assert.Equal(t, map[string]string{"host": "127.0.0.1", "port": "3306", "timeout": "3s"}, c.GetStringMapString("db"))
+	assert.False(t, c.IsExist("missing"))
+
+	ports := make(chan []interface{}, 10)
+	c.Watch("db.port", func(old, new interface{}) { ports <- []interface{}{old, new} })
+
+	// the watch start after the loaded revision
+	w := nextWatch(t, f)
+	assert.Equal(t, int64(6), w.rev)
+	w.ch <- clientv3.WatchResponse{
+		Header: etcdserverpb.ResponseHeader{Revision: 7},
+		Events: []*clientv3.Event{event("/app/db/port", "3307"), event("/app/feature/beta", "")},
+	}
+	eventually(t, func() bool { return c.GetInt("db.port") == 3307 })
+	assert.False(t, c.IsExist("feature.beta"))
+	assert.Equal(t, []interface{}{"3306", "3307"}, <-ports)
+
+	// a broken watch resume from the last revision
+	close(w.ch)
+	w = nextWatch(t, f)
+	assert.Equal(t, int64(8), w.rev)
+
+	// a failed watch is reported and resumed
+	errs := make(chan error, 10)
+	c.OnError(func(err error) { errs <- err })
+	w.ch <- clientv3.WatchResponse{Canceled: true}
+	assert.Contains(t, (<-errs).Error(), "config: watch etcd config")
+	w = nextWatch(t, f)
+	assert.Equal(t, int64(8), w.rev)
+
+	// a compacted revision reload everything
+	f.mutex.Lock()
+	f.kvs = map[string]string{"/app/name": "reloaded"}
+	f.revision = 20
+	f.mutex.Unlock()
+	w.ch <- clientv3.WatchResponse{CompactRevision: 15, Canceled: true}
+	w = nextWatch(t, f)
+	assert.Equal(t, int64(21), w.rev)
+	assert.Equal(t, "reloaded", c.GetString("name"))
+	assert.False(t, c.IsExist("db.host"))
+	assert.Equal(t, map[string]interface{}{"name": "reloaded"}, c.AllSettings())
+}
+
+func TestEtcdConfigSibling(t *testing.T) {
+	f := newFakeEtcd(map[string]string{
+		"/app/name":         "test",
+		"/application/name": "sibling",
+		"/apps":             "sibling",
+	}, 5)
+	c := &Config{Store: NewEtcdConfig().(*Config).Store, prefix: "/app", retry: time.Millisecond}
+	assert.Nil(t, c.start(f, f))
+	defer c.Close()
+
+	// keys of a sibling sharing the prefix are not loaded
+	assert.Equal(t, map[string]interface{}{"name": "test"}, c.AllSettings())
+
+	// nor watched
+	w := nextWatch(t, f)
+	w.ch <- clientv3.WatchResponse{
+		Header: etcdserverpb.ResponseHeader{Revision: 6},
+		Events: []*clientv3.Event{event("/app/port", "3306")},
+	}
+	eventually(t, func() bool { return c.GetInt("port") == 3306 })
+	assert.False(t, c.IsExist("lication"))
+}
+
+func TestEtcdConfigParam(t *testing.T) {
+	_, err := config.NewConfig(config.TypeETCD, config.Param{Server: "127.0.0.1"})
+	assert.NotNil(t, err)
+	_, err = config.NewConfig(config.TypeETCD, config.Param{Prefix: "/app"})
+	assert.NotNil(t, err)
+	_, err = config.NewConfig(config.TypeETCD, config.Param{Prefix: "/app", Server: "127.0.0.1", CAFile: "missing.pem"})
+	assert.NotNil(t, err)
+}
+
+func TestEtcdConfigWrite(t *testing.T) {
+	f := newFakeEtcd(map[string]string{
+		"/app/name":    "test",
+		"/app/db/host": "127.0.0.1",
+		"/app/db/port": "3306",
+	}, 5)
+	c := &Config{Store: NewEtcdConfig().(*Config).Store, prefix: "/app", retry: time.Millisecond}
+	assert.Nil(t, c.start(f, f))
+	defer c.Close()
+	ctx := config.WithAuthor(context.Background(), "alice")
+
+	first, err := c.Snapshot(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), first.ID)
+
+	assert.Nil(t, c.Set(ctx, "db.port", 3307))
+	assert.Nil(t, c.Set(ctx, "cache", map[string]interface{}{"size": 100, "ttl": "10s"}))
+	assert.Nil(t, c.Delete(ctx, "name"))
+	// writes are seen before the watch bring them
+	assert.Equal(t, "3307", c.Get("db.port"))
+	assert.Equal(t, 100, c.GetInt("cache.size"))
+	assert.False(t, c.IsExist("name"))
+
+	f.mutex.Lock()
+	assert.Equal(t, "3307", f.kvs["/app/db/port"])
+	assert.Equal(t, "10s", f.kvs["/app/cache/ttl"])
+	assert.NotContains(t, f.kvs, "/app/name")
+	assert.Contains(t, f.kvs, "/config_history/app/00000000000000000002")
+	f.mutex.Unlock()
+
+	versions, err := c.History(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 4)
+	assert.Equal(t, "alice", versions[1].Author)
+	assert.Equal(t, config.ActionSet, versions[1].Action)
+	assert.Equal(t, []config.Change{{Key: "db.port", Old: "3306", New: float64(3307)}}, versions[1].Changes)
+	assert.Equal(t, []config.Change{{Key: "name", Old: "test"}}, versions[3].Changes)
+
+	// roll back the bad push
+	assert.Nil(t, c.Rollback(ctx, first.ID))
+	assert.Equal(t, "test", c.GetString("name"))
+	assert.Equal(t, "3306", c.GetString("db.port"))
+	assert.False(t, c.IsExist("cache"))
+	f.mutex.Lock()
+	assert.Equal(t, "3306", f.kvs["/app/db/port"])
+	assert.NotContains(t, f.kvs, "/app/cache/size")
+	f.mutex.Unlock()
+
+	versions, err = c.History(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, config.ActionRollback, versions[4].Action)
+	assert.Equal(t, first.ID, versions[4].Target)
+	assert.True(t, errors.Is(c.Rollback(ctx, 100), config.ErrVersionNotFound))
+}
@@ -1,21 +1,35 @@
 package file
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/dbunion/com/config"
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
-// Config is File config adapter.
+// Config is File config adapter. The file is read again when it changed,
+// and Set, Delete and Rollback rewrite it atomically in its format, with
+// the versions kept in Param.History, the file path with .history if empty.
 type Config struct {
+	config.Notifier
+	*config.Versions
+
 	fileType string
 	path     string
 	name     string
 	file     string
-	viper    *viper.Viper
+
+	// serialize reloads, so callbacks see them in order
+	update sync.Mutex
+	mutex  sync.RWMutex
+	viper  *viper.Viper
 }
 
 // NewFileConfig create new file config with default collection name.
@@ -23,74 +37,144 @@ func NewFileConfig() config.Config {
 	return &Config{}
 }
 
+// snapshot return the viper of the last read.
+func (fc *Config) snapshot() *viper.Viper {
+	fc.mutex.RLock()
+	defer fc.mutex.RUnlock()
+	return fc.viper
+}
+
+// read the file into a new viper.
+func (fc *Config) read() (*viper.Viper, error) {
+	vp := viper.New()
+	vp.SetConfigFile(fc.file)
+	vp.SetConfigType(fc.fileType)
+	if err := vp.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	return vp, nil
+}
+
+// reload read the file again and notify the changed keys.
+func (fc *Config) reload() error {
+	vp, err := fc.read()
+	if err != nil {
+		return err
+	}
+	fc.update.Lock()
+	defer fc.update.Unlock()
+	old := fc.snapshot()
+	fc.mutex.Lock()
+	fc.viper = vp
+	fc.mutex.Unlock()
+	fc.Notify(old.AllSettings(), vp.AllSettings())
+	return nil
+}
+
+// write replace the file by the settings of w, the file is written
+// aside and renamed so readers never see a partial file.
+func (fc *Config) write(ctx context.Context, w config.Write) error {
+	vp := viper.New()
+	if err := vp.MergeConfigMap(w.Settings); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fc.file), "."+filepath.Base(fc.file)+".*."+strings.ToLower(fc.fileType))
+	if err != nil {
+		return err
+	}
+	_ = tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := vp.WriteConfigAs(tmp.Name()); err != nil {
+		return fmt.Errorf("write config file err:%v", err)
+	}
+	if info, err := os.Stat(fc.file); err == nil {
+		_ = os.Chmod(tmp.Name(), info.Mode())
+	}
+	if err := os.Rename(tmp.Name(), fc.file); err != nil {
+		return err
+	}
+	return fc.reload()
+}
+
 // Get get config value by key.
 func (fc *Config) Get(key string) interface{} {
-	return fc.viper.Get(key)
+	return fc.snapshot().Get(key)
 }
 
 // GetBool get bool config value by key
 func (fc *Config) GetBool(key string) bool {
-	return fc.viper.GetBool(key)
+	return fc.snapshot().GetBool(key)
 }
 
 // GetFloat64 get float64 config value by key
 func (fc *Config) GetFloat64(key string) float64 {
-	return fc.viper.GetFloat64(key)
+	return fc.snapshot().GetFloat64(key)
 }
 
 // GetInt get Int config value by key
 func (fc *Config) GetInt(key string) int {
-	return fc.viper.GetInt(key)
+	return fc.snapshot().GetInt(key)
 }
 
 // GetInt32 get Int32 config value by key
 func (fc *Config) GetInt32(key string) int32 {
-	return fc.viper.GetInt32(key)
+	return fc.snapshot().GetInt32(key)
 }
 
 // GetInt64 get Int64 config value by key
 func (fc *Config) GetInt64(key string) int64 {
-	return fc.viper.GetInt64(key)
+	return fc.snapshot().GetInt64(key)
 }
 
 // GetString get string config value by key
 func (fc *Config) GetString(key string) string {
-	return fc.viper.GetString(key)
+	return fc.snapshot().GetString(key)
 }
 
 // GetStringMap get stringMap config value by key
 func (fc *Config) GetStringMap(key string) map[string]interface{} {
-	return fc.viper.GetStringMap(key)
+	return fc.snapshot().GetStringMap(key)
 }
 
 // GetStringMapString get stringMapstring config value by key
 func (fc *Config) GetStringMapString(key string) map[string]string {
-	return fc.viper.GetStringMapString(key)
+	return fc.snapshot().GetStringMapString(key)
 }
 
 // GetStringSlice get stringSlice config value by key
 func (fc *Config) GetStringSlice(key string) []string {
-	return fc.viper.GetStringSlice(key)
+	return fc.snapshot().GetStringSlice(key)
 }
 
 // GetTime get time config value by key
 func (fc *Config) GetTime(key string) time.Time {
-	return fc.viper.GetTime(key)
+	return fc.snapshot().GetTime(key)
 }
 
 // GetDuration get Duration config value by key
 func (fc *Config) GetDuration(key string) time.Duration {
-	return fc.viper.GetDuration(key)
+	return fc.snapshot().GetDuration(key)
 }
 
 // AllSettings get all config value
 func (fc *Config) AllSettings() map[string]interface{} {
-	return fc.viper.AllSettings()
+	return fc.snapshot().AllSettings()
+}
+
+// Unmarshal bind the value of key into a struct.
+func (fc *Config) Unmarshal(key string, out interface{}) error {
+	return config.Decode(fc.snapshot().Get(key), out)
+}
+
+// UnmarshalAll bind all config value into a struct.
+func (fc *Config) UnmarshalAll(out interface{}) error {
+	return config.Decode(fc.snapshot().AllSettings(), out)
 }
 
 // IsExist check config's existence in file.
 func (fc *Config) IsExist(key string) bool {
-	return fc.viper.IsSet(key)
+	return fc.snapshot().IsSet(key)
 }
 
 // StartAndGC start file config adapter.
@@ -134,14 +218,26 @@ func (fc *Config) StartAndGC(cfg config.Param) error {
 	if err != nil {
 		panic(err)
 	}
+	fc.file = vp.ConfigFileUsed()
 
 	fc.viper = vp
 
-	// watch file change
-	vp.WatchConfig()
-	vp.OnConfigChange(func(in fsnotify.Event) {
-		fmt.Printf("config change, name:%v op:%s\n", in.Name, in.Op)
+	history := cfg.History
+	if history == "" {
+		history = fc.file + ".history"
+	}
+	fc.Versions = config.NewVersions(config.NewFileHistory(history), fc.AllSettings, fc.write)
+
+	// watch file change, callbacks are called for the changed keys
+	watcher := viper.New()
+	watcher.SetConfigFile(fc.file)
+	watcher.SetConfigType(fc.fileType)
+	watcher.OnConfigChange(func(in fsnotify.Event) {
+		if err := fc.reload(); err != nil {
+			fc.NotifyError(fmt.Errorf("config: reload %s err:%v", in.Name, err))
+		}
 	})
+	watcher.WatchConfig()
 
 	return nil
 }
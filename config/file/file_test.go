@@ -1,7 +1,10 @@
 package file
 
 import (
+	"context"
 	"github.com/dbunion/com/config"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -140,3 +143,142 @@ func TestYaml(t *testing.T) {
 	assert.Equal(t, "testname", tLgs)
 
 }
+
+func TestWatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.json")
+	if err := os.WriteFile(file, []byte(`{"name": "test", "db": {"port": 3306}}`), 0644); err != nil {
+		t.Fatalf("write config error, err:%v", err)
+	}
+	cfg, err := config.NewConfig(config.TypeFile, config.Param{File: file, Type: "json"})
+	if err != nil {
+		t.Fatalf("create config error, err:%v", err)
+	}
+
+	ports := make(chan []interface{}, 10)
+	names := make(chan []interface{}, 10)
+	changes := make(chan []config.Change, 10)
+	cfg.Watch("db.port", func(old, new interface{}) { ports <- []interface{}{old, new} })
+	cfg.Watch("name", func(old, new interface{}) { names <- []interface{}{old, new} })
+	cfg.OnChange(func(c []config.Change) { changes <- c })
+
+	if err := os.WriteFile(file, []byte(`{"name": "test", "db": {"port": 3307}}`), 0644); err != nil {
+		t.Fatalf("write config error, err:%v", err)
+	}
+	select {
+	case port := <-ports:
+		assert.Equal(t, []interface{}{float64(3306), float64(3307)}, port)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("change not notified")
+	}
+	assert.Equal(t, []config.Change{{Key: "db.port", Old: float64(3306), New: float64(3307)}}, <-changes)
+	assert.Empty(t, names)
+	assert.Equal(t, 3307, cfg.GetInt("db.port"))
+
+	// a broken file is reported and the last settings are kept
+	errs := make(chan error, 10)
+	cfg.(config.ErrorNotifier).OnError(func(err error) { errs <- err })
+	if err := os.WriteFile(file, []byte(`{"name": "test", "db": {`), 0644); err != nil {
+		t.Fatalf("write config error, err:%v", err)
+	}
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "config: reload")
+	case <-time.After(5 * time.Second):
+		t.Fatalf("reload error not notified")
+	}
+	assert.Equal(t, 3307, cfg.GetInt("db.port"))
+}
+
+func TestBind(t *testing.T) {
+	type db struct {
+		Host string `config:"host" validate:"required"`
+		Port int    `config:"port" default:"3306" validate:"max=65535"`
+	}
+
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(file, []byte("db:\n  host: 127.0.0.1\n"), 0644); err != nil {
+		t.Fatalf("write config error, err:%v", err)
+	}
+	cfg, err := config.NewConfig(config.TypeFile, config.Param{File: file, Type: "yaml"})
+	if err != nil {
+		t.Fatalf("create config error, err:%v", err)
+	}
+
+	var out db
+	b, err := config.Bind(cfg, "db", &out)
+	if err != nil {
+		t.Fatalf("bind config error, err:%v", err)
+	}
+	assert.Equal(t, db{Host: "127.0.0.1", Port: 3306}, out)
+	assert.Equal(t, &out, b.Load())
+
+	// a reload swap in a new struct
+	if err := os.WriteFile(file, []byte("db:\n  host: 127.0.0.2\n  port: 3307\n"), 0644); err != nil {
+		t.Fatalf("write config error, err:%v", err)
+	}
+	assert.Eventually(t, func() bool { return b.Load().(*db).Port == 3307 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "127.0.0.2", b.Load().(*db).Host)
+
+	// an invalid reload keep the last struct
+	if err := os.WriteFile(file, []byte("db:\n  host: 127.0.0.2\n  port: 70000\n"), 0644); err != nil {
+		t.Fatalf("write config error, err:%v", err)
+	}
+	assert.Eventually(t, func() bool { return b.Err() != nil }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 3307, b.Load().(*db).Port)
+
+	var all struct {
+		DB db `config:"db"`
+	}
+	assert.NotNil(t, cfg.UnmarshalAll(&all))
+	assert.NotNil(t, cfg.Unmarshal("db", &out))
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.yaml")
+	if err := os.WriteFile(file, []byte("name: test\ndb:\n  host: 127.0.0.1\n  port: 3306\n"), 0640); err != nil {
+		t.Fatalf("write config error, err:%v", err)
+	}
+	cfg, err := config.NewConfig(config.TypeFile, config.Param{File: file, Type: "yaml"})
+	if err != nil {
+		t.Fatalf("create config error, err:%v", err)
+	}
+	w := cfg.(config.Writer)
+	ctx := config.WithAuthor(context.Background(), "alice")
+
+	ports := make(chan interface{}, 10)
+	cfg.Watch("db.port", func(old, new interface{}) { ports <- new })
+
+	first, err := w.Snapshot(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, w.Set(ctx, "db.port", 3307))
+	assert.Nil(t, w.Set(ctx, "cache", map[string]interface{}{"size": 100}))
+	assert.Nil(t, w.Delete(ctx, "name"))
+
+	// the file is rewritten in its format and read at once
+	assert.Equal(t, 3307, cfg.GetInt("db.port"))
+	assert.Equal(t, 100, cfg.GetInt("cache.size"))
+	assert.False(t, cfg.IsExist("name"))
+	assert.Equal(t, 3307, <-ports)
+	data, _ := os.ReadFile(file)
+	assert.Contains(t, string(data), "port: 3307")
+	info, _ := os.Stat(file)
+	assert.Equal(t, os.FileMode(0640), info.Mode())
+
+	versions, err := w.History(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, versions, 4)
+	assert.Equal(t, "alice", versions[1].Author)
+	assert.Equal(t, []config.Change{{Key: "db.port", Old: float64(3306), New: float64(3307)}}, versions[1].Changes)
+
+	assert.Nil(t, w.Rollback(ctx, first.ID))
+	assert.Equal(t, "test", cfg.GetString("name"))
+	assert.Equal(t, 3306, cfg.GetInt("db.port"))
+	assert.False(t, cfg.IsExist("cache"))
+	_, err = os.Stat(file + ".history")
+	assert.Nil(t, err)
+
+	// no temporary file is left
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 2)
+}
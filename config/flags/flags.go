@@ -0,0 +1,54 @@
+package flags
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/dbunion/com/config"
+	"github.com/dbunion/com/config/kv"
+)
+
+// Config is command line flags config adapter. Only flags set on the
+// command line are loaded, so flag defaults never override the layers
+// below. A flag is loaded as its name, -db.port is db.port, or as its
+// key in Param.Mapping. The flags are read once at start, Param.FlagSet
+// must be parsed before.
+type Config struct {
+	*kv.Store
+}
+
+// NewFlagConfig create new flag config.
+func NewFlagConfig() config.Config {
+	return &Config{Store: kv.NewStore()}
+}
+
+// StartAndGC start flag config adapter.
+// config is like {"mapping":{"port":"server.port"}} with a parsed FlagSet
+func (c *Config) StartAndGC(param config.Param) error {
+	fs := param.FlagSet
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	if !fs.Parsed() {
+		return errors.New("flag config flags are not parsed")
+	}
+
+	values := make(map[string]interface{})
+	fs.Visit(func(f *flag.Flag) {
+		key := f.Name
+		if mapped, ok := param.Mapping[f.Name]; ok {
+			key = mapped
+		}
+		if getter, ok := f.Value.(flag.Getter); ok {
+			values[key] = getter.Get()
+		} else {
+			values[key] = f.Value.String()
+		}
+	})
+	c.Store.Reset(values)
+	return nil
+}
+
+func init() {
+	config.Register(config.TypeFlag, NewFlagConfig)
+}
@@ -0,0 +1,68 @@
+package kv
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// FormatRaw - values are strings
+	FormatRaw = "raw"
+	// FormatJSON - values are json documents
+	FormatJSON = "json"
+	// FormatYAML - values are yaml documents
+	FormatYAML = "yaml"
+)
+
+// CheckFormat check format is known.
+func CheckFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "", FormatRaw, FormatJSON, FormatYAML, "yml":
+		return nil
+	}
+	return fmt.Errorf("unknown value format %q, format:json/yaml/raw", format)
+}
+
+// Decode parse a value of format, objects become subtrees of its key.
+func Decode(format string, data []byte) (interface{}, error) {
+	if err := CheckFormat(format); err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(format) {
+	case "", FormatRaw:
+		return string(data), nil
+	case FormatJSON:
+		var val interface{}
+		if err := json.Unmarshal(data, &val); err != nil {
+			return nil, err
+		}
+		return val, nil
+	case FormatYAML, "yml":
+		var val interface{}
+		if err := yaml.Unmarshal(data, &val); err != nil {
+			return nil, err
+		}
+		return val, nil
+	}
+	return string(data), nil
+}
+
+// Encode format a value for a key, strings are stored as is in raw.
+func Encode(format string, value interface{}) ([]byte, error) {
+	if err := CheckFormat(format); err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(format) {
+	case "", FormatRaw:
+		if s, ok := value.(string); ok {
+			return []byte(s), nil
+		}
+		return json.Marshal(value)
+	case FormatYAML, "yml":
+		return yaml.Marshal(value)
+	}
+	return json.Marshal(value)
+}
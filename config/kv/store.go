@@ -0,0 +1,320 @@
+package kv
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dbunion/com/config"
+	"github.com/spf13/viper"
+)
+
+// Store - typed config getters over a key/value tree. Remote adapters
+// keep the flat values of their keys in it, keys are dotted paths and
+// every update swaps in a new snapshot so readers never see a partial one.
+// Watch and OnChange callbacks are called after an update changed a key.
+type Store struct {
+	config.Notifier
+
+	// serialize updates, so callbacks see them in order
+	update sync.Mutex
+	mutex  sync.RWMutex
+	values map[string]interface{}
+	viper  *viper.Viper
+}
+
+// NewStore create an empty store.
+func NewStore() *Store {
+	s := &Store{values: make(map[string]interface{})}
+	s.viper = build(s.values)
+	return s
+}
+
+// Prefix return prefix ending with a slash, so the keys of a sibling
+// like /application are not under the prefix /app.
+func Prefix(prefix string) string {
+	return strings.TrimSuffix(prefix, "/") + "/"
+}
+
+// Key convert the path of a remote key under prefix into a dotted key,
+// e.g. /app/db/host under /app is db.host.
+func Key(prefix, path string) string {
+	key := strings.TrimPrefix(path, prefix)
+	key = strings.Trim(key, "/")
+	return strings.ReplaceAll(key, "/", ".")
+}
+
+// build a viper of flat values, a key is shadowed by the keys below it.
+func build(values map[string]interface{}) *viper.Viper {
+	tree := make(map[string]interface{})
+	for key, val := range values {
+		if key == "" {
+			continue
+		}
+		parts := strings.Split(strings.ToLower(key), ".")
+		node := tree
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[part] = child
+			}
+			node = child
+		}
+		last := parts[len(parts)-1]
+		if _, ok := node[last].(map[string]interface{}); !ok {
+			node[last] = clone(val)
+		}
+	}
+	vp := viper.New()
+	_ = vp.MergeConfigMap(tree)
+	return vp
+}
+
+// clone copy the maps of a decoded value, the tree of build must
+// not share them with the stored values.
+func clone(val interface{}) interface{} {
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		return val
+	}
+	copied := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		copied[k] = clone(v)
+	}
+	return copied
+}
+
+// Reset replace all values.
+func (s *Store) Reset(values map[string]interface{}) {
+	copied := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		copied[k] = v
+	}
+	s.update.Lock()
+	defer s.update.Unlock()
+	s.set(copied)
+}
+
+// Apply set puts and remove deletes in one update.
+func (s *Store) Apply(puts map[string]interface{}, deletes []string) {
+	s.update.Lock()
+	defer s.update.Unlock()
+	s.mutex.RLock()
+	current := s.values
+	s.mutex.RUnlock()
+
+	values := make(map[string]interface{}, len(current)+len(puts))
+	for k, v := range current {
+		values[k] = v
+	}
+	for _, k := range deletes {
+		delete(values, k)
+	}
+	for k, v := range puts {
+		values[k] = v
+	}
+	s.set(values)
+}
+
+// set install a snapshot of values and notify the changes, the caller
+// hold the update lock.
+func (s *Store) set(values map[string]interface{}) {
+	vp := build(values)
+	s.mutex.Lock()
+	old := s.viper
+	s.values, s.viper = values, vp
+	s.mutex.Unlock()
+	s.Notify(old.AllSettings(), vp.AllSettings())
+}
+
+// Plan compute the writes of a change to the remote keys: the keys to
+// put with their new value and the keys to delete, so that puts are set
+// and deletes are removed with the keys below them. A document under a
+// key, e.g. a json object, is put whole with the change applied to it.
+func (s *Store) Plan(puts map[string]interface{}, deletes []string) (map[string]interface{}, []string) {
+	s.mutex.RLock()
+	current := s.values
+	s.mutex.RUnlock()
+
+	values := make(map[string]interface{}, len(current))
+	for k, v := range current {
+		values[k] = clone(v)
+	}
+	for _, key := range deletes {
+		for k, v := range values {
+			switch {
+			case under(k, key):
+				delete(values, k)
+			case under(key, k):
+				if doc, ok := v.(map[string]interface{}); ok {
+					unset(doc, subpath(key, k))
+				}
+			}
+		}
+	}
+	for key, val := range puts {
+		placed := false
+		for k, v := range values {
+			switch {
+			case strings.EqualFold(k, key):
+				values[k] = val
+				placed = true
+			case under(k, key):
+				// a leaf replace the keys below it
+				delete(values, k)
+			case under(key, k):
+				if doc, ok := v.(map[string]interface{}); ok {
+					setIn(doc, subpath(key, k), val)
+					placed = true
+				} else {
+					// a leaf above the key is replaced by it
+					delete(values, k)
+				}
+			}
+		}
+		if !placed {
+			values[key] = val
+		}
+	}
+
+	changed := make(map[string]interface{})
+	for k, v := range values {
+		if old, ok := current[k]; !ok || !reflect.DeepEqual(old, v) {
+			changed[k] = v
+		}
+	}
+	var removed []string
+	for k := range current {
+		if _, ok := values[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(removed)
+	return changed, removed
+}
+
+// under check if key is parent or parent.x, ignoring case.
+func under(key, parent string) bool {
+	key, parent = strings.ToLower(key), strings.ToLower(parent)
+	return key == parent || strings.HasPrefix(key, parent+".")
+}
+
+// subpath return the path of key below parent.
+func subpath(key, parent string) []string {
+	return strings.Split(strings.ToLower(key)[len(parent)+1:], ".")
+}
+
+// setIn set the value of a path in a document.
+func setIn(doc map[string]interface{}, path []string, val interface{}) {
+	for _, part := range path[:len(path)-1] {
+		child, ok := doc[part].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			doc[part] = child
+		}
+		doc = child
+	}
+	doc[path[len(path)-1]] = val
+}
+
+// unset delete a path in a document.
+func unset(doc map[string]interface{}, path []string) {
+	for _, part := range path[:len(path)-1] {
+		child, ok := doc[part].(map[string]interface{})
+		if !ok {
+			return
+		}
+		doc = child
+	}
+	delete(doc, path[len(path)-1])
+}
+
+// snapshot return the current viper.
+func (s *Store) snapshot() *viper.Viper {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.viper
+}
+
+// Get get config value by key.
+func (s *Store) Get(key string) interface{} {
+	return s.snapshot().Get(key)
+}
+
+// GetBool get bool config value by key
+func (s *Store) GetBool(key string) bool {
+	return s.snapshot().GetBool(key)
+}
+
+// GetFloat64 get float64 config value by key
+func (s *Store) GetFloat64(key string) float64 {
+	return s.snapshot().GetFloat64(key)
+}
+
+// GetInt get Int config value by key
+func (s *Store) GetInt(key string) int {
+	return s.snapshot().GetInt(key)
+}
+
+// GetInt32 get Int32 config value by key
+func (s *Store) GetInt32(key string) int32 {
+	return s.snapshot().GetInt32(key)
+}
+
+// GetInt64 get Int64 config value by key
+func (s *Store) GetInt64(key string) int64 {
+	return s.snapshot().GetInt64(key)
+}
+
+// GetString get string config value by key
+func (s *Store) GetString(key string) string {
+	return s.snapshot().GetString(key)
+}
+
+// GetStringMap get stringMap config value by key
+func (s *Store) GetStringMap(key string) map[string]interface{} {
+	return s.snapshot().GetStringMap(key)
+}
+
+// GetStringMapString get stringMapstring config value by key
+func (s *Store) GetStringMapString(key string) map[string]string {
+	return s.snapshot().GetStringMapString(key)
+}
+
+// GetStringSlice get stringSlice config value by key
+func (s *Store) GetStringSlice(key string) []string {
+	return s.snapshot().GetStringSlice(key)
+}
+
+// GetTime get time config value by key
+func (s *Store) GetTime(key string) time.Time {
+	return s.snapshot().GetTime(key)
+}
+
+// GetDuration get Duration config value by key
+func (s *Store) GetDuration(key string) time.Duration {
+	return s.snapshot().GetDuration(key)
+}
+
+// AllSettings get all config value
+func (s *Store) AllSettings() map[string]interface{} {
+	return s.snapshot().AllSettings()
+}
+
+// Unmarshal bind the value of key into a struct.
+func (s *Store) Unmarshal(key string, out interface{}) error {
+	return config.Decode(s.snapshot().Get(key), out)
+}
+
+// UnmarshalAll bind all config value into a struct.
+func (s *Store) UnmarshalAll(out interface{}) error {
+	return config.Decode(s.snapshot().AllSettings(), out)
+}
+
+// IsExist check if config value exists or not.
+func (s *Store) IsExist(key string) bool {
+	return s.snapshot().IsSet(key)
+}
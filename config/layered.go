@@ -0,0 +1,195 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Layer - a source of a layered config
+type Layer struct {
+	// name of the layer in Source, Adapter if empty
+	Name string `json:"name"`
+	// adapter and param the layer is created with
+	Adapter string `json:"adapter"`
+	Param   Param  `json:"param"`
+	// a config created by the caller, e.g. NewStatic defaults,
+	// Adapter and Param are ignored if it is set
+	Config Config `json:"-"`
+}
+
+// Static is a config of fixed values, e.g. the defaults of a layered config.
+type Static struct {
+	Notifier
+	view
+}
+
+// NewStatic create a config of values, keys are nested maps or dotted paths.
+func NewStatic(values map[string]interface{}) *Static {
+	s := &Static{}
+	leaves := make(map[string]interface{})
+	flatten("", values, leaves)
+	s.set(unflatten(leaves))
+	return s
+}
+
+// StartAndGC nothing to start.
+func (s *Static) StartAndGC(param Param) error {
+	return nil
+}
+
+// Layered merge the settings of its layers, a key of a later layer
+// override the same key of the layers before it. It is reloaded when a
+// layer changed and Source tell which layer supplied a key.
+type Layered struct {
+	Notifier
+	view
+
+	layers []Layer
+	// layers created by StartAndGC, closed by Close
+	owned []Config
+
+	// serialize merges, so callbacks see them in order
+	update sync.Mutex
+	// layer of every leaf key
+	sourceMutex sync.RWMutex
+	sources     map[string]string
+}
+
+// NewLayered create a config of layers, from the lowest priority to the
+// highest, e.g. defaults, base file, environment file, etcd, env and flags.
+func NewLayered(layers ...Layer) (*Layered, error) {
+	l := &Layered{}
+	if err := l.StartAndGC(Param{Layers: layers}); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// newLayered create new layered config.
+func newLayered() Config {
+	return &Layered{}
+}
+
+// merge the settings of all layers.
+func (l *Layered) merge() {
+	l.update.Lock()
+	defer l.update.Unlock()
+
+	leaves := make(map[string]interface{})
+	sources := make(map[string]string)
+	for _, layer := range l.layers {
+		values := make(map[string]interface{})
+		flatten("", layer.Config.AllSettings(), values)
+		for key, val := range values {
+			// a leaf replace the keys above and below it
+			for k := range leaves {
+				if strings.HasPrefix(k, key+".") || strings.HasPrefix(key, k+".") {
+					delete(leaves, k)
+					delete(sources, k)
+				}
+			}
+			leaves[key] = val
+			sources[key] = layer.Name
+		}
+	}
+
+	old := l.set(unflatten(leaves))
+	l.sourceMutex.Lock()
+	l.sources = sources
+	l.sourceMutex.Unlock()
+	l.Notify(old, l.AllSettings())
+}
+
+// Source return the name of the layer which supplied key, the highest
+// of the keys below it if key is a map, or "" if key is not set.
+func (l *Layered) Source(key string) string {
+	key = strings.ToLower(key)
+	l.sourceMutex.RLock()
+	defer l.sourceMutex.RUnlock()
+	if name, ok := l.sources[key]; ok {
+		return name
+	}
+
+	source, rank := "", -1
+	for k, name := range l.sources {
+		if !strings.HasPrefix(k, key+".") {
+			continue
+		}
+		for i, layer := range l.layers {
+			if layer.Name == name && i > rank {
+				source, rank = name, i
+			}
+		}
+	}
+	return source
+}
+
+// Sources return the layer of every leaf key, for debugging.
+func (l *Layered) Sources() map[string]string {
+	l.sourceMutex.RLock()
+	defer l.sourceMutex.RUnlock()
+	sources := make(map[string]string, len(l.sources))
+	for k, name := range l.sources {
+		sources[k] = name
+	}
+	return sources
+}
+
+// Close close the layers created by the layered config.
+func (l *Layered) Close() error {
+	var errs []string
+	for _, c := range l.owned {
+		if closer, ok := c.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// StartAndGC create the layers of param.Layers and merge them.
+// config is like {"layers":[{"name":"base", "adapter":"file", "param":{"file":"app.yaml", "type":"yaml"}},
+// {"name":"env", "adapter":"env", "param":{"prefix":"APP_"}}]}
+func (l *Layered) StartAndGC(param Param) error {
+	if len(param.Layers) == 0 {
+		return errors.New("layered config layers can not be empty")
+	}
+
+	names := make(map[string]bool)
+	for i, layer := range param.Layers {
+		if layer.Config == nil {
+			c, err := NewConfig(layer.Adapter, layer.Param)
+			if err != nil {
+				_ = l.Close()
+				return fmt.Errorf("create config layer %d err:%v", i, err)
+			}
+			layer.Config = c
+			l.owned = append(l.owned, c)
+		}
+		if layer.Name == "" {
+			layer.Name = layer.Adapter
+		}
+		if layer.Name == "" || names[layer.Name] {
+			layer.Name = fmt.Sprintf("%s#%d", layer.Name, i)
+		}
+		names[layer.Name] = true
+		l.layers = append(l.layers, layer)
+	}
+
+	l.merge()
+	for _, layer := range l.layers {
+		layer.Config.OnChange(func([]Change) { l.merge() })
+	}
+	return nil
+}
+
+func init() {
+	Register(TypeLayered, newLayered)
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// changing - a static config which can be changed
+type changing struct {
+	*Static
+}
+
+func (c *changing) change(values map[string]interface{}) {
+	old := c.set(values)
+	c.Notify(old, c.AllSettings())
+}
+
+func TestLayered(t *testing.T) {
+	defaults := NewStatic(map[string]interface{}{
+		"name":       "app",
+		"db.host":    "127.0.0.1",
+		"db.port":    3306,
+		"db.timeout": "3s",
+		"cache":      map[string]interface{}{"size": 100},
+	})
+	remote := &changing{Static: NewStatic(map[string]interface{}{"db": map[string]interface{}{"port": 3307}})}
+	override := NewStatic(map[string]interface{}{"cache": "off", "db.host": "10.0.0.1"})
+
+	l, err := NewLayered(
+		Layer{Name: "defaults", Config: defaults},
+		Layer{Name: "etcd", Config: remote},
+		Layer{Name: "flags", Config: override},
+	)
+	if err != nil {
+		t.Fatalf("create layered config error, err:%v", err)
+	}
+
+	assert.Equal(t, "app", l.GetString("name"))
+	assert.Equal(t, "10.0.0.1", l.GetString("db.host"))
+	assert.Equal(t, 3307, l.GetInt("db.port"))
+	assert.Equal(t, "3s", l.GetString("db.timeout"))
+	// a leaf of a higher layer replace a map
+	assert.Equal(t, "off", l.GetString("cache"))
+	assert.False(t, l.IsExist("cache.size"))
+
+	assert.Equal(t, "defaults", l.Source("name"))
+	assert.Equal(t, "etcd", l.Source("db.port"))
+	assert.Equal(t, "flags", l.Source("DB.Host"))
+	assert.Equal(t, "flags", l.Source("db"))
+	assert.Equal(t, "", l.Source("missing"))
+	assert.Equal(t, map[string]string{
+		"name":       "defaults",
+		"db.host":    "flags",
+		"db.port":    "etcd",
+		"db.timeout": "defaults",
+		"cache":      "flags",
+	}, l.Sources())
+
+	// a changed layer is merged again
+	var changes []Change
+	l.OnChange(func(c []Change) { changes = append(changes, c...) })
+	remote.change(map[string]interface{}{"db": map[string]interface{}{"port": 3308, "host": "10.0.0.2"}})
+	assert.Equal(t, 3308, l.GetInt("db.port"))
+	assert.Equal(t, "10.0.0.1", l.GetString("db.host"))
+	assert.Equal(t, []Change{{Key: "db.port", Old: 3307, New: 3308}}, changes)
+
+	remote.change(map[string]interface{}{})
+	assert.Equal(t, 3306, l.GetInt("db.port"))
+	assert.Equal(t, "defaults", l.Source("db.port"))
+}
+
+func TestLayeredError(t *testing.T) {
+	_, err := NewLayered()
+	assert.NotNil(t, err)
+	_, err = NewLayered(Layer{Adapter: "missing"})
+	assert.NotNil(t, err)
+	_, err = NewConfig(TypeLayered, Param{Layers: []Layer{{Config: NewStatic(nil)}}})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,78 @@
+// Command secret manage the keyring and encrypted values of config secrets.
+//
+//	secret genkey -id 2024                    print a new keyring line
+//	secret encrypt -keyring keys.txt VALUE    print enc:... of VALUE, read stdin if no VALUE
+//	secret decrypt -keyring keys.txt enc:...  print the plaintext of a value
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dbunion/com/config/secret"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: secret genkey [-id id] | encrypt [-keyring file] [value] | decrypt [-keyring file] value")
+	os.Exit(2)
+}
+
+// value return the first arg or the first line of stdin.
+func value(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read value from stdin err:%v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func run(cmd string, args []string) (string, error) {
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	id := fs.String("id", "1", "id of the new key")
+	path := fs.String("keyring", os.Getenv("SECRET_KEYRING"), "keyring file, default $SECRET_KEYRING")
+	_ = fs.Parse(args)
+
+	if cmd == "genkey" {
+		key, err := secret.GenerateKey()
+		if err != nil {
+			return "", err
+		}
+		return *id + ":" + key, nil
+	}
+
+	keyring, err := secret.LoadKeyring(*path)
+	if err != nil {
+		return "", err
+	}
+	val, err := value(fs.Args())
+	if err != nil {
+		return "", err
+	}
+	if cmd == "encrypt" {
+		return keyring.Encrypt(val)
+	}
+	return keyring.Decrypt(val)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "genkey", "encrypt", "decrypt":
+	default:
+		usage()
+	}
+	out, err := run(os.Args[1], os.Args[2:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println(out)
+}
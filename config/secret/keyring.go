@@ -0,0 +1,144 @@
+package secret
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize - size of an AES-256 key
+const KeySize = 32
+
+var (
+	// ErrNoKey - the key an enc value was encrypted with is not in the keyring
+	ErrNoKey = errors.New("secret: key not found")
+	// ErrInvalidValue - an enc value is not in the format of Encrypt
+	ErrInvalidValue = errors.New("secret: invalid encrypted value")
+)
+
+// Keyring - AES-GCM keys by id. The first key encrypts, all keys decrypt,
+// so a new key is rotated in by putting it first and re-encrypting values.
+type Keyring struct {
+	ids  []string
+	keys map[string]cipher.AEAD
+}
+
+// GenerateKey return a new random key in the format of a keyring file.
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// NewKeyring create an empty keyring.
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string]cipher.AEAD)}
+}
+
+// ParseKeyring parse a keyring file, a "id:base64 key" per line,
+// empty lines and lines starting with # are skipped.
+func ParseKeyring(data []byte) (*Keyring, error) {
+	k := NewKeyring()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, encoded, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("secret: invalid keyring line %d", n)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("secret: invalid key %s err:%v", id, err)
+		}
+		if err := k.Add(strings.TrimSpace(id), key); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// LoadKeyring read a keyring file.
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("secret: read keyring err:%v", err)
+	}
+	return ParseKeyring(data)
+}
+
+// Add add a key, the first key added encrypts.
+func (k *Keyring) Add(id string, key []byte) error {
+	if id == "" || strings.Contains(id, ":") {
+		return fmt.Errorf("secret: invalid key id %q", id)
+	}
+	if _, ok := k.keys[id]; ok {
+		return fmt.Errorf("secret: duplicate key id %q", id)
+	}
+	if len(key) != KeySize {
+		return fmt.Errorf("secret: key %s is %d bytes, want %d", id, len(key), KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	k.ids = append(k.ids, id)
+	k.keys[id] = aead
+	return nil
+}
+
+// Encrypt encrypt plaintext with the first key, the result is like
+// enc:<key id>:<base64 nonce and ciphertext>.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if len(k.ids) == 0 {
+		return "", ErrNoKey
+	}
+	id := k.ids[0]
+	aead := k.keys[id]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(id))
+	return PrefixEnc + id + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypt a value of Encrypt.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, PrefixEnc), ":")
+	if !ok {
+		return "", ErrInvalidValue
+	}
+	aead, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNoKey, id)
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidValue
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("secret: decrypt with key %s err:%v", id, err)
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,375 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dbunion/com/config"
+	"github.com/spf13/cast"
+)
+
+// prefixes of references
+const (
+	// PrefixEnc - enc:<key id>:<ciphertext>, decrypted with the keyring
+	PrefixEnc = "enc:"
+	// PrefixFile - file:/path, the content of the file without the trailing newline
+	PrefixFile = "file:"
+	// PrefixEnv - env:NAME, the value of an environment variable
+	PrefixEnv = "env:"
+	// PrefixRaw - raw:value, a plain value which would be taken for a
+	// reference otherwise, e.g. raw:file:test.db is file:test.db
+	PrefixRaw = "raw:"
+)
+
+// Redacted - the value of a reference in AllSettings and OnChange
+const Redacted = "******"
+
+// IsReference check if value is a reference.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, PrefixEnc) ||
+		strings.HasPrefix(value, PrefixFile) ||
+		strings.HasPrefix(value, PrefixEnv)
+}
+
+// Resolve return the value of a reference, raw values without PrefixRaw
+// and other values as is.
+func Resolve(value string, keyring *Keyring) (string, error) {
+	switch {
+	case strings.HasPrefix(value, PrefixEnc):
+		if keyring == nil {
+			return "", ErrNoKey
+		}
+		return keyring.Decrypt(value)
+	case strings.HasPrefix(value, PrefixFile):
+		data, err := os.ReadFile(strings.TrimPrefix(value, PrefixFile))
+		if err != nil {
+			return "", fmt.Errorf("secret: read %s err:%v", value, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, PrefixEnv):
+		name := strings.TrimPrefix(value, PrefixEnv)
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("secret: env %s is not set", name)
+		}
+		return val, nil
+	case strings.HasPrefix(value, PrefixRaw):
+		return strings.TrimPrefix(value, PrefixRaw), nil
+	}
+	return value, nil
+}
+
+// walk replace the strings of a config value by fn, maps and slices are copied.
+func walk(v interface{}, fn func(string) (interface{}, error)) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		return fn(val)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			resolved, err := walk(item, fn)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = resolved
+		}
+		return m, nil
+	case []interface{}:
+		s := make([]interface{}, len(val))
+		for i, item := range val {
+			resolved, err := walk(item, fn)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = resolved
+		}
+		return s, nil
+	case []string:
+		s := make([]interface{}, len(val))
+		for i, item := range val {
+			resolved, err := fn(item)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = resolved
+		}
+		return s, nil
+	}
+	return v, nil
+}
+
+// Config resolve the references in the values of a config. Getters,
+// Unmarshal and Watch see the resolved values, AllSettings and OnChange
+// see Redacted instead, so dumps and change logs do not leak secrets.
+// All values are resolved when the config changed, a file of a reference
+// changed alone is not read again. A change whose references can not be
+// resolved is reported to OnError and the last resolved values are kept.
+type Config struct {
+	config.Config
+	keyring *Keyring
+	// Watch of the resolved values and OnError
+	notifier config.Notifier
+
+	mutex sync.RWMutex
+	// the last settings which were resolved and their resolved values
+	settings map[string]interface{}
+	resolved *config.Static
+}
+
+// Wrap create a config which resolve the references of c, enc values
+// are decrypted by keyring. All references are resolved once, so a
+// broken one fail here instead of when it is used.
+func Wrap(c config.Config, keyring *Keyring) (*Config, error) {
+	sc := &Config{Config: c, keyring: keyring}
+	settings := c.AllSettings()
+	resolved, err := sc.resolve(settings)
+	if err != nil {
+		return nil, err
+	}
+	sc.settings = settings
+	sc.resolved = config.NewStatic(resolved.(map[string]interface{}))
+	c.OnChange(func([]config.Change) {
+		sc.reload()
+	})
+	return sc, nil
+}
+
+// reload resolve the settings of the wrapped config after a change.
+func (c *Config) reload() {
+	settings := c.Config.AllSettings()
+	resolved, err := c.resolve(settings)
+	if err != nil {
+		c.notifier.NotifyError(fmt.Errorf("secret: resolve config err:%v", err))
+		return
+	}
+
+	c.mutex.Lock()
+	old := c.resolved
+	c.settings = settings
+	c.resolved = config.NewStatic(resolved.(map[string]interface{}))
+	c.mutex.Unlock()
+	c.notifier.Notify(old.AllSettings(), resolved.(map[string]interface{}))
+}
+
+// current return the last resolved values.
+func (c *Config) current() *config.Static {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.resolved
+}
+
+// resolve the references in v.
+func (c *Config) resolve(v interface{}) (interface{}, error) {
+	return walk(v, func(s string) (interface{}, error) {
+		return Resolve(s, c.keyring)
+	})
+}
+
+// redact the references in v.
+func redact(v interface{}) interface{} {
+	redacted, _ := walk(v, func(s string) (interface{}, error) {
+		if IsReference(s) {
+			return Redacted, nil
+		}
+		return strings.TrimPrefix(s, PrefixRaw), nil
+	})
+	return redacted
+}
+
+// Get get the resolved config value by key.
+func (c *Config) Get(key string) interface{} {
+	return c.current().Get(key)
+}
+
+// GetBool get bool config value by key
+func (c *Config) GetBool(key string) bool {
+	return cast.ToBool(c.Get(key))
+}
+
+// GetFloat64 get float64 config value by key
+func (c *Config) GetFloat64(key string) float64 {
+	return cast.ToFloat64(c.Get(key))
+}
+
+// GetInt get Int config value by key
+func (c *Config) GetInt(key string) int {
+	return cast.ToInt(c.Get(key))
+}
+
+// GetInt32 get Int32 config value by key
+func (c *Config) GetInt32(key string) int32 {
+	return cast.ToInt32(c.Get(key))
+}
+
+// GetInt64 get Int64 config value by key
+func (c *Config) GetInt64(key string) int64 {
+	return cast.ToInt64(c.Get(key))
+}
+
+// GetString get string config value by key
+func (c *Config) GetString(key string) string {
+	return cast.ToString(c.Get(key))
+}
+
+// GetStringMap get stringMap config value by key
+func (c *Config) GetStringMap(key string) map[string]interface{} {
+	return cast.ToStringMap(c.Get(key))
+}
+
+// GetStringMapString get stringMapstring config value by key
+func (c *Config) GetStringMapString(key string) map[string]string {
+	return cast.ToStringMapString(c.Get(key))
+}
+
+// GetStringSlice get stringSlice config value by key
+func (c *Config) GetStringSlice(key string) []string {
+	return cast.ToStringSlice(c.Get(key))
+}
+
+// GetTime get time config value by key
+func (c *Config) GetTime(key string) time.Time {
+	return cast.ToTime(c.Get(key))
+}
+
+// GetDuration get Duration config value by key
+func (c *Config) GetDuration(key string) time.Duration {
+	return cast.ToDuration(c.Get(key))
+}
+
+// IsExist check if config value exists or not.
+func (c *Config) IsExist(key string) bool {
+	return c.current().IsExist(key)
+}
+
+// AllSettings get all config value, references are redacted.
+func (c *Config) AllSettings() map[string]interface{} {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	settings, _ := redact(c.settings).(map[string]interface{})
+	return settings
+}
+
+// Unmarshal bind the resolved value of key into a struct.
+func (c *Config) Unmarshal(key string, out interface{}) error {
+	return c.current().Unmarshal(key, out)
+}
+
+// UnmarshalAll bind all resolved config value into a struct.
+func (c *Config) UnmarshalAll(out interface{}) error {
+	return c.current().UnmarshalAll(out)
+}
+
+// Watch call fn with the resolved old and new value when key changed.
+func (c *Config) Watch(key string, fn func(old, new interface{})) {
+	c.notifier.Watch(key, fn)
+}
+
+// OnError call fn with the errors of the wrapped config and the changes
+// whose references can not be resolved.
+func (c *Config) OnError(fn func(err error)) {
+	c.notifier.OnError(fn)
+	if n, ok := c.Config.(config.ErrorNotifier); ok {
+		n.OnError(fn)
+	}
+}
+
+// OnChange call fn with the changed keys, references are redacted.
+func (c *Config) OnChange(fn func(changes []config.Change)) {
+	c.Config.OnChange(func(changes []config.Change) {
+		redacted := make([]config.Change, len(changes))
+		for i, change := range changes {
+			redacted[i] = config.Change{Key: change.Key, Old: redact(change.Old), New: redact(change.New)}
+		}
+		fn(redacted)
+	})
+}
+
+// ErrNotWritable is returned by the Writer methods if the wrapped config
+// does not implement config.Writer.
+var ErrNotWritable = errors.New("secret: config is not writable")
+
+// writer return the wrapped config.Writer.
+func (c *Config) writer() (config.Writer, error) {
+	w, ok := c.Config.(config.Writer)
+	if !ok {
+		return nil, ErrNotWritable
+	}
+	return w, nil
+}
+
+// Set set key to value in the wrapped config, a secret should be set as a reference.
+func (c *Config) Set(ctx context.Context, key string, value interface{}) error {
+	w, err := c.writer()
+	if err != nil {
+		return err
+	}
+	return w.Set(ctx, key, value)
+}
+
+// Delete delete key and the keys below it in the wrapped config.
+func (c *Config) Delete(ctx context.Context, key string) error {
+	w, err := c.writer()
+	if err != nil {
+		return err
+	}
+	return w.Delete(ctx, key)
+}
+
+// Snapshot record the settings of the wrapped config as a version.
+func (c *Config) Snapshot(ctx context.Context) (*config.Version, error) {
+	w, err := c.writer()
+	if err != nil {
+		return nil, err
+	}
+	return w.Snapshot(ctx)
+}
+
+// Rollback restore the settings of a version in the wrapped config.
+func (c *Config) Rollback(ctx context.Context, version int64) error {
+	w, err := c.writer()
+	if err != nil {
+		return err
+	}
+	return w.Rollback(ctx, version)
+}
+
+// History get all versions of the wrapped config, references are kept
+// as recorded and never resolved.
+func (c *Config) History(ctx context.Context) ([]config.Version, error) {
+	w, err := c.writer()
+	if err != nil {
+		return nil, err
+	}
+	return w.History(ctx)
+}
+
+// Close close the wrapped config.
+func (c *Config) Close() error {
+	if closer, ok := c.Config.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// wrap is the secrets of NewConfig, enc values are decrypted with the
+// keyring file of param.
+func wrap(c config.Config, param config.Param) (config.Config, error) {
+	var keyring *Keyring
+	if param.Keyring != "" {
+		var err error
+		if keyring, err = LoadKeyring(param.Keyring); err != nil {
+			return nil, err
+		}
+	}
+	return Wrap(c, keyring)
+}
+
+func init() {
+	config.RegisterSecrets(wrap)
+}
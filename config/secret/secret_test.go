@@ -0,0 +1,243 @@
+package secret
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/config"
+	_ "github.com/dbunion/com/config/file"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestKeyring create a keyring of new keys.
+func newTestKeyring(t *testing.T, ids ...string) (*Keyring, string) {
+	var lines []string
+	for _, id := range ids {
+		key, err := GenerateKey()
+		if err != nil {
+			t.Fatalf("generate key error, err:%v", err)
+		}
+		lines = append(lines, id+":"+key)
+	}
+	data := "# test keys\n" + strings.Join(lines, "\n") + "\n"
+	k, err := ParseKeyring([]byte(data))
+	if err != nil {
+		t.Fatalf("parse keyring error, err:%v", err)
+	}
+	return k, data
+}
+
+func TestKeyring(t *testing.T) {
+	k, data := newTestKeyring(t, "new", "old")
+	enc, err := k.Encrypt("p@ss")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(enc, "enc:new:"))
+	plain, err := k.Decrypt(enc)
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ss", plain)
+
+	// the same plaintext encrypt differently
+	again, _ := k.Encrypt("p@ss")
+	assert.NotEqual(t, enc, again)
+
+	// values of a rotated out key still decrypt, values of a missing key do not
+	old, _ := ParseKeyring([]byte(strings.Replace(data, "new:", "#", 1)))
+	oldEnc, _ := old.Encrypt("old")
+	plain, err = k.Decrypt(oldEnc)
+	assert.Nil(t, err)
+	assert.Equal(t, "old", plain)
+	_, err = old.Decrypt(enc)
+	assert.True(t, errors.Is(err, ErrNoKey))
+
+	// tampered values fail
+	_, err = k.Decrypt(enc[:len(enc)-2] + "AA")
+	assert.NotNil(t, err)
+	_, err = k.Decrypt("enc:new")
+	assert.Equal(t, ErrInvalidValue, err)
+
+	for _, data := range []string{"nocolon", "id:!!", "id:" + base64.StdEncoding.EncodeToString([]byte("short"))} {
+		_, err := ParseKeyring([]byte(data))
+		assert.NotNil(t, err)
+	}
+}
+
+func TestSecretConfig(t *testing.T) {
+	k, _ := newTestKeyring(t, "1")
+	enc, _ := k.Encrypt("db-pass")
+	file := filepath.Join(t.TempDir(), "redis")
+	assert.Nil(t, os.WriteFile(file, []byte("redis-pass\n"), 0600))
+	t.Setenv("TEST_TOKEN", "token")
+
+	values := map[string]interface{}{
+		"db":    map[string]interface{}{"user": "root", "password": enc, "port": 3306},
+		"redis": map[string]interface{}{"password": "file:" + file},
+		"token": "env:TEST_TOKEN",
+		"tags":  []interface{}{"a", "env:TEST_TOKEN"},
+	}
+	c, err := Wrap(config.NewStatic(values), k)
+	if err != nil {
+		t.Fatalf("wrap config error, err:%v", err)
+	}
+
+	assert.Equal(t, "db-pass", c.GetString("db.password"))
+	assert.Equal(t, "redis-pass", c.GetString("redis.password"))
+	assert.Equal(t, "token", c.GetString("token"))
+	assert.Equal(t, []string{"a", "token"}, c.GetStringSlice("tags"))
+	assert.Equal(t, 3306, c.GetInt("db.port"))
+	assert.Equal(t, map[string]string{"user": "root", "password": "db-pass", "port": "3306"}, c.GetStringMapString("db"))
+
+	var db struct {
+		User     string `config:"user"`
+		Password string `config:"password" validate:"required"`
+	}
+	assert.Nil(t, c.Unmarshal("db", &db))
+	assert.Equal(t, "db-pass", db.Password)
+
+	// dumps never contain secrets
+	assert.Equal(t, map[string]interface{}{
+		"db":    map[string]interface{}{"user": "root", "password": Redacted, "port": 3306},
+		"redis": map[string]interface{}{"password": Redacted},
+		"token": Redacted,
+		"tags":  []interface{}{"a", Redacted},
+	}, c.AllSettings())
+
+	// broken references fail fast
+	for _, val := range []string{"enc:2:abc", "file:/missing", "env:TEST_MISSING"} {
+		_, err := Wrap(config.NewStatic(map[string]interface{}{"password": val}), k)
+		assert.NotNil(t, err, val)
+	}
+	_, err = Wrap(config.NewStatic(map[string]interface{}{"password": enc}), nil)
+	assert.NotNil(t, err)
+
+	// plain values which look like a reference are escaped by raw:
+	c, err = Wrap(config.NewStatic(map[string]interface{}{"dsn": "raw:file:test.db?cache=shared", "env": "raw:env:prod"}), k)
+	assert.Nil(t, err)
+	assert.Equal(t, "file:test.db?cache=shared", c.GetString("dsn"))
+	assert.Equal(t, map[string]interface{}{"dsn": "file:test.db?cache=shared", "env": "env:prod"}, c.AllSettings())
+}
+
+func TestNewConfigSecrets(t *testing.T) {
+	k, data := newTestKeyring(t, "1")
+	enc, _ := k.Encrypt("db-pass")
+	dir := t.TempDir()
+	keyring := filepath.Join(dir, "keys.txt")
+	assert.Nil(t, os.WriteFile(keyring, []byte(data), 0600))
+	file := filepath.Join(dir, "app.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte("db:\n  password: "+enc+"\n  dsn: raw:file:test.db\n"), 0600))
+
+	// references are resolved by NewConfig
+	c, err := config.NewConfig(config.TypeFile, config.Param{File: file, Type: "yaml", Secrets: true, Keyring: keyring})
+	if err != nil {
+		t.Fatalf("create config error, err:%v", err)
+	}
+	assert.Equal(t, "db-pass", c.GetString("db.password"))
+	assert.Equal(t, "file:test.db", c.GetString("db.dsn"))
+	assert.Equal(t, map[string]interface{}{"password": Redacted, "dsn": "file:test.db"}, c.AllSettings()["db"])
+
+	// without the keyring the config fail to start
+	_, err = config.NewConfig(config.TypeFile, config.Param{File: file, Type: "yaml", Secrets: true})
+	assert.NotNil(t, err)
+}
+
+func TestSecretConfigCache(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	assert.Nil(t, os.WriteFile(secret, []byte("v1"), 0600))
+	file := filepath.Join(dir, "app.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte("password: file:"+secret+"\n"), 0600))
+	c, err := config.NewConfig(config.TypeFile, config.Param{File: file, Type: "yaml", Secrets: true})
+	if err != nil {
+		t.Fatalf("create config error, err:%v", err)
+	}
+	sc := c.(*Config)
+	defer sc.Close()
+
+	// the file is read once
+	assert.Equal(t, "v1", sc.GetString("password"))
+	assert.Nil(t, os.WriteFile(secret, []byte("v2"), 0600))
+	assert.Equal(t, "v1", sc.GetString("password"))
+
+	// a change of the config resolve the values again
+	assert.Nil(t, sc.Set(context.Background(), "name", "app"))
+	assert.Eventually(t, func() bool { return sc.GetString("password") == "v2" }, time.Second, 10*time.Millisecond)
+}
+
+func TestSecretConfigWriter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte("token: env:TEST_TOKEN\n"), 0600))
+	t.Setenv("TEST_TOKEN", "token")
+	c, err := config.NewConfig(config.TypeFile, config.Param{File: file, Type: "yaml", Secrets: true})
+	if err != nil {
+		t.Fatalf("create config error, err:%v", err)
+	}
+	defer c.(*Config).Close()
+
+	// the writer methods of the wrapped config are forwarded
+	w, ok := c.(config.Writer)
+	assert.True(t, ok)
+	ctx := context.Background()
+	assert.Nil(t, w.Set(ctx, "name", "app"))
+	assert.Eventually(t, func() bool { return c.GetString("name") == "app" }, time.Second, 10*time.Millisecond)
+	assert.Nil(t, w.Delete(ctx, "name"))
+	_, err = w.Snapshot(ctx)
+	assert.Nil(t, err)
+	versions, err := w.History(ctx)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, versions)
+	assert.Equal(t, "env:TEST_TOKEN", versions[len(versions)-1].Settings["token"])
+	assert.Nil(t, w.Rollback(ctx, versions[0].ID))
+	assert.Eventually(t, func() bool { return c.GetString("name") == "app" }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "token", c.GetString("token"))
+
+	// a static config is not writable
+	sc, err := Wrap(config.NewStatic(map[string]interface{}{"name": "app"}), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrNotWritable, sc.Set(ctx, "name", "other"))
+}
+
+func TestSecretConfigResolveError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	assert.Nil(t, os.WriteFile(file, []byte("token: env:TEST_TOKEN\n"), 0600))
+	t.Setenv("TEST_TOKEN", "v1")
+	t.Setenv("TEST_OTHER", "v2")
+	c, err := config.NewConfig(config.TypeFile, config.Param{File: file, Type: "yaml", Secrets: true})
+	if err != nil {
+		t.Fatalf("create config error, err:%v", err)
+	}
+	sc := c.(*Config)
+	defer sc.Close()
+
+	errs := make(chan error, 10)
+	sc.OnError(func(err error) { errs <- err })
+	tokens := make(chan []interface{}, 10)
+	sc.Watch("token", func(old, new interface{}) { tokens <- []interface{}{old, new} })
+
+	// a change which can not be resolved is reported and the last values are kept
+	ctx := context.Background()
+	assert.Nil(t, sc.Set(ctx, "token", "env:TEST_MISSING"))
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), "env TEST_MISSING is not set")
+	case <-time.After(time.Second):
+		t.Fatalf("resolve error not notified")
+	}
+	assert.Equal(t, "v1", sc.GetString("token"))
+	assert.Equal(t, Redacted, sc.AllSettings()["token"])
+	assert.Empty(t, tokens)
+
+	// watchers see the resolved values of the next change
+	assert.Nil(t, sc.Set(ctx, "token", "env:TEST_OTHER"))
+	select {
+	case token := <-tokens:
+		assert.Equal(t, []interface{}{"v1", "v2"}, token)
+	case <-time.After(time.Second):
+		t.Fatalf("change not notified")
+	}
+	assert.Equal(t, "v2", sc.GetString("token"))
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSConfig build the tls config of param, nil if no file is set.
+func (p Param) TLSConfig() (*tls.Config, error) {
+	if p.CAFile == "" && p.CertFile == "" && p.KeyFile == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if p.CertFile != "" || p.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(p.CertFile, p.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load tls key pair err:%v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if p.CAFile != "" {
+		pem, err := os.ReadFile(p.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read tls ca file err:%v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in tls ca file %s", p.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
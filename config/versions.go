@@ -0,0 +1,339 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// actions of a version
+const (
+	ActionSet      = "set"
+	ActionDelete   = "delete"
+	ActionSnapshot = "snapshot"
+	ActionRollback = "rollback"
+)
+
+// ErrVersionNotFound - the version to roll back to is not in the history
+var ErrVersionNotFound = errors.New("config: version not found")
+
+// Version - a recorded change of a writable config
+type Version struct {
+	ID     int64     `json:"id"`
+	Time   time.Time `json:"time"`
+	Author string    `json:"author"`
+	Action string    `json:"action"`
+	// the version rolled back to
+	Target int64 `json:"target,omitempty"`
+	// changed keys, empty for a snapshot
+	Changes []Change `json:"changes,omitempty"`
+	// all settings after the change
+	Settings map[string]interface{} `json:"settings"`
+}
+
+// Writer - a config which can be changed. Every change is recorded as a
+// version with its author, see WithAuthor, and can be rolled back.
+type Writer interface {
+	Config
+	// set key to value, a map value set the keys below key
+	Set(ctx context.Context, key string, value interface{}) error
+	// delete key and the keys below it
+	Delete(ctx context.Context, key string) error
+	// record the current settings as a version, e.g. before a push
+	Snapshot(ctx context.Context) (*Version, error)
+	// restore the settings of a version, recorded as a new version
+	Rollback(ctx context.Context, version int64) error
+	// get all versions, the oldest first
+	History(ctx context.Context) ([]Version, error)
+}
+
+// History - where the versions of a writable config are kept
+type History interface {
+	// record v and set its ID, one more than the last version
+	Append(ctx context.Context, v *Version) error
+	// get all versions, the oldest first
+	List(ctx context.Context) ([]Version, error)
+}
+
+type authorKey struct{}
+
+// WithAuthor return a context whose changes are recorded as made by author.
+func WithAuthor(ctx context.Context, author string) context.Context {
+	return context.WithValue(ctx, authorKey{}, author)
+}
+
+// Author return the author of ctx, user@host of the process if not set.
+func Author(ctx context.Context) string {
+	if author, ok := ctx.Value(authorKey{}).(string); ok && author != "" {
+		return author
+	}
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	return name + "@" + host
+}
+
+// Write - a change for an adapter to write
+type Write struct {
+	// dotted keys to set
+	Puts map[string]interface{}
+	// dotted keys to remove with the keys below them, before Puts are set
+	Deletes []string
+	// all settings after the change
+	Settings map[string]interface{}
+}
+
+// Versions implement Set, Delete, Snapshot, Rollback and History of a
+// writable adapter on top of its write. Changes made by one Versions
+// are serialized, changes of several processes are not.
+type Versions struct {
+	mutex    sync.Mutex
+	history  History
+	settings func() map[string]interface{}
+	write    func(ctx context.Context, w Write) error
+}
+
+// NewVersions create the versions of an adapter, settings return its
+// current settings and write apply a change, in one step if it can.
+func NewVersions(history History, settings func() map[string]interface{},
+	write func(ctx context.Context, w Write) error) *Versions {
+	return &Versions{history: history, settings: settings, write: write}
+}
+
+// change write puts and deletes and record the settings after as a version.
+func (v *Versions) change(ctx context.Context, version *Version, puts map[string]interface{}, deletes []string) error {
+	old := v.settings()
+	leaves := make(map[string]interface{})
+	flatten("", old, leaves)
+	for _, key := range deletes {
+		for k := range leaves {
+			if k == key || strings.HasPrefix(k, key+".") {
+				delete(leaves, k)
+			}
+		}
+	}
+	for key, val := range puts {
+		for k := range leaves {
+			if strings.HasPrefix(k, key+".") || strings.HasPrefix(key, k+".") {
+				delete(leaves, k)
+			}
+		}
+		leaves[key] = val
+	}
+	settings := unflatten(leaves)
+
+	if err := v.write(ctx, Write{Puts: puts, Deletes: deletes, Settings: settings}); err != nil {
+		return err
+	}
+	version.Time = time.Now()
+	version.Author = Author(ctx)
+	version.Changes = Diff(old, settings)
+	version.Settings = settings
+	if err := v.history.Append(ctx, version); err != nil {
+		return fmt.Errorf("config: record version err:%v", err)
+	}
+	return nil
+}
+
+// Set set key to value, a map value set the keys below key.
+func (v *Versions) Set(ctx context.Context, key string, value interface{}) error {
+	key = strings.ToLower(key)
+	if key == "" {
+		return errors.New("config: key can not be empty")
+	}
+	puts := make(map[string]interface{})
+	if m, ok := value.(map[string]interface{}); ok {
+		flatten(key, m, puts)
+	} else {
+		puts[key] = value
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	// a map replace the keys below key
+	return v.change(ctx, &Version{Action: ActionSet}, puts, []string{key})
+}
+
+// Delete delete key and the keys below it.
+func (v *Versions) Delete(ctx context.Context, key string) error {
+	key = strings.ToLower(key)
+	if key == "" {
+		return errors.New("config: key can not be empty")
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.change(ctx, &Version{Action: ActionDelete}, nil, []string{key})
+}
+
+// Snapshot record the current settings as a version.
+func (v *Versions) Snapshot(ctx context.Context) (*Version, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	version := &Version{
+		Time:     time.Now(),
+		Author:   Author(ctx),
+		Action:   ActionSnapshot,
+		Settings: v.settings(),
+	}
+	if err := v.history.Append(ctx, version); err != nil {
+		return nil, fmt.Errorf("config: record version err:%v", err)
+	}
+	return version, nil
+}
+
+// Rollback restore the settings of version.
+func (v *Versions) Rollback(ctx context.Context, version int64) error {
+	versions, err := v.history.List(ctx)
+	if err != nil {
+		return err
+	}
+	var target *Version
+	for i := range versions {
+		if versions[i].ID == version {
+			target = &versions[i]
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	puts := make(map[string]interface{})
+	var deletes []string
+	// the history decode numbers as float64, compare them in the same type
+	current := normalize(v.settings()).(map[string]interface{})
+	for _, c := range Diff(current, normalize(target.Settings).(map[string]interface{})) {
+		if c.New == nil {
+			deletes = append(deletes, c.Key)
+		} else {
+			puts[c.Key] = c.New
+		}
+	}
+	return v.change(ctx, &Version{Action: ActionRollback, Target: version}, puts, deletes)
+}
+
+// normalize convert the numbers in v to float64 as json decode them.
+func normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			m[k] = normalize(e)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, e := range val {
+			m[fmt.Sprint(k)] = normalize(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(val))
+		for i, e := range val {
+			s[i] = normalize(e)
+		}
+		return s
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return v
+}
+
+// History get all versions, the oldest first.
+func (v *Versions) History(ctx context.Context) ([]Version, error) {
+	return v.history.List(ctx)
+}
+
+// FileHistory keep versions in a file, a json version per line.
+type FileHistory struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// NewFileHistory create a history kept in the file of path.
+func NewFileHistory(path string) *FileHistory {
+	return &FileHistory{path: path}
+}
+
+// Append record v and set its ID.
+func (h *FileHistory) Append(ctx context.Context, v *Version) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	versions, err := h.list()
+	if err != nil {
+		return err
+	}
+	v.ID = 1
+	if len(versions) > 0 {
+		v.ID = versions[len(versions)-1].ID + 1
+	}
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// List get all versions, the oldest first.
+func (h *FileHistory) List(ctx context.Context) ([]Version, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.list()
+}
+
+func (h *FileHistory) list() ([]Version, error) {
+	f, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var versions []Version
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var v Version
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			return nil, fmt.Errorf("config: invalid history %s err:%v", h.path, err)
+		}
+		versions = append(versions, v)
+	}
+	return versions, scanner.Err()
+}
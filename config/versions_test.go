@@ -0,0 +1,36 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionsRollback(t *testing.T) {
+	settings := map[string]interface{}{
+		"db": map[string]interface{}{"port": 3306, "pool": map[interface{}]interface{}{"size": int64(10)}},
+	}
+	var last Write
+	v := NewVersions(NewFileHistory(filepath.Join(t.TempDir(), "history")),
+		func() map[string]interface{} { return settings },
+		func(ctx context.Context, w Write) error {
+			last = w
+			settings = w.Settings
+			return nil
+		})
+	ctx := context.Background()
+
+	first, err := v.Snapshot(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, v.Set(ctx, "name", "test"))
+
+	// the unchanged ints are not rewritten by the float64 of the history
+	assert.Nil(t, v.Rollback(ctx, first.ID))
+	assert.Empty(t, last.Puts)
+	assert.Equal(t, []string{"name"}, last.Deletes)
+	versions, err := v.History(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []Change{{Key: "name", Old: "test"}}, versions[2].Changes)
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// view - typed getters over a settings tree which is replaced as a whole
+type view struct {
+	mutex sync.RWMutex
+	vp    *viper.Viper
+}
+
+// unflatten nest leaves by dotted key, a key is shadowed by the keys below it.
+func unflatten(leaves map[string]interface{}) map[string]interface{} {
+	tree := make(map[string]interface{})
+	for key, val := range leaves {
+		if key == "" {
+			continue
+		}
+		parts := strings.Split(strings.ToLower(key), ".")
+		node := tree
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[part] = child
+			}
+			node = child
+		}
+		last := parts[len(parts)-1]
+		if _, ok := node[last].(map[string]interface{}); !ok {
+			node[last] = val
+		}
+	}
+	return tree
+}
+
+// set replace the settings, return the settings before.
+func (v *view) set(settings map[string]interface{}) map[string]interface{} {
+	vp := viper.New()
+	_ = vp.MergeConfigMap(settings)
+
+	v.mutex.Lock()
+	old := v.vp
+	v.vp = vp
+	v.mutex.Unlock()
+	if old == nil {
+		return map[string]interface{}{}
+	}
+	return old.AllSettings()
+}
+
+// snapshot return the current viper.
+func (v *view) snapshot() *viper.Viper {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	if v.vp == nil {
+		return viper.New()
+	}
+	return v.vp
+}
+
+// Get get config value by key.
+func (v *view) Get(key string) interface{} {
+	return v.snapshot().Get(key)
+}
+
+// GetBool get bool config value by key
+func (v *view) GetBool(key string) bool {
+	return v.snapshot().GetBool(key)
+}
+
+// GetFloat64 get float64 config value by key
+func (v *view) GetFloat64(key string) float64 {
+	return v.snapshot().GetFloat64(key)
+}
+
+// GetInt get Int config value by key
+func (v *view) GetInt(key string) int {
+	return v.snapshot().GetInt(key)
+}
+
+// GetInt32 get Int32 config value by key
+func (v *view) GetInt32(key string) int32 {
+	return v.snapshot().GetInt32(key)
+}
+
+// GetInt64 get Int64 config value by key
+func (v *view) GetInt64(key string) int64 {
+	return v.snapshot().GetInt64(key)
+}
+
+// GetString get string config value by key
+func (v *view) GetString(key string) string {
+	return v.snapshot().GetString(key)
+}
+
+// GetStringMap get stringMap config value by key
+func (v *view) GetStringMap(key string) map[string]interface{} {
+	return v.snapshot().GetStringMap(key)
+}
+
+// GetStringMapString get stringMapstring config value by key
+func (v *view) GetStringMapString(key string) map[string]string {
+	return v.snapshot().GetStringMapString(key)
+}
+
+// GetStringSlice get stringSlice config value by key
+func (v *view) GetStringSlice(key string) []string {
+	return v.snapshot().GetStringSlice(key)
+}
+
+// GetTime get time config value by key
+func (v *view) GetTime(key string) time.Time {
+	return v.snapshot().GetTime(key)
+}
+
+// GetDuration get Duration config value by key
+func (v *view) GetDuration(key string) time.Duration {
+	return v.snapshot().GetDuration(key)
+}
+
+// AllSettings get all config value
+func (v *view) AllSettings() map[string]interface{} {
+	return v.snapshot().AllSettings()
+}
+
+// Unmarshal bind the value of key into a struct.
+func (v *view) Unmarshal(key string, out interface{}) error {
+	return Decode(v.snapshot().Get(key), out)
+}
+
+// UnmarshalAll bind all config value into a struct.
+func (v *view) UnmarshalAll(out interface{}) error {
+	return Decode(v.snapshot().AllSettings(), out)
+}
+
+// IsExist check if config value exists or not.
+func (v *view) IsExist(key string) bool {
+	return v.snapshot().IsSet(key)
+}
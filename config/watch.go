@@ -0,0 +1,156 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Change - a key changed by a reload, Old is nil if the key is added
+// and New is nil if it is removed.
+type Change struct {
+	Key string      `json:"key"`
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// watcher - a callback of Watch
+type watcher struct {
+	key string
+	fn  func(old, new interface{})
+}
+
+// Notifier keep the change subscriptions of an adapter. An adapter
+// embed it and call Notify with the settings before and after a reload,
+// or NotifyError if a reload failed. The zero value is ready to use.
+type Notifier struct {
+	mutex    sync.Mutex
+	watchers []watcher
+	handlers []func(changes []Change)
+	errors   []func(err error)
+}
+
+// Watch call fn when the value of key or any key below it changed.
+func (n *Notifier) Watch(key string, fn func(old, new interface{})) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.watchers = append(n.watchers, watcher{key: strings.ToLower(key), fn: fn})
+}
+
+// OnChange call fn with the changed keys of every reload which changed something.
+func (n *Notifier) OnChange(fn func(changes []Change)) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.handlers = append(n.handlers, fn)
+}
+
+// OnError call fn with the error of every reload which failed, the
+// settings before it are kept.
+func (n *Notifier) OnError(fn func(err error)) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.errors = append(n.errors, fn)
+}
+
+// NotifyError call the callbacks of OnError with err.
+func (n *Notifier) NotifyError(err error) {
+	n.mutex.Lock()
+	handlers := n.errors
+	n.mutex.Unlock()
+	for _, fn := range handlers {
+		fn(err)
+	}
+}
+
+// Notify diff the settings of AllSettings before and after a reload and
+// call the callbacks of the changed keys.
+func (n *Notifier) Notify(old, new map[string]interface{}) {
+	n.mutex.Lock()
+	watchers := n.watchers
+	handlers := n.handlers
+	n.mutex.Unlock()
+	if len(watchers) == 0 && len(handlers) == 0 {
+		return
+	}
+
+	changes := Diff(old, new)
+	if len(changes) == 0 {
+		return
+	}
+	for _, w := range watchers {
+		for _, c := range changes {
+			if c.Key == w.key || strings.HasPrefix(c.Key, w.key+".") {
+				w.fn(lookup(old, w.key), lookup(new, w.key))
+				break
+			}
+		}
+	}
+	for _, fn := range handlers {
+		fn(changes)
+	}
+}
+
+// Diff return the changed leaf keys of two settings sorted by key,
+// nested maps are compared key by key and other values as a whole.
+func Diff(old, new map[string]interface{}) []Change {
+	before := make(map[string]interface{})
+	after := make(map[string]interface{})
+	flatten("", old, before)
+	flatten("", new, after)
+
+	var changes []Change
+	for key, val := range before {
+		if next, ok := after[key]; !ok {
+			changes = append(changes, Change{Key: key, Old: val})
+		} else if !reflect.DeepEqual(val, next) {
+			changes = append(changes, Change{Key: key, Old: val, New: next})
+		}
+	}
+	for key, val := range after {
+		if _, ok := before[key]; !ok {
+			changes = append(changes, Change{Key: key, New: val})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// flatten put the leaves of settings into out by dotted key.
+func flatten(prefix string, settings map[string]interface{}, out map[string]interface{}) {
+	for k, v := range settings {
+		key := strings.ToLower(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch m := v.(type) {
+		case map[string]interface{}:
+			flatten(key, m, out)
+		case map[interface{}]interface{}:
+			converted := make(map[string]interface{}, len(m))
+			for mk, mv := range m {
+				if s, ok := mk.(string); ok {
+					converted[s] = mv
+				}
+			}
+			flatten(key, converted, out)
+		default:
+			out[key] = v
+		}
+	}
+}
+
+// lookup find the value of a dotted key in settings.
+func lookup(settings map[string]interface{}, key string) interface{} {
+	var val interface{} = settings
+	for _, part := range strings.Split(key, ".") {
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if val, ok = m[part]; !ok {
+			return nil
+		}
+	}
+	return val
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	old := map[string]interface{}{
+		"name": "test",
+		"db":   map[string]interface{}{"host": "127.0.0.1", "port": 3306},
+		"tags": []interface{}{"a", "b"},
+		"gone": true,
+	}
+	new := map[string]interface{}{
+		"name": "test",
+		"db":   map[string]interface{}{"host": "127.0.0.1", "port": 3307},
+		"tags": []interface{}{"a", "c"},
+		"new":  1,
+	}
+	assert.Equal(t, []Change{
+		{Key: "db.port", Old: 3306, New: 3307},
+		{Key: "gone", Old: true},
+		{Key: "new", New: 1},
+		{Key: "tags", Old: []interface{}{"a", "b"}, New: []interface{}{"a", "c"}},
+	}, Diff(old, new))
+	assert.Empty(t, Diff(old, old))
+}
+
+func TestNotifier(t *testing.T) {
+	var n Notifier
+	var db, port, name []interface{}
+	var changes [][]Change
+	n.Watch("db", func(old, new interface{}) { db = append(db, old, new) })
+	n.Watch("DB.Port", func(old, new interface{}) { port = append(port, old, new) })
+	n.Watch("name", func(old, new interface{}) { name = append(name, old, new) })
+	n.OnChange(func(c []Change) { changes = append(changes, c) })
+
+	old := map[string]interface{}{"name": "test", "db": map[string]interface{}{"host": "a", "port": 1}}
+	new := map[string]interface{}{"name": "test", "db": map[string]interface{}{"host": "b", "port": 1}}
+	n.Notify(old, new)
+	assert.Equal(t, []interface{}{old["db"], new["db"]}, db)
+	assert.Empty(t, port)
+	assert.Empty(t, name)
+	assert.Equal(t, [][]Change{{{Key: "db.host", Old: "a", New: "b"}}}, changes)
+
+	// nothing changed, nothing called
+	n.Notify(new, new)
+	assert.Len(t, changes, 1)
+
+	n.Notify(new, map[string]interface{}{})
+	assert.Equal(t, []interface{}{1, nil}, port)
+	assert.Equal(t, []interface{}{"test", nil}, name)
+}
@@ -0,0 +1,48 @@
+// Package prom share prometheus helpers of the instrumented packages.
+package prom
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Registry - collectors M of a package, created once per registerer
+type Registry[M any] struct {
+	mutex      sync.Mutex
+	registered map[prometheus.Registerer]*M
+}
+
+// Load return the collectors of reg, calling create to register them the first time.
+func (r *Registry[M]) Load(reg prometheus.Registerer, create func(reg prometheus.Registerer) (*M, error)) (*M, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if m, ok := r.registered[reg]; ok {
+		return m, nil
+	}
+
+	m, err := create(reg)
+	if err != nil {
+		return nil, err
+	}
+	if r.registered == nil {
+		r.registered = make(map[prometheus.Registerer]*M)
+	}
+	r.registered[reg] = m
+	return m, nil
+}
+
+// Register register c, or return the same collector registered before.
+func Register[T prometheus.Collector](reg prometheus.Registerer, c T) (T, error) {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
@@ -0,0 +1,34 @@
+package prom
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	var r Registry[prometheus.Counter]
+	reg := prometheus.NewRegistry()
+	create := func(reg prometheus.Registerer) (*prometheus.Counter, error) {
+		c, err := Register(reg, prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "test"}))
+		return &c, err
+	}
+
+	// collectors are created once per registerer
+	first, err := r.Load(reg, create)
+	assert.Nil(t, err)
+	second, err := r.Load(reg, create)
+	assert.Nil(t, err)
+	assert.Same(t, first, second)
+
+	// a collector registered before is reused
+	var other Registry[prometheus.Counter]
+	third, err := other.Load(reg, create)
+	assert.Nil(t, err)
+	assert.Equal(t, *first, *third)
+
+	// conflicting collectors fail
+	_, err = Register(reg, prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_total", Help: "test"}))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,263 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// TypeRedis - lock use a single redis node
+	TypeRedis = "redis"
+	// TypeRedlock - lock use the redlock algorithm across independent redis nodes
+	TypeRedlock = "redlock"
+	// TypeMySQL - lock use mysql GET_LOCK
+	TypeMySQL = "mysql"
+	// TypeMemory - lock in process memory, for tests
+	TypeMemory = "memory"
+)
+
+const (
+	defaultTTL           = 30 * time.Second
+	defaultRetryInterval = 100 * time.Millisecond
+)
+
+var (
+	// ErrLocked is returned by TryLock if the lock is held by someone else.
+	ErrLocked = errors.New("lock: already locked")
+	// ErrNotHeld is returned by Refresh and Unlock if the lease is no longer held.
+	ErrNotHeld = errors.New("lock: lease not held")
+)
+
+// Config - lock config
+type Config struct {
+	// lease expiration, default 30s
+	TTL time.Duration `json:"ttl"`
+	// interval between attempts of Lock, default 100ms
+	RetryInterval time.Duration `json:"retry_interval"`
+	// refresh leases every TTL/3 until unlocked
+	AutoRefresh bool `json:"auto_refresh"`
+
+	// public common
+	Server   string `json:"server"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Port     int64  `json:"port"`
+
+	// redis
+	DBNum int64  `json:"db_num"`
+	Key   string `json:"key"`
+	// redis sentinel, name of the monitored master and host:port of sentinels
+	MasterName       string   `json:"master_name"`
+	SentinelAddrs    []string `json:"sentinel_addrs"`
+	SentinelPassword string   `json:"sentinel_password"`
+	// redis cluster, host:port of seed nodes
+	ClusterAddrs []string `json:"cluster_addrs"`
+	// redlock, host:port of independent redis nodes
+	Servers []string `json:"servers"`
+
+	// mysql
+	DBName          string `json:"db_name"`
+	TableName       string `json:"table_name"`
+	AutoCreateTable bool   `json:"auto_create_table"`
+
+	// Extend fields
+	// Extended fields can be used if there is a special implementation
+	Extend1 string `json:"extend_1"`
+	Extend2 string `json:"extend_2"`
+}
+
+// CheckWithDefault - check default value, if not set use default
+func (c *Config) CheckWithDefault() {
+	if c.TTL <= 0 {
+		c.TTL = defaultTTL
+	}
+	if c.RetryInterval <= 0 {
+		c.RetryInterval = defaultRetryInterval
+	}
+}
+
+// Lease is an acquired lock.
+type Lease struct {
+	// locked key
+	Key string
+	// random value identify the owner of the lease
+	Token string
+	// fencing token, increase every time the key is locked,
+	// pass it to the protected resource to reject stale owners
+	Fence int64
+
+	stop chan struct{}
+	lost chan struct{}
+	once sync.Once
+	mu   sync.Mutex
+	err  error
+}
+
+// Lost returns a channel closed when automatic refresh of the lease fails,
+// the channel is nil if the lease is not refreshed automatically.
+func (l *Lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Err returns the error of the last failed automatic refresh, nil if the
+// last refresh succeeded. Once Lost is closed it tells why the lease is lost.
+func (l *Lease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// setErr record the error of a refresh.
+func (l *Lease) setErr(err error) {
+	l.mu.Lock()
+	l.err = err
+	l.mu.Unlock()
+}
+
+// Locker interface contains all behaviors for lock adapter.
+type Locker interface {
+	// Lock block until the lock of key is acquired or ctx is done.
+	Lock(ctx context.Context, key string) (*Lease, error)
+	// TryLock acquire the lock of key, returns ErrLocked if it is held by someone else.
+	TryLock(ctx context.Context, key string) (*Lease, error)
+	// Refresh extend the lease by the configured ttl.
+	Refresh(ctx context.Context, lease *Lease) error
+	// Unlock release the lease.
+	Unlock(ctx context.Context, lease *Lease) error
+	// Locked check if the lock of key is held by anyone.
+	Locked(ctx context.Context, key string) (bool, error)
+
+	// close connection
+	Close() error
+	// start gc routine based on config settings.
+	StartAndGC(config Config) error
+}
+
+// Instance is a function create a new Locker Instance
+type Instance func() Locker
+
+var adapters = make(map[string]Instance)
+
+// Register makes a lock adapter available by the adapter name.
+// If Register is called twice with the same name or if driver is nil,
+// it panics.
+func Register(name string, adapter Instance) {
+	if adapter == nil {
+		panic("lock: Register adapter is nil")
+	}
+	if _, ok := adapters[name]; ok {
+		panic("lock: Register called twice for adapter " + name)
+	}
+	adapters[name] = adapter
+}
+
+// NewLocker Create a new lock driver by adapter name and config setting.
+// Leases are refreshed in background if config.AutoRefresh is set.
+func NewLocker(adapterName string, config Config) (adapter Locker, err error) {
+	instanceFunc, ok := adapters[adapterName]
+	if !ok {
+		err = fmt.Errorf("lock: unknown adapter name %q (forgot to import?)", adapterName)
+		return
+	}
+	config.CheckWithDefault()
+	adapter = instanceFunc()
+	if err = adapter.StartAndGC(config); err != nil {
+		return nil, err
+	}
+	if config.AutoRefresh {
+		adapter = &keepalive{Locker: adapter, ttl: config.TTL, interval: config.TTL / 3}
+	}
+	return
+}
+
+// Retry call try every interval until it acquires the lock, fails with
+// an error other than ErrLocked, or ctx is done. It helps adapters implement Lock.
+func Retry(ctx context.Context, interval time.Duration, try func() (*Lease, error)) (*Lease, error) {
+	for {
+		lease, err := try()
+		if err != ErrLocked {
+			return lease, err
+		}
+
+		// jitter avoid waiters retrying in lockstep
+		wait := interval/2 + time.Duration(rand.Int63n(int64(interval)))
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// keepalive refresh leases of the wrapped Locker in background.
+type keepalive struct {
+	Locker
+	ttl      time.Duration
+	interval time.Duration
+}
+
+// Lock acquire the lock and start refreshing it.
+func (k *keepalive) Lock(ctx context.Context, key string) (*Lease, error) {
+	lease, err := k.Locker.Lock(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	k.start(lease)
+	return lease, nil
+}
+
+// TryLock acquire the lock and start refreshing it.
+func (k *keepalive) TryLock(ctx context.Context, key string) (*Lease, error) {
+	lease, err := k.Locker.TryLock(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	k.start(lease)
+	return lease, nil
+}
+
+// Unlock stop refreshing and release the lease.
+func (k *keepalive) Unlock(ctx context.Context, lease *Lease) error {
+	if lease.stop != nil {
+		lease.once.Do(func() {
+			close(lease.stop)
+		})
+	}
+	return k.Locker.Unlock(ctx, lease)
+}
+
+// start refresh lease every interval until unlocked or the lease is lost.
+// Other errors than ErrNotHeld are retried, the lease is lost once
+// refreshes keep failing until its ttl elapsed.
+func (k *keepalive) start(lease *Lease) {
+	lease.stop = make(chan struct{})
+	lease.lost = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(k.interval)
+		defer ticker.Stop()
+		refreshed := time.Now()
+		for {
+			select {
+			case <-lease.stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), k.interval)
+				err := k.Locker.Refresh(ctx, lease)
+				cancel()
+				lease.setErr(err)
+				if err == nil {
+					refreshed = time.Now()
+					continue
+				}
+				if err == ErrNotHeld || time.Since(refreshed) >= k.ttl {
+					close(lease.lost)
+					return
+				}
+			}
+		}
+	}()
+}
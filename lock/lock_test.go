@@ -0,0 +1,97 @@
+package lock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/lock"
+	"github.com/dbunion/com/lock/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoRefresh(t *testing.T) {
+	locker, err := lock.NewLocker(lock.TypeMemory, lock.Config{TTL: 60 * time.Millisecond, AutoRefresh: true})
+	if err != nil {
+		t.Fatalf("create locker error, err:%v", err)
+	}
+
+	ctx := context.Background()
+	lease, err := locker.TryLock(ctx, "key")
+	assert.Nil(t, err)
+	assert.NotNil(t, lease.Lost())
+
+	// the lease outlive its ttl while refreshed
+	time.Sleep(200 * time.Millisecond)
+	locked, err := locker.Locked(ctx, "key")
+	assert.Nil(t, err)
+	assert.True(t, locked)
+
+	assert.Nil(t, locker.Unlock(ctx, lease))
+	time.Sleep(100 * time.Millisecond)
+	locked, err = locker.Locked(ctx, "key")
+	assert.Nil(t, err)
+	assert.False(t, locked)
+}
+
+func TestAutoRefreshLost(t *testing.T) {
+	locker, err := lock.NewLocker(lock.TypeMemory, lock.Config{TTL: 60 * time.Millisecond, AutoRefresh: true})
+	if err != nil {
+		t.Fatalf("create locker error, err:%v", err)
+	}
+
+	ctx := context.Background()
+	lease, err := locker.TryLock(ctx, "key")
+	assert.Nil(t, err)
+
+	// release behind the back of the refresher
+	assert.Nil(t, locker.Unlock(ctx, &lock.Lease{Key: lease.Key, Token: lease.Token}))
+	select {
+	case <-lease.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lease lost not notified")
+	}
+	assert.Equal(t, lock.ErrNotHeld, lease.Err())
+}
+
+var errBackend = errors.New("backend down")
+
+// brokenLocker fail every refresh with a backend error.
+type brokenLocker struct {
+	lock.Locker
+}
+
+func (b *brokenLocker) Refresh(ctx context.Context, lease *lock.Lease) error {
+	return errBackend
+}
+
+func init() {
+	lock.Register("broken", func() lock.Locker {
+		return &brokenLocker{Locker: memory.NewMemoryLocker()}
+	})
+}
+
+func TestAutoRefreshError(t *testing.T) {
+	locker, err := lock.NewLocker("broken", lock.Config{TTL: 60 * time.Millisecond, AutoRefresh: true})
+	if err != nil {
+		t.Fatalf("create locker error, err:%v", err)
+	}
+
+	ctx := context.Background()
+	lease, err := locker.TryLock(ctx, "key")
+	assert.Nil(t, err)
+
+	// failed refreshes are reported, the lease is lost once its ttl elapsed
+	select {
+	case <-lease.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lease lost not notified")
+	}
+	assert.Equal(t, errBackend, lease.Err())
+}
+
+func TestUnknownAdapter(t *testing.T) {
+	_, err := lock.NewLocker("unknown", lock.Config{})
+	assert.NotNil(t, err)
+}
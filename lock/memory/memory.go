@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dbunion/com/lock"
+	"github.com/google/uuid"
+)
+
+// entry - held lock
+type entry struct {
+	token    string
+	expireAt time.Time
+}
+
+// Locker is in-memory lock adapter, locks are only visible in process.
+type Locker struct {
+	mutex   sync.Mutex
+	entries map[string]*entry
+	fences  map[string]int64
+	config  lock.Config
+}
+
+// NewMemoryLocker create new in-memory locker.
+func NewMemoryLocker() lock.Locker {
+	return &Locker{
+		entries: make(map[string]*entry),
+		fences:  make(map[string]int64),
+	}
+}
+
+// held return the unexpired entry of key, mutex must be held.
+func (m *Locker) held(key string) *entry {
+	e, ok := m.entries[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(e.expireAt) {
+		delete(m.entries, key)
+		return nil
+	}
+	return e
+}
+
+// Lock block until the lock of key is acquired or ctx is done.
+func (m *Locker) Lock(ctx context.Context, key string) (*lock.Lease, error) {
+	return lock.Retry(ctx, m.config.RetryInterval, func() (*lock.Lease, error) {
+		return m.TryLock(ctx, key)
+	})
+}
+
+// TryLock acquire the lock of key.
+func (m *Locker) TryLock(ctx context.Context, key string) (*lock.Lease, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.held(key) != nil {
+		return nil, lock.ErrLocked
+	}
+
+	token := uuid.New().String()
+	m.entries[key] = &entry{token: token, expireAt: time.Now().Add(m.config.TTL)}
+	m.fences[key]++
+	return &lock.Lease{Key: key, Token: token, Fence: m.fences[key]}, nil
+}
+
+// Refresh extend the lease by ttl.
+func (m *Locker) Refresh(ctx context.Context, lease *lock.Lease) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	e := m.held(lease.Key)
+	if e == nil || e.token != lease.Token {
+		return lock.ErrNotHeld
+	}
+	e.expireAt = time.Now().Add(m.config.TTL)
+	return nil
+}
+
+// Unlock release the lease.
+func (m *Locker) Unlock(ctx context.Context, lease *lock.Lease) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	e := m.held(lease.Key)
+	if e == nil || e.token != lease.Token {
+		return lock.ErrNotHeld
+	}
+	delete(m.entries, lease.Key)
+	return nil
+}
+
+// Locked check if the lock of key is held.
+func (m *Locker) Locked(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.held(key) != nil, nil
+}
+
+// Close do nothing.
+func (m *Locker) Close() error {
+	return nil
+}
+
+// StartAndGC start in-memory locker.
+func (m *Locker) StartAndGC(config lock.Config) error {
+	config.CheckWithDefault()
+	m.config = config
+	return nil
+}
+
+func init() {
+	lock.Register(lock.TypeMemory, NewMemoryLocker)
+}
@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/lock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLocker(t *testing.T) {
+	locker, err := lock.NewLocker(lock.TypeMemory, lock.Config{TTL: 100 * time.Millisecond, RetryInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("create locker error, err:%v", err)
+	}
+	defer locker.Close()
+
+	ctx := context.Background()
+	lease, err := locker.TryLock(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), lease.Fence)
+
+	_, err = locker.TryLock(ctx, "key")
+	assert.Equal(t, lock.ErrLocked, err)
+
+	locked, err := locker.Locked(ctx, "key")
+	assert.Nil(t, err)
+	assert.True(t, locked)
+
+	assert.Nil(t, locker.Refresh(ctx, lease))
+	assert.Nil(t, locker.Unlock(ctx, lease))
+	assert.Equal(t, lock.ErrNotHeld, locker.Unlock(ctx, lease))
+
+	// fencing token increase on each acquisition
+	lease, err = locker.Lock(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), lease.Fence)
+
+	// Lock wait for expiration of the previous lease
+	next, err := locker.Lock(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), next.Fence)
+	assert.Equal(t, lock.ErrNotHeld, locker.Refresh(ctx, lease))
+
+	// Lock give up when ctx is done
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.Nil(t, locker.Refresh(ctx, next))
+	_, err = locker.Lock(timeout, "key")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -0,0 +1,225 @@
+package mysql
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dbunion/com/lock"
+	"github.com/google/uuid"
+)
+
+const (
+	// mysql limit lock names to 64 characters
+	maxLockName = 64
+)
+
+/**
+CREATE TABLE `lock_fence` (
+  `name` varchar(64) NOT NULL,
+  `fence` bigint(20) NOT NULL,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='lock fencing token';
+**/
+
+var fenceTemplate = `
+CREATE TABLE IF NOT EXISTS %s.%s (
+  name varchar(64) NOT NULL,
+  fence bigint(20) NOT NULL,
+  PRIMARY KEY (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='lock fencing token';
+`
+
+// Locker is mysql GET_LOCK adapter. A lock is bound to the connection
+// which acquired it, so each lease keep a dedicated connection until unlock.
+// Leases never expire while the connection is alive, Refresh only verify
+// the lock is still held.
+type Locker struct {
+	config lock.Config
+	db     *sql.DB
+	mutex  sync.Mutex
+	conns  map[string]*sql.Conn
+}
+
+// NewMySQLLocker create new mysql locker.
+func NewMySQLLocker() lock.Locker {
+	return &Locker{conns: make(map[string]*sql.Conn)}
+}
+
+// name return the mysql lock name of key.
+func (m *Locker) name(key string) string {
+	name := key
+	if m.config.Key != "" {
+		name = fmt.Sprintf("%s:%s", m.config.Key, key)
+	}
+	if len(name) > maxLockName {
+		sum := sha1.Sum([]byte(name))
+		name = hex.EncodeToString(sum[:])
+	}
+	return name
+}
+
+// Lock block until the lock of key is acquired or ctx is done.
+func (m *Locker) Lock(ctx context.Context, key string) (*lock.Lease, error) {
+	return lock.Retry(ctx, m.config.RetryInterval, func() (*lock.Lease, error) {
+		return m.TryLock(ctx, key)
+	})
+}
+
+// TryLock acquire the lock of key.
+func (m *Locker) TryLock(ctx context.Context, key string) (*lock.Lease, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", m.name(key)).Scan(&locked); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		_ = conn.Close()
+		return nil, lock.ErrLocked
+	}
+
+	lease := &lock.Lease{Key: key, Token: uuid.New().String()}
+	if m.config.TableName != "" {
+		if lease.Fence, err = m.fence(ctx, conn, key); err != nil {
+			_, _ = conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", m.name(key))
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	m.mutex.Lock()
+	m.conns[lease.Token] = conn
+	m.mutex.Unlock()
+	return lease, nil
+}
+
+// fence increase and return the fencing token of key.
+func (m *Locker) fence(ctx context.Context, conn *sql.Conn, key string) (int64, error) {
+	query := fmt.Sprintf("INSERT INTO %s (name, fence) VALUES (?, LAST_INSERT_ID(1)) "+
+		"ON DUPLICATE KEY UPDATE fence = LAST_INSERT_ID(fence + 1)", m.config.TableName)
+	result, err := conn.ExecContext(ctx, query, m.name(key))
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// conn return the connection holding lease.
+func (m *Locker) conn(lease *lock.Lease) (*sql.Conn, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	conn, ok := m.conns[lease.Token]
+	if !ok {
+		return nil, lock.ErrNotHeld
+	}
+	return conn, nil
+}
+
+// Refresh verify the lease is still held by its connection.
+func (m *Locker) Refresh(ctx context.Context, lease *lock.Lease) error {
+	conn, err := m.conn(lease)
+	if err != nil {
+		return err
+	}
+
+	var held sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?) = CONNECTION_ID()", m.name(lease.Key)).Scan(&held); err != nil {
+		return err
+	}
+	if !held.Valid || held.Int64 != 1 {
+		return lock.ErrNotHeld
+	}
+	return nil
+}
+
+// Unlock release the lease and its connection.
+func (m *Locker) Unlock(ctx context.Context, lease *lock.Lease) error {
+	conn, err := m.conn(lease)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	delete(m.conns, lease.Token)
+	m.mutex.Unlock()
+
+	var released sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", m.name(lease.Key)).Scan(&released)
+	if cErr := conn.Close(); cErr != nil && err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return err
+	}
+	if !released.Valid || released.Int64 != 1 {
+		return lock.ErrNotHeld
+	}
+	return nil
+}
+
+// Locked check if the lock of key is held.
+func (m *Locker) Locked(ctx context.Context, key string) (bool, error) {
+	var free sql.NullInt64
+	if err := m.db.QueryRowContext(ctx, "SELECT IS_FREE_LOCK(?)", m.name(key)).Scan(&free); err != nil {
+		return false, err
+	}
+	return free.Valid && free.Int64 == 0, nil
+}
+
+// Close release held leases and close connection.
+func (m *Locker) Close() error {
+	m.mutex.Lock()
+	for token, conn := range m.conns {
+		_ = conn.Close()
+		delete(m.conns, token)
+	}
+	m.mutex.Unlock()
+	return m.db.Close()
+}
+
+// StartAndGC start mysql locker.
+// the fencing table is only used if TableName is set.
+func (m *Locker) StartAndGC(config lock.Config) error {
+	config.CheckWithDefault()
+	m.config = config
+	if config.Server == "" {
+		return errors.New("server config is empty")
+	}
+
+	db, err := sql.Open("mysql",
+		fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?&timeout=30s", config.User, config.Password, config.Server, config.Port, config.DBName))
+	if err != nil {
+		return err
+	}
+	db.SetConnMaxLifetime(time.Hour * 8)
+
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return err
+	}
+
+	if config.TableName != "" && config.AutoCreateTable {
+		sql := fmt.Sprintf(fenceTemplate, config.DBName, config.TableName)
+		if _, err := db.Exec(sql); err != nil {
+			_ = db.Close()
+			return fmt.Errorf("prepare init table err:%v sql:%v", err, sql)
+		}
+	}
+
+	m.db = db
+	return nil
+}
+
+func init() {
+	lock.Register(lock.TypeMySQL, NewMySQLLocker)
+}
@@ -0,0 +1,48 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dbunion/com/lock"
+	// import mysql driver
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMySQLLocker(t *testing.T) {
+	locker, err := lock.NewLocker(lock.TypeMySQL, lock.Config{
+		Server:          "127.0.0.1",
+		Port:            3306,
+		User:            "test",
+		Password:        "123456",
+		DBName:          "test",
+		TableName:       "lock_fence",
+		AutoCreateTable: true,
+	})
+	if err != nil {
+		t.Fatalf("start err:%v", err)
+	}
+	defer locker.Close()
+
+	ctx := context.Background()
+	lease, err := locker.TryLock(ctx, "key")
+	assert.Nil(t, err)
+	assert.True(t, lease.Fence > 0)
+
+	_, err = locker.TryLock(ctx, "key")
+	assert.Equal(t, lock.ErrLocked, err)
+
+	locked, err := locker.Locked(ctx, "key")
+	assert.Nil(t, err)
+	assert.True(t, locked)
+
+	assert.Nil(t, locker.Refresh(ctx, lease))
+	assert.Nil(t, locker.Unlock(ctx, lease))
+	assert.Equal(t, lock.ErrNotHeld, locker.Unlock(ctx, lease))
+
+	next, err := locker.Lock(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, lease.Fence+1, next.Fence)
+	assert.Nil(t, locker.Unlock(ctx, next))
+}
@@ -0,0 +1,315 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dbunion/com/lock"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+var (
+	defaultKey = "lock"
+	// clock drift factor of redlock validity
+	driftFactor = 0.01
+)
+
+// acquire the lock and return the highest fence the node has seen, -1 if locked.
+var lockScript = redis.NewScript(`
+if redis.call('set', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+   return tonumber(redis.call('get', KEYS[2]) or '0')
+end
+return -1`)
+
+// record the fence of the lease if it is still owned and above any fence the node has seen.
+var fenceScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] and tonumber(redis.call('get', KEYS[2]) or '0') < tonumber(ARGV[2]) then
+   redis.call('set', KEYS[2], ARGV[2])
+   return 1
+end
+return 0`)
+
+// extend the lock if it is still owned.
+var refreshScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+   return redis.call('pexpire', KEYS[1], ARGV[2])
+end
+return 0`)
+
+// release the lock if it is still owned.
+var unlockScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+   return redis.call('del', KEYS[1])
+end
+return 0`)
+
+// Locker is redis lock adapter. With a single server, a sentinel
+// monitored master or a cluster it is a plain SET NX lock, with several
+// independent nodes it runs redlock and a lease is held once a majority
+// of the nodes granted it.
+type Locker struct {
+	clients []redis.UniversalClient
+	quorum  int
+	key     string
+	config  lock.Config
+	redlock bool
+}
+
+// NewRedisLocker create new single node redis locker.
+func NewRedisLocker() lock.Locker {
+	return &Locker{key: defaultKey}
+}
+
+// NewRedlockLocker create new redlock locker.
+func NewRedlockLocker() lock.Locker {
+	return &Locker{key: defaultKey, redlock: true}
+}
+
+// associate with config key, the key is a hash tag so the lock
+// and its fence are kept in the same slot of a cluster.
+func (r *Locker) associate(key string) string {
+	return fmt.Sprintf("%s:{%s}", r.key, key)
+}
+
+// each run fn on every node concurrently and return the number of successes.
+func (r *Locker) each(ctx context.Context, fn func(c redis.UniversalClient) (bool, error)) (int, error) {
+	return r.on(ctx, r.clients, fn)
+}
+
+// on run fn on the nodes of clients concurrently and return the number of successes.
+func (r *Locker) on(ctx context.Context, clients []redis.UniversalClient, fn func(c redis.UniversalClient) (bool, error)) (int, error) {
+	type result struct {
+		ok  bool
+		err error
+	}
+	results := make(chan result, len(clients))
+	for _, c := range clients {
+		go func(c redis.UniversalClient) {
+			ok, err := fn(c)
+			results <- result{ok: ok, err: err}
+		}(c)
+	}
+
+	var n int
+	var err error
+	for range clients {
+		res := <-results
+		if res.ok {
+			n++
+		} else if res.err != nil {
+			err = res.err
+		}
+	}
+	return n, err
+}
+
+// Lock block until the lock of key is acquired or ctx is done.
+func (r *Locker) Lock(ctx context.Context, key string) (*lock.Lease, error) {
+	return lock.Retry(ctx, r.config.RetryInterval, func() (*lock.Lease, error) {
+		return r.TryLock(ctx, key)
+	})
+}
+
+// TryLock acquire the lock of key on a majority of nodes.
+// The fencing token is above the highest fence seen by the granting nodes
+// and is recorded on a majority of them, since any two majorities share a
+// node, it is above the fence of every lease granted before.
+func (r *Locker) TryLock(ctx context.Context, key string) (*lock.Lease, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token := uuid.New().String()
+	ttl := int64(r.config.TTL / time.Millisecond)
+	lockKey, fenceKey := r.associate(key), r.associate(key)+":fence"
+	start := time.Now()
+
+	var (
+		mu      sync.Mutex
+		granted []redis.UniversalClient
+		seen    int64
+		denied  int32
+	)
+	n, err := r.on(ctx, r.clients, func(c redis.UniversalClient) (bool, error) {
+		fence, err := lockScript.Run(ctx, c, []string{lockKey, fenceKey}, token, ttl).Int64()
+		if err != nil {
+			return false, err
+		}
+		if fence < 0 {
+			atomic.AddInt32(&denied, 1)
+			return false, nil
+		}
+		mu.Lock()
+		granted = append(granted, c)
+		if fence > seen {
+			seen = fence
+		}
+		mu.Unlock()
+		return true, nil
+	})
+
+	lease := &lock.Lease{Key: key, Token: token, Fence: seen + 1}
+	if n >= r.quorum {
+		n, err = r.on(ctx, granted, func(c redis.UniversalClient) (bool, error) {
+			return fenceScript.Run(ctx, c, []string{lockKey, fenceKey}, token, lease.Fence).Bool()
+		})
+	}
+
+	// the lease is valid if granted by a majority before it expires
+	drift := time.Duration(float64(r.config.TTL)*driftFactor) + 2*time.Millisecond
+	if n >= r.quorum && time.Since(start)+drift < r.config.TTL {
+		return lease, nil
+	}
+
+	// release partially acquired lock
+	if len(granted) > 0 {
+		_, _ = r.each(context.Background(), func(c redis.UniversalClient) (bool, error) {
+			return unlockScript.Run(context.Background(), c, []string{lockKey}, token).Bool()
+		})
+	}
+	// report backend failure only if no node answered
+	if len(granted) == 0 && atomic.LoadInt32(&denied) == 0 && err != nil {
+		return nil, err
+	}
+	return nil, lock.ErrLocked
+}
+
+// Refresh extend the lease by ttl on a majority of nodes.
+func (r *Locker) Refresh(ctx context.Context, lease *lock.Lease) error {
+	ttl := int64(r.config.TTL / time.Millisecond)
+	n, err := r.each(ctx, func(c redis.UniversalClient) (bool, error) {
+		return refreshScript.Run(ctx, c, []string{r.associate(lease.Key)}, lease.Token, ttl).Bool()
+	})
+	if n >= r.quorum {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return lock.ErrNotHeld
+}
+
+// Unlock release the lease on every node.
+func (r *Locker) Unlock(ctx context.Context, lease *lock.Lease) error {
+	n, err := r.each(ctx, func(c redis.UniversalClient) (bool, error) {
+		return unlockScript.Run(ctx, c, []string{r.associate(lease.Key)}, lease.Token).Bool()
+	})
+	if n > 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return lock.ErrNotHeld
+}
+
+// Locked check if the lock of key is held on a majority of nodes.
+func (r *Locker) Locked(ctx context.Context, key string) (bool, error) {
+	n, err := r.each(ctx, func(c redis.UniversalClient) (bool, error) {
+		n, err := c.Exists(ctx, r.associate(key)).Result()
+		return n > 0, err
+	})
+	if n >= r.quorum {
+		return true, nil
+	}
+	return false, err
+}
+
+// Close close connections to redis.
+func (r *Locker) Close() error {
+	var err error
+	for _, c := range r.clients {
+		if cErr := c.Close(); cErr != nil {
+			err = cErr
+		}
+	}
+	return err
+}
+
+// StartAndGC start redis locker.
+// redlock use Servers, otherwise the topology is sentinel if MasterName is set,
+// cluster if ClusterAddrs is set, or a single server of Server and Port.
+func (r *Locker) StartAndGC(config lock.Config) error {
+	config.CheckWithDefault()
+	r.config = config
+	if config.Key != "" {
+		r.key = config.Key
+	}
+
+	if r.redlock {
+		if len(config.Servers) == 0 {
+			return errors.New("servers config is empty")
+		}
+		for _, addr := range config.Servers {
+			r.clients = append(r.clients, redis.NewClient(&redis.Options{
+				Addr:        addr,
+				Password:    config.Password,
+				DB:          int(config.DBNum),
+				IdleTimeout: 180 * time.Second,
+			}))
+		}
+	} else {
+		client, err := newClient(config)
+		if err != nil {
+			return err
+		}
+		r.clients = []redis.UniversalClient{client}
+	}
+	r.quorum = len(r.clients)/2 + 1
+
+	// redlock tolerate a minority of unavailable nodes
+	n, err := r.each(context.Background(), func(c redis.UniversalClient) (bool, error) {
+		err := c.Ping(context.Background()).Err()
+		return err == nil, err
+	})
+	if n < r.quorum {
+		_ = r.Close()
+		return err
+	}
+	return nil
+}
+
+// newClient create redis client of the topology described by config.
+func newClient(config lock.Config) (redis.UniversalClient, error) {
+	switch {
+	case config.MasterName != "":
+		if len(config.SentinelAddrs) == 0 {
+			return nil, errors.New("sentinel addrs config is empty")
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       config.MasterName,
+			SentinelAddrs:    config.SentinelAddrs,
+			SentinelPassword: config.SentinelPassword,
+			Password:         config.Password,
+			DB:               int(config.DBNum),
+			IdleTimeout:      180 * time.Second,
+		}), nil
+	case len(config.ClusterAddrs) > 0:
+		if config.DBNum != 0 {
+			return nil, errors.New("redis cluster only support db 0")
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:       config.ClusterAddrs,
+			Password:    config.Password,
+			IdleTimeout: 180 * time.Second,
+		}), nil
+	case config.Server != "":
+		return redis.NewClient(&redis.Options{
+			Addr:        fmt.Sprintf("%s:%d", config.Server, config.Port),
+			Password:    config.Password,
+			DB:          int(config.DBNum),
+			IdleTimeout: 180 * time.Second,
+		}), nil
+	}
+	return nil, errors.New("server config is empty")
+}
+
+func init() {
+	lock.Register(lock.TypeRedis, NewRedisLocker)
+	lock.Register(lock.TypeRedlock, NewRedlockLocker)
+}
@@ -0,0 +1,192 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/dbunion/com/lock"
+	"github.com/stretchr/testify/assert"
+)
+
+func startMiniRedis(t *testing.T) *miniredis.Miniredis {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	return mr
+}
+
+func TestRedisLocker(t *testing.T) {
+	mr := startMiniRedis(t)
+	defer mr.Close()
+
+	locker, err := lock.NewLocker(lock.TypeRedis, lock.Config{
+		Server:        mr.Host(),
+		Port:          int64(mr.Server().Addr().Port),
+		TTL:           10 * time.Second,
+		RetryInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("create locker error, err:%v", err)
+	}
+	defer locker.Close()
+
+	ctx := context.Background()
+	lease, err := locker.TryLock(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), lease.Fence)
+
+	_, err = locker.TryLock(ctx, "key")
+	assert.Equal(t, lock.ErrLocked, err)
+
+	locked, err := locker.Locked(ctx, "key")
+	assert.Nil(t, err)
+	assert.True(t, locked)
+
+	assert.Nil(t, locker.Refresh(ctx, lease))
+	assert.Nil(t, locker.Unlock(ctx, lease))
+	assert.Equal(t, lock.ErrNotHeld, locker.Unlock(ctx, lease))
+
+	lease, err = locker.Lock(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), lease.Fence)
+
+	// the lease expire on the server
+	mr.FastForward(11 * time.Second)
+	assert.Equal(t, lock.ErrNotHeld, locker.Refresh(ctx, lease))
+
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = locker.Lock(ctx, "key")
+	assert.Nil(t, err)
+	_, err = locker.Lock(timeout, "key")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestRedisClusterLocker(t *testing.T) {
+	mr := startMiniRedis(t)
+	defer mr.Close()
+	// a cluster of a single node serving every slot
+	mr.Server().SetPreHook(func(c *server.Peer, cmd string, args ...string) bool {
+		if cmd != "CLUSTER" {
+			return false
+		}
+		c.WriteLen(1)
+		c.WriteLen(3)
+		c.WriteInt(0)
+		c.WriteInt(16383)
+		c.WriteLen(3)
+		c.WriteBulk(mr.Host())
+		c.WriteInt(mr.Server().Addr().Port)
+		c.WriteBulk("node0")
+		return true
+	})
+
+	locker, err := lock.NewLocker(lock.TypeRedis, lock.Config{ClusterAddrs: []string{mr.Addr()}, TTL: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("create locker error, err:%v", err)
+	}
+	defer locker.Close()
+
+	ctx := context.Background()
+	lease, err := locker.TryLock(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), lease.Fence)
+	// the lock and its fence share the hash tag of the key
+	assert.Equal(t, []string{"lock:{key}", "lock:{key}:fence"}, mr.Keys())
+
+	_, err = locker.TryLock(ctx, "key")
+	assert.Equal(t, lock.ErrLocked, err)
+	assert.Nil(t, locker.Unlock(ctx, lease))
+
+	_, err = lock.NewLocker(lock.TypeRedis, lock.Config{ClusterAddrs: []string{mr.Addr()}, DBNum: 1})
+	assert.NotNil(t, err)
+	_, err = lock.NewLocker(lock.TypeRedis, lock.Config{MasterName: "master"})
+	assert.NotNil(t, err)
+}
+
+func TestRedlockLocker(t *testing.T) {
+	var servers []string
+	var nodes []*miniredis.Miniredis
+	for i := 0; i < 3; i++ {
+		mr := startMiniRedis(t)
+		defer mr.Close()
+		nodes = append(nodes, mr)
+		servers = append(servers, mr.Addr())
+	}
+
+	locker, err := lock.NewLocker(lock.TypeRedlock, lock.Config{Servers: servers, TTL: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("create locker error, err:%v", err)
+	}
+	defer locker.Close()
+
+	ctx := context.Background()
+	lease, err := locker.TryLock(ctx, "key")
+	assert.Nil(t, err)
+	assert.Nil(t, locker.Unlock(ctx, lease))
+
+	// a minority of failed nodes is tolerated
+	nodes[0].Close()
+	lease, err = locker.TryLock(ctx, "key")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), lease.Fence)
+	assert.Nil(t, locker.Refresh(ctx, lease))
+
+	_, err = locker.TryLock(ctx, "key")
+	assert.Equal(t, lock.ErrLocked, err)
+
+	// a lock held on a single node is not a quorum
+	assert.Nil(t, locker.Unlock(ctx, lease))
+	assert.Nil(t, nodes[1].Set(fmt.Sprintf("%s:{%s}", defaultKey, "key"), "other"))
+	_, err = locker.TryLock(ctx, "key")
+	assert.Equal(t, lock.ErrLocked, err)
+	assert.False(t, nodes[2].Exists(fmt.Sprintf("%s:{%s}", defaultKey, "key")))
+}
+
+func TestRedlockFenceRotateMajority(t *testing.T) {
+	var servers []string
+	var nodes []*miniredis.Miniredis
+	for i := 0; i < 3; i++ {
+		mr := startMiniRedis(t)
+		defer mr.Close()
+		nodes = append(nodes, mr)
+		servers = append(servers, mr.Addr())
+	}
+
+	locker, err := lock.NewLocker(lock.TypeRedlock, lock.Config{
+		Servers:       servers,
+		TTL:           10 * time.Second,
+		RetryInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("create locker error, err:%v", err)
+	}
+	defer locker.Close()
+
+	// every lease is granted by a different majority, the fences still increase
+	var fence int64
+	for i := 0; i < 6; i++ {
+		down := nodes[i%len(nodes)]
+		down.Close()
+
+		// the client back off dialing a failed node for up to a second after it restarts
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		lease, err := locker.Lock(ctx, "key")
+		cancel()
+		if err != nil {
+			t.Fatalf("lock error, err:%v", err)
+		}
+		assert.Greater(t, lease.Fence, fence)
+		fence = lease.Fence
+		assert.Nil(t, locker.Unlock(context.Background(), lease))
+
+		if err := down.Restart(); err != nil {
+			t.Fatalf("restart miniredis error, err:%v", err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package log
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// keys of the fields of a request logger
+const (
+	FieldRequestID = "request_id"
+	FieldTraceID   = "trace_id"
+	FieldSpanID    = "span_id"
+)
+
+type contextKey struct{}
+
+type holder struct {
+	logger Logger
+}
+
+var defaultLogger atomic.Value
+
+// SetDefault set the logger of FromContext for a context without logger.
+func SetDefault(l Logger) {
+	defaultLogger.Store(holder{logger: l})
+}
+
+// Default return the logger set by SetDefault, a logger which discard
+// all lines if not set.
+func Default() Logger {
+	if h, ok := defaultLogger.Load().(holder); ok && h.logger != nil {
+		return h.logger
+	}
+	return Nop()
+}
+
+// WithContext return a copy of ctx which carry l.
+func WithContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext return the logger carried by ctx, Default if it carry none.
+func FromContext(ctx context.Context) Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(Logger); ok && l != nil {
+			return l
+		}
+	}
+	return Default()
+}
@@ -0,0 +1,91 @@
+package log
+
+import (
+	"fmt"
+	"time"
+)
+
+// FieldLogger - the key of the component name of a Named logger
+const FieldLogger = "logger"
+
+// BadKey - the key of a value without a key in key/value pairs
+const BadKey = "!BADKEY"
+
+// Field - a key/value of a structured log
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Any - field of any value
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// String - string field
+func String(key string, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int - int field
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int64 - int64 field
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Bool - bool field
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Duration - duration field
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err - error field with key error
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// Fields convert key/value pairs to fields, a Field in kv is used as is,
+// a key which is not a string is formatted and a value without a key is
+// put under BadKey.
+func Fields(kv ...interface{}) []Field {
+	fields := make([]Field, 0, len(kv)/2+1)
+	for i := 0; i < len(kv); i++ {
+		switch key := kv[i].(type) {
+		case Field:
+			fields = append(fields, key)
+		case []Field:
+			fields = append(fields, key...)
+		default:
+			if i == len(kv)-1 {
+				fields = append(fields, Field{Key: BadKey, Value: key})
+				break
+			}
+			name, ok := key.(string)
+			if !ok {
+				name = fmt.Sprint(key)
+			}
+			fields = append(fields, Field{Key: name, Value: kv[i+1]})
+			i++
+		}
+	}
+	return fields
+}
+
+// JoinName join the name of a Named logger to the name of its parent.
+func JoinName(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	if name == "" {
+		return parent
+	}
+	return parent + "." + name
+}
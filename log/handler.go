@@ -0,0 +1,92 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandler expose a Logger as a slog.Handler, e.g. for a library which
+// log by slog. Records are logged by the KV methods of the level, attrs
+// become fields and the keys in groups are joined by dot.
+type SlogHandler struct {
+	logger Logger
+	level  slog.Leveler
+	prefix string
+}
+
+// NewSlogHandler create a handler which log to l, records below level are
+// dropped, all records are passed to l if level is nil.
+func NewSlogHandler(l Logger, level slog.Leveler) *SlogHandler {
+	return &SlogHandler{logger: l, level: level}
+}
+
+// Enabled check level is enabled.
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.level == nil || level >= h.level.Level()
+}
+
+// Handle log r by the KV method of its level.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	kv := make([]interface{}, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		for _, f := range h.fields(nil, h.prefix, a) {
+			kv = append(kv, f)
+		}
+		return true
+	})
+
+	switch {
+	case r.Level < slog.LevelInfo:
+		h.logger.DebugKV(r.Message, kv...)
+	case r.Level < slog.LevelWarn:
+		h.logger.InfoKV(r.Message, kv...)
+	case r.Level < slog.LevelError:
+		h.logger.WarnKV(r.Message, kv...)
+	default:
+		h.logger.ErrorKV(r.Message, kv...)
+	}
+	return nil
+}
+
+// WithAttrs return a handler whose logger log attrs with every line.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []Field
+	for _, a := range attrs {
+		fields = h.fields(fields, h.prefix, a)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+	child := *h
+	child.logger = h.logger.With(fields...)
+	return &child
+}
+
+// WithGroup return a handler which put the keys of later attrs under name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	child := *h
+	child.prefix = h.prefix + name + "."
+	return &child
+}
+
+// fields append a as fields to fields, a group is flattened and empty
+// attrs are ignored.
+func (h *SlogHandler) fields(fields []Field, prefix string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return append(fields, Field{Key: prefix + a.Key, Value: a.Value.Any()})
+	}
+	if a.Key != "" {
+		prefix += a.Key + "."
+	}
+	for _, ga := range a.Value.Group() {
+		fields = h.fields(fields, prefix, ga)
+	}
+	return fields
+}
@@ -26,6 +26,10 @@ const (
 	TypeZsskyLog = "zssky"
 	// TypeLogrus - use logrus
 	TypeLogrus = "logrus"
+	// TypeSlog - use log/slog of the standard library
+	TypeSlog = "slog"
+	// TypeZap - use uber zap
+	TypeZap = "zap"
 )
 
 // Config - log base config
@@ -131,6 +135,24 @@ type Logger interface {
 	// Panicln - panic
 	Panicln(v ...interface{})
 
+	// With - child logger which log fields with every line
+	With(fields ...Field) Logger
+
+	// Named - child logger of a component, names of nested loggers are joined by dot
+	Named(name string) Logger
+
+	// DebugKV - debug log with key/value pairs, see Fields
+	DebugKV(msg string, kv ...interface{})
+
+	// InfoKV - info log with key/value pairs, see Fields
+	InfoKV(msg string, kv ...interface{})
+
+	// WarnKV - warn log with key/value pairs, see Fields
+	WarnKV(msg string, kv ...interface{})
+
+	// ErrorKV - error log with key/value pairs, see Fields
+	ErrorKV(msg string, kv ...interface{})
+
 	// close connection
 	Close() error
 
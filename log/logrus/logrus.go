@@ -1,21 +1,24 @@
 package logrus
 
 import (
-	"bytes"
 	"fmt"
 	"github.com/dbunion/com/log"
 	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
 	"github.com/sirupsen/logrus"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 // Log is log adapter.
 type Log struct {
 	config    log.Config
 	logger    *logrus.Logger
+	entry     *logrus.Entry
 	outWriter io.Writer
+	name      string
 }
 
 // NewLogrus create new logrus log with default collection name.
@@ -25,97 +28,140 @@ func NewLogrus() log.Logger {
 
 // Infof - info format log
 func (l *Log) Infof(format string, v ...interface{}) {
-	l.logger.Infof(format, v...)
+	l.entry.Infof(format, v...)
 }
 
 // Info - info format log
 func (l *Log) Info(v ...interface{}) {
-	l.logger.Info(v...)
+	l.entry.Info(v...)
 }
 
 // Debugf - debug format log
 func (l *Log) Debugf(format string, v ...interface{}) {
-	l.logger.Debugf(format, v...)
+	l.entry.Debugf(format, v...)
 }
 
 // Debug - debug log
 func (l *Log) Debug(v ...interface{}) {
-	l.logger.Debug(v...)
+	l.entry.Debug(v...)
 }
 
 // Warnf - warn format log
 func (l *Log) Warnf(format string, v ...interface{}) {
-	l.logger.Warnf(format, v...)
+	l.entry.Warnf(format, v...)
 }
 
 // Warn - warn log
 func (l *Log) Warn(v ...interface{}) {
-	l.logger.Warn(v...)
+	l.entry.Warn(v...)
 }
 
 // Warningf - Warning format log
 func (l *Log) Warningf(format string, v ...interface{}) {
-	l.logger.Warningf(format, v...)
+	l.entry.Warningf(format, v...)
 }
 
 // Warning - Warning log
 func (l *Log) Warning(v ...interface{}) {
-	l.logger.Warning(v...)
+	l.entry.Warning(v...)
 }
 
 // Errorf - error format log
 func (l *Log) Errorf(format string, v ...interface{}) {
-	l.logger.Errorf(format, v...)
+	l.entry.Errorf(format, v...)
 }
 
 // Error - error log
 func (l *Log) Error(v ...interface{}) {
-	l.logger.Error(v...)
+	l.entry.Error(v...)
 }
 
 // Fatalf - fatal format log
 func (l *Log) Fatalf(format string, v ...interface{}) {
-	l.logger.Fatalf(format, v...)
+	l.entry.Fatalf(format, v...)
 }
 
 // Fatal - fatal format log
 func (l *Log) Fatal(v ...interface{}) {
-	l.logger.Fatal(v...)
+	l.entry.Fatal(v...)
 }
 
 // Fatalln - fatal log
 func (l *Log) Fatalln(v ...interface{}) {
-	l.logger.Fatalln(v...)
+	l.entry.Fatalln(v...)
 }
 
 // Printf - print format log
 func (l *Log) Printf(format string, v ...interface{}) {
-	l.logger.Printf(format, v...)
+	l.entry.Printf(format, v...)
 }
 
 // Print - print log
 func (l *Log) Print(v ...interface{}) {
-	l.logger.Print(v...)
+	l.entry.Print(v...)
 }
 
 // Println - print log
 func (l *Log) Println(v ...interface{}) {
-	l.logger.Println(v...)
+	l.entry.Println(v...)
 }
 
 // Panic - panic
 func (l *Log) Panic(v ...interface{}) {
-	l.logger.Panic(v...)
+	l.entry.Panic(v...)
 }
 
 // Panicf - panic format value
 func (l *Log) Panicf(format string, v ...interface{}) {
-	l.logger.Panicf(format, v...)
+	l.entry.Panicf(format, v...)
 }
 
 // Panicln - panic
 func (l *Log) Panicln(v ...interface{}) {
-	l.logger.Panicln(v...)
+	l.entry.Panicln(v...)
+}
+
+// With - child logger which log fields with every line
+func (l *Log) With(fields ...log.Field) log.Logger {
+	child := *l
+	child.entry = l.entry.WithFields(toFields(fields))
+	return &child
+}
+
+// Named - child logger of a component
+func (l *Log) Named(name string) log.Logger {
+	child := *l
+	child.name = log.JoinName(l.name, name)
+	child.entry = l.entry.WithField(log.FieldLogger, child.name)
+	return &child
+}
+
+// DebugKV - debug log with key/value pairs
+func (l *Log) DebugKV(msg string, kv ...interface{}) {
+	l.entry.WithFields(toFields(log.Fields(kv...))).Debug(msg)
+}
+
+// InfoKV - info log with key/value pairs
+func (l *Log) InfoKV(msg string, kv ...interface{}) {
+	l.entry.WithFields(toFields(log.Fields(kv...))).Info(msg)
+}
+
+// WarnKV - warn log with key/value pairs
+func (l *Log) WarnKV(msg string, kv ...interface{}) {
+	l.entry.WithFields(toFields(log.Fields(kv...))).Warn(msg)
+}
+
+// ErrorKV - error log with key/value pairs
+func (l *Log) ErrorKV(msg string, kv ...interface{}) {
+	l.entry.WithFields(toFields(log.Fields(kv...))).Error(msg)
+}
+
+func toFields(fields []log.Field) logrus.Fields {
+	data := make(logrus.Fields, len(fields))
+	for _, f := range fields {
+		data[f.Key] = f.Value
+	}
+	return data
 }
 
 // Close connection
@@ -129,6 +175,7 @@ func (l *Log) StartAndGC(config log.Config) error {
 
 	l.config = config
 	l.logger = logrus.New()
+	l.entry = logrus.NewEntry(l.logger)
 
 	l.logger.SetReportCaller(true)
 	l.logger.SetLevel(l.getLogLevel())
@@ -163,43 +210,54 @@ func (l *Log) StartAndGC(config log.Config) error {
 		TimestampFormat:           "2006-01-02 15:04:05.99",
 		ForceColors:               config.HighLighting,
 		EnvironmentOverrideColors: config.HighLighting,
-		CallerPrettyfier: func(frame *runtime.Frame) (function string, file string) {
-			pc, file, line, _ := runtime.Caller(config.CallerSkip)
-			name := runtime.FuncForPC(pc).Name()
-			if i := bytes.LastIndexAny([]byte(name), "."); i != -1 {
-				name = name[i+1:]
-			}
-
-			if i := bytes.LastIndexAny([]byte(file), "/"); i != -1 {
-				file = file[i+1:]
-			}
-
-			return name, fmt.Sprintf("%s:%d", file, line)
-		},
+		CallerPrettyfier:          l.caller,
 	})
 	if config.JSONFormatter {
 		l.logger.SetFormatter(&logrus.JSONFormatter{
 			TimestampFormat:  "2006-01-02 15:04:05.99",
 			DisableTimestamp: false,
-			CallerPrettyfier: func(frame *runtime.Frame) (function string, file string) {
-				pc, file, line, _ := runtime.Caller(9)
-				name := runtime.FuncForPC(pc).Name()
-				if i := bytes.LastIndexAny([]byte(name), "."); i != -1 {
-					name = name[i+1:]
-				}
-
-				if i := bytes.LastIndexAny([]byte(file), "/"); i != -1 {
-					file = file[i+1:]
-				}
-
-				return name, fmt.Sprintf("%s:%d", file, line)
-			},
-			PrettyPrint: false,
+			CallerPrettyfier: l.caller,
+			PrettyPrint:      false,
 		})
 	}
 	return nil
 }
 
+// caller report the first frame out of logrus and the logger, CallerSkip
+// skip more frames above it, e.g. of a helper which log for its caller.
+func (l *Log) caller(frame *runtime.Frame) (function string, file string) {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	skip := l.config.CallerSkip
+	if skip < 0 {
+		skip = 0
+	}
+	found := false
+	for more := true; more && skip >= 0; {
+		var f runtime.Frame
+		f, more = frames.Next()
+		if found || !isLogFrame(f.Function) {
+			found = true
+			frame = &f
+			skip--
+		}
+	}
+
+	name := frame.Function
+	if i := strings.LastIndex(name, "."); i != -1 {
+		name = name[i+1:]
+	}
+	return name, fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+}
+
+// isLogFrame check function is of logrus, slog, the log package or a method of Log.
+func isLogFrame(function string) bool {
+	return strings.HasPrefix(function, "github.com/sirupsen/logrus.") ||
+		strings.HasPrefix(function, "log/slog.") ||
+		strings.HasPrefix(function, "github.com/dbunion/com/log.") ||
+		strings.HasPrefix(function, "github.com/dbunion/com/log/logrus.(*Log).")
+}
+
 func (l *Log) getLogLevel() logrus.Level {
 	switch l.config.Level {
 	case log.LevelInfo:
@@ -1,7 +1,12 @@
 package logrus
 
 import (
+	"encoding/json"
+	"errors"
 	"github.com/dbunion/com/log"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -81,3 +86,57 @@ func TestLogrusError(t *testing.T) {
 
 	logger.Errorf("logrus test, date:%v", time.Now().Unix())
 }
+
+func TestLogrusFields(t *testing.T) {
+	for _, jsonFormatter := range []bool{true, false} {
+		path := filepath.Join(t.TempDir(), "logrus.log")
+		logger, err := log.NewLogger(log.TypeLogrus, log.Config{
+			Level:         log.LevelInfo,
+			FilePath:      path,
+			JSONFormatter: jsonFormatter,
+		})
+		if err != nil {
+			t.Fatalf("create new logger error, err:%v", err)
+		}
+
+		db := logger.Named("store").Named("db").With(log.String("host", "db1"), log.Int("port", 3306))
+		db.InfoKV("connected", "elapsed", time.Second, log.Err(errors.New("slow dial")))
+		db.Infof("query %d", 1)
+		db.DebugKV("hidden", "k", "v")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read log error, err:%v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expect 2 lines, got:%q", lines)
+		}
+
+		if jsonFormatter {
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+				t.Fatalf("unmarshal json line error, err:%v", err)
+			}
+			expect := map[string]interface{}{
+				"msg": "connected", "logger": "store.db", "host": "db1", "port": float64(3306),
+				"elapsed": float64(time.Second), "error": "slow dial", "func": "TestLogrusFields",
+			}
+			for k, v := range expect {
+				if entry[k] != v {
+					t.Fatalf("expect %s=%v, got:%v", k, v, entry[k])
+				}
+			}
+			continue
+		}
+		for _, s := range []string{"msg=connected", "logger=store.db", "host=db1", "port=3306",
+			"elapsed=1s", `error="slow dial"`, "logrus_test.go"} {
+			if !strings.Contains(lines[0], s) {
+				t.Fatalf("expect %s in line:%s", s, lines[0])
+			}
+		}
+		if !strings.Contains(lines[1], `msg="query 1"`) || !strings.Contains(lines[1], "host=db1") {
+			t.Fatalf("expect fields of logger in line:%s", lines[1])
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"fmt"
+	"os"
+)
+
+// nopLogger discard all lines, Fatal still exit and Panic still panic.
+type nopLogger struct{}
+
+// Nop return a logger which discard all lines.
+func Nop() Logger {
+	return nopLogger{}
+}
+
+func (nopLogger) Infof(format string, v ...interface{})    {}
+func (nopLogger) Info(v ...interface{})                    {}
+func (nopLogger) Debugf(format string, v ...interface{})   {}
+func (nopLogger) Debug(v ...interface{})                   {}
+func (nopLogger) Warnf(format string, v ...interface{})    {}
+func (nopLogger) Warn(v ...interface{})                    {}
+func (nopLogger) Warningf(format string, v ...interface{}) {}
+func (nopLogger) Warning(v ...interface{})                 {}
+func (nopLogger) Errorf(format string, v ...interface{})   {}
+func (nopLogger) Error(v ...interface{})                   {}
+func (nopLogger) Fatalf(format string, v ...interface{})   { os.Exit(1) }
+func (nopLogger) Fatal(v ...interface{})                   { os.Exit(1) }
+func (nopLogger) Fatalln(v ...interface{})                 { os.Exit(1) }
+func (nopLogger) Printf(format string, v ...interface{})   {}
+func (nopLogger) Print(v ...interface{})                   {}
+func (nopLogger) Println(v ...interface{})                 {}
+func (nopLogger) Panic(v ...interface{})                   { panic(fmt.Sprint(v...)) }
+func (nopLogger) Panicf(format string, v ...interface{})   { panic(fmt.Sprintf(format, v...)) }
+func (nopLogger) Panicln(v ...interface{})                 { panic(fmt.Sprintln(v...)) }
+func (l nopLogger) With(fields ...Field) Logger            { return l }
+func (l nopLogger) Named(name string) Logger               { return l }
+func (nopLogger) DebugKV(msg string, kv ...interface{})    {}
+func (nopLogger) InfoKV(msg string, kv ...interface{})     {}
+func (nopLogger) WarnKV(msg string, kv ...interface{})     {}
+func (nopLogger) ErrorKV(msg string, kv ...interface{})    {}
+func (nopLogger) Close() error                             { return nil }
+func (nopLogger) StartAndGC(config Config) error           { return nil }
@@ -0,0 +1,316 @@
+package slog
+
+import (
+	"context"
+	"fmt"
+	"github.com/dbunion/com/log"
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// levels of slog for fatal and panic logs
+const (
+	LevelFatal = slog.Level(12)
+	LevelPanic = slog.Level(16)
+)
+
+// Log is log adapter, HighLighting is not supported by slog.
+type Log struct {
+	config  log.Config
+	root    slog.Handler
+	handler slog.Handler
+	name    string
+	attrs   []slog.Attr
+	writer  *rotatelogs.RotateLogs
+}
+
+// NewSlog create new slog log with default collection name.
+func NewSlog() log.Logger {
+	return &Log{}
+}
+
+// Infof - info format log
+func (l *Log) Infof(format string, v ...interface{}) {
+	l.logf(slog.LevelInfo, format, v...)
+}
+
+// Info - info format log
+func (l *Log) Info(v ...interface{}) {
+	l.log(slog.LevelInfo, fmt.Sprint(v...))
+}
+
+// Debugf - debug format log
+func (l *Log) Debugf(format string, v ...interface{}) {
+	l.logf(slog.LevelDebug, format, v...)
+}
+
+// Debug - debug log
+func (l *Log) Debug(v ...interface{}) {
+	l.log(slog.LevelDebug, fmt.Sprint(v...))
+}
+
+// Warnf - warn format log
+func (l *Log) Warnf(format string, v ...interface{}) {
+	l.logf(slog.LevelWarn, format, v...)
+}
+
+// Warn - warn log
+func (l *Log) Warn(v ...interface{}) {
+	l.log(slog.LevelWarn, fmt.Sprint(v...))
+}
+
+// Warningf - Warning format log
+func (l *Log) Warningf(format string, v ...interface{}) {
+	l.logf(slog.LevelWarn, format, v...)
+}
+
+// Warning - Warning log
+func (l *Log) Warning(v ...interface{}) {
+	l.log(slog.LevelWarn, fmt.Sprint(v...))
+}
+
+// Errorf - error format log
+func (l *Log) Errorf(format string, v ...interface{}) {
+	l.logf(slog.LevelError, format, v...)
+}
+
+// Error - error log
+func (l *Log) Error(v ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprint(v...))
+}
+
+// Fatalf - fatal format log
+func (l *Log) Fatalf(format string, v ...interface{}) {
+	l.logf(LevelFatal, format, v...)
+	os.Exit(1)
+}
+
+// Fatal - fatal format log
+func (l *Log) Fatal(v ...interface{}) {
+	l.log(LevelFatal, fmt.Sprint(v...))
+	os.Exit(1)
+}
+
+// Fatalln - fatal log
+func (l *Log) Fatalln(v ...interface{}) {
+	l.log(LevelFatal, sprintln(v...))
+	os.Exit(1)
+}
+
+// Printf - print format log
+func (l *Log) Printf(format string, v ...interface{}) {
+	l.logf(slog.LevelInfo, format, v...)
+}
+
+// Print - print log
+func (l *Log) Print(v ...interface{}) {
+	l.log(slog.LevelInfo, fmt.Sprint(v...))
+}
+
+// Println - print log
+func (l *Log) Println(v ...interface{}) {
+	l.log(slog.LevelInfo, sprintln(v...))
+}
+
+// Panic - panic
+func (l *Log) Panic(v ...interface{}) {
+	msg := fmt.Sprint(v...)
+	l.log(LevelPanic, msg)
+	panic(msg)
+}
+
+// Panicf - panic format value
+func (l *Log) Panicf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	l.log(LevelPanic, msg)
+	panic(msg)
+}
+
+// Panicln - panic
+func (l *Log) Panicln(v ...interface{}) {
+	msg := sprintln(v...)
+	l.log(LevelPanic, msg)
+	panic(msg)
+}
+
+// With - child logger which log fields with every line
+func (l *Log) With(fields ...log.Field) log.Logger {
+	child := *l
+	child.attrs = append(append([]slog.Attr(nil), l.attrs...), toAttrs(fields)...)
+	child.handler = l.handler.WithAttrs(toAttrs(fields))
+	return &child
+}
+
+// Named - child logger of a component
+func (l *Log) Named(name string) log.Logger {
+	child := *l
+	child.name = log.JoinName(l.name, name)
+	attrs := append([]slog.Attr{slog.String(log.FieldLogger, child.name)}, l.attrs...)
+	child.handler = l.root.WithAttrs(attrs)
+	return &child
+}
+
+// DebugKV - debug log with key/value pairs
+func (l *Log) DebugKV(msg string, kv ...interface{}) {
+	l.log(slog.LevelDebug, msg, toAttrs(log.Fields(kv...))...)
+}
+
+// InfoKV - info log with key/value pairs
+func (l *Log) InfoKV(msg string, kv ...interface{}) {
+	l.log(slog.LevelInfo, msg, toAttrs(log.Fields(kv...))...)
+}
+
+// WarnKV - warn log with key/value pairs
+func (l *Log) WarnKV(msg string, kv ...interface{}) {
+	l.log(slog.LevelWarn, msg, toAttrs(log.Fields(kv...))...)
+}
+
+// ErrorKV - error log with key/value pairs
+func (l *Log) ErrorKV(msg string, kv ...interface{}) {
+	l.log(slog.LevelError, msg, toAttrs(log.Fields(kv...))...)
+}
+
+// logf log a format message, called by the methods of Log only.
+func (l *Log) logf(level slog.Level, format string, v ...interface{}) {
+	if !l.handler.Enabled(context.Background(), level) {
+		return
+	}
+	l.output(level, fmt.Sprintf(format, v...), nil)
+}
+
+// log log msg with attrs, called by the methods of Log only.
+func (l *Log) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	if !l.handler.Enabled(context.Background(), level) {
+		return
+	}
+	l.output(level, msg, attrs)
+}
+
+// output hand a record to the handler, its source is the caller of the
+// method of Log, CallerSkip skip more frames above it.
+func (l *Log) output(level slog.Level, msg string, attrs []slog.Attr) {
+	var pcs [1]uintptr
+	// skip Callers, output, log and the method of Log
+	runtime.Callers(4+l.config.CallerSkip, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.AddAttrs(attrs...)
+	_ = l.handler.Handle(context.Background(), r)
+}
+
+func toAttrs(fields []log.Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		if err, ok := f.Value.(error); ok {
+			attrs = append(attrs, slog.String(f.Key, err.Error()))
+			continue
+		}
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	return attrs
+}
+
+func sprintln(v ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+}
+
+// Close close the log file, the child loggers share it.
+func (l *Log) Close() error {
+	if l.writer == nil {
+		return nil
+	}
+	return l.writer.Close()
+}
+
+// StartAndGC start log adapter.
+func (l *Log) StartAndGC(config log.Config) error {
+	config.CheckWithDefault()
+
+	l.config = config
+
+	opts := []rotatelogs.Option{
+		rotatelogs.WithLinkName(config.FilePath),
+		rotatelogs.WithRotationTime(config.RotationTime),
+	}
+
+	if config.RotationMaxAge > 0 {
+		opts = append(opts, rotatelogs.WithMaxAge(config.RotationMaxAge))
+	}
+
+	if config.RotationCount > 0 {
+		opts = append(opts, rotatelogs.WithRotationCount(config.RotationCount))
+	}
+
+	writer, err := rotatelogs.New(l.config.FilePath+".%Y%m%d%H%M", opts...)
+	if err != nil {
+		return err
+	}
+	l.writer = writer
+
+	var out io.Writer = writer
+	if config.AlsoToStdOut {
+		out = io.MultiWriter(os.Stdout, writer)
+	}
+
+	handlerOpts := &slog.HandlerOptions{
+		AddSource:   true,
+		Level:       l.getLogLevel(),
+		ReplaceAttr: replaceAttr,
+	}
+	if config.JSONFormatter {
+		l.root = slog.NewJSONHandler(out, handlerOpts)
+	} else {
+		l.root = slog.NewTextHandler(out, handlerOpts)
+	}
+	l.handler = l.root
+	return nil
+}
+
+// replaceAttr name the fatal and panic levels and shorten the source file.
+func replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.LevelKey:
+		switch a.Value.Any() {
+		case LevelFatal:
+			return slog.String(slog.LevelKey, "FATAL")
+		case LevelPanic:
+			return slog.String(slog.LevelKey, "PANIC")
+		}
+	case slog.SourceKey:
+		if src, ok := a.Value.Any().(*slog.Source); ok {
+			file := src.File
+			if i := strings.LastIndex(file, "/"); i != -1 {
+				file = file[i+1:]
+			}
+			return slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", file, src.Line))
+		}
+	}
+	return a
+}
+
+func (l *Log) getLogLevel() slog.Level {
+	switch l.config.Level {
+	case log.LevelInfo:
+		return slog.LevelInfo
+	case log.LevelDebug:
+		return slog.LevelDebug
+	case log.LevelWarning:
+		return slog.LevelWarn
+	case log.LevelError:
+		return slog.LevelError
+	case log.LevelFatal:
+		return LevelFatal
+	}
+	return slog.LevelInfo
+}
+
+func init() {
+	log.Register(log.TypeSlog, NewSlog)
+}
@@ -0,0 +1,124 @@
+package slog
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dbunion/com/log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlogInfo(t *testing.T) {
+	logger, err := log.NewLogger(log.TypeSlog, log.Config{
+		Level:          log.LevelInfo,
+		FilePath:       filepath.Join(t.TempDir(), "slog.log"),
+		JSONFormatter:  true,
+		RotationCount:  3,
+		RotationTime:   time.Minute,
+		RotationMaxAge: time.Minute * 4,
+	})
+
+	if err != nil {
+		t.Fatalf("create new logger error, err:%v", err)
+	}
+
+	logger.Infof("slog test, date:%v", time.Now().Unix())
+}
+
+func TestSlogFields(t *testing.T) {
+	for _, jsonFormatter := range []bool{true, false} {
+		path := filepath.Join(t.TempDir(), "slog.log")
+		logger, err := log.NewLogger(log.TypeSlog, log.Config{
+			Level:         log.LevelInfo,
+			FilePath:      path,
+			JSONFormatter: jsonFormatter,
+		})
+		if err != nil {
+			t.Fatalf("create new logger error, err:%v", err)
+		}
+
+		db := logger.Named("store").With(log.String("host", "db1")).Named("db").With(log.Int("port", 3306))
+		db.InfoKV("connected", "elapsed", time.Second, log.Err(errors.New("slow dial")))
+		db.Warningf("query %d", 1)
+		db.DebugKV("hidden", "k", "v")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read log error, err:%v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expect 2 lines, got:%q", lines)
+		}
+
+		if jsonFormatter {
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+				t.Fatalf("unmarshal json line error, err:%v", err)
+			}
+			expect := map[string]interface{}{
+				"msg": "connected", "level": "INFO", "logger": "store.db", "host": "db1", "port": float64(3306),
+				"elapsed": float64(time.Second), "error": "slow dial",
+			}
+			for k, v := range expect {
+				if entry[k] != v {
+					t.Fatalf("expect %s=%v, got:%v", k, v, entry[k])
+				}
+			}
+			if source, _ := entry["source"].(string); !strings.HasPrefix(source, "slog_test.go:") {
+				t.Fatalf("expect source of test, got:%v", entry["source"])
+			}
+			continue
+		}
+		for _, s := range []string{"level=INFO", "msg=connected", "logger=store.db", "host=db1", "port=3306",
+			"elapsed=1s", `error="slow dial"`, "source=slog_test.go:"} {
+			if !strings.Contains(lines[0], s) {
+				t.Fatalf("expect %s in line:%s", s, lines[0])
+			}
+		}
+		if !strings.Contains(lines[1], `msg="query 1"`) || !strings.Contains(lines[1], "level=WARN") ||
+			!strings.Contains(lines[1], "port=3306") {
+			t.Fatalf("expect fields of logger in line:%s", lines[1])
+		}
+	}
+}
+
+func TestSlogClose(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := log.NewLogger(log.TypeSlog, log.Config{
+		Level:    log.LevelInfo,
+		FilePath: filepath.Join(dir, "slog.log"),
+	})
+	if err != nil {
+		t.Fatalf("create new logger error, err:%v", err)
+	}
+
+	logger.Named("child").Infof("slog close test")
+	if n := openFiles(t, dir); n != 1 {
+		t.Fatalf("expect 1 open log file, got:%v", n)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("close logger error, err:%v", err)
+	}
+	if n := openFiles(t, dir); n != 0 {
+		t.Fatalf("expect log file closed, got:%v open", n)
+	}
+}
+
+// openFiles count the files in dir opened by the process.
+func openFiles(t *testing.T, dir string) int {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skipf("list open files error, err:%v", err)
+	}
+	n := 0
+	for _, fd := range fds {
+		if path, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name())); err == nil && strings.HasPrefix(path, dir) {
+			n++
+		}
+	}
+	return n
+}
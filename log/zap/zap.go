@@ -0,0 +1,250 @@
+package zap
+
+import (
+	"fmt"
+	"github.com/dbunion/com/log"
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"os"
+	"strings"
+)
+
+// Log is log adapter.
+type Log struct {
+	config log.Config
+	logger *zap.Logger
+	writer *rotatelogs.RotateLogs
+}
+
+// NewZap create new zap log with default collection name.
+func NewZap() log.Logger {
+	return &Log{}
+}
+
+// Infof - info format log
+func (l *Log) Infof(format string, v ...interface{}) {
+	l.logger.Info(fmt.Sprintf(format, v...))
+}
+
+// Info - info format log
+func (l *Log) Info(v ...interface{}) {
+	l.logger.Info(fmt.Sprint(v...))
+}
+
+// Debugf - debug format log
+func (l *Log) Debugf(format string, v ...interface{}) {
+	l.logger.Debug(fmt.Sprintf(format, v...))
+}
+
+// Debug - debug log
+func (l *Log) Debug(v ...interface{}) {
+	l.logger.Debug(fmt.Sprint(v...))
+}
+
+// Warnf - warn format log
+func (l *Log) Warnf(format string, v ...interface{}) {
+	l.logger.Warn(fmt.Sprintf(format, v...))
+}
+
+// Warn - warn log
+func (l *Log) Warn(v ...interface{}) {
+	l.logger.Warn(fmt.Sprint(v...))
+}
+
+// Warningf - Warning format log
+func (l *Log) Warningf(format string, v ...interface{}) {
+	l.logger.Warn(fmt.Sprintf(format, v...))
+}
+
+// Warning - Warning log
+func (l *Log) Warning(v ...interface{}) {
+	l.logger.Warn(fmt.Sprint(v...))
+}
+
+// Errorf - error format log
+func (l *Log) Errorf(format string, v ...interface{}) {
+	l.logger.Error(fmt.Sprintf(format, v...))
+}
+
+// Error - error log
+func (l *Log) Error(v ...interface{}) {
+	l.logger.Error(fmt.Sprint(v...))
+}
+
+// Fatalf - fatal format log
+func (l *Log) Fatalf(format string, v ...interface{}) {
+	l.logger.Fatal(fmt.Sprintf(format, v...))
+}
+
+// Fatal - fatal format log
+func (l *Log) Fatal(v ...interface{}) {
+	l.logger.Fatal(fmt.Sprint(v...))
+}
+
+// Fatalln - fatal log
+func (l *Log) Fatalln(v ...interface{}) {
+	l.logger.Fatal(sprintln(v...))
+}
+
+// Printf - print format log
+func (l *Log) Printf(format string, v ...interface{}) {
+	l.logger.Info(fmt.Sprintf(format, v...))
+}
+
+// Print - print log
+func (l *Log) Print(v ...interface{}) {
+	l.logger.Info(fmt.Sprint(v...))
+}
+
+// Println - print log
+func (l *Log) Println(v ...interface{}) {
+	l.logger.Info(sprintln(v...))
+}
+
+// Panic - panic
+func (l *Log) Panic(v ...interface{}) {
+	l.logger.Panic(fmt.Sprint(v...))
+}
+
+// Panicf - panic format value
+func (l *Log) Panicf(format string, v ...interface{}) {
+	l.logger.Panic(fmt.Sprintf(format, v...))
+}
+
+// Panicln - panic
+func (l *Log) Panicln(v ...interface{}) {
+	l.logger.Panic(sprintln(v...))
+}
+
+// With - child logger which log fields with every line
+func (l *Log) With(fields ...log.Field) log.Logger {
+	child := *l
+	child.logger = l.logger.With(toFields(fields)...)
+	return &child
+}
+
+// Named - child logger of a component
+func (l *Log) Named(name string) log.Logger {
+	child := *l
+	child.logger = l.logger.Named(name)
+	return &child
+}
+
+// DebugKV - debug log with key/value pairs
+func (l *Log) DebugKV(msg string, kv ...interface{}) {
+	l.logger.Debug(msg, toFields(log.Fields(kv...))...)
+}
+
+// InfoKV - info log with key/value pairs
+func (l *Log) InfoKV(msg string, kv ...interface{}) {
+	l.logger.Info(msg, toFields(log.Fields(kv...))...)
+}
+
+// WarnKV - warn log with key/value pairs
+func (l *Log) WarnKV(msg string, kv ...interface{}) {
+	l.logger.Warn(msg, toFields(log.Fields(kv...))...)
+}
+
+// ErrorKV - error log with key/value pairs
+func (l *Log) ErrorKV(msg string, kv ...interface{}) {
+	l.logger.Error(msg, toFields(log.Fields(kv...))...)
+}
+
+func toFields(fields []log.Field) []zap.Field {
+	data := make([]zap.Field, 0, len(fields))
+	for _, f := range fields {
+		if err, ok := f.Value.(error); ok {
+			data = append(data, zap.String(f.Key, err.Error()))
+			continue
+		}
+		data = append(data, zap.Any(f.Key, f.Value))
+	}
+	return data
+}
+
+func sprintln(v ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+}
+
+// Close close the log file, the child loggers share it.
+func (l *Log) Close() error {
+	if l.logger == nil {
+		return nil
+	}
+	// sync of stdout fail on some systems, the file is written unbuffered
+	_ = l.logger.Sync()
+	return l.writer.Close()
+}
+
+// StartAndGC start log adapter.
+func (l *Log) StartAndGC(config log.Config) error {
+	config.CheckWithDefault()
+
+	l.config = config
+
+	opts := []rotatelogs.Option{
+		rotatelogs.WithLinkName(config.FilePath),
+		rotatelogs.WithRotationTime(config.RotationTime),
+	}
+
+	if config.RotationMaxAge > 0 {
+		opts = append(opts, rotatelogs.WithMaxAge(config.RotationMaxAge))
+	}
+
+	if config.RotationCount > 0 {
+		opts = append(opts, rotatelogs.WithRotationCount(config.RotationCount))
+	}
+
+	writer, err := rotatelogs.New(l.config.FilePath+".%Y%m%d%H%M", opts...)
+	if err != nil {
+		return err
+	}
+	l.writer = writer
+
+	var out io.Writer = writer
+	if config.AlsoToStdOut {
+		out = io.MultiWriter(os.Stdout, writer)
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.NameKey = log.FieldLogger
+	encoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05.99")
+	encoderConfig.EncodeDuration = zapcore.StringDurationEncoder
+	var encoder zapcore.Encoder
+	if config.JSONFormatter {
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	} else {
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		if config.HighLighting {
+			encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		}
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.AddSync(out), l.getLogLevel())
+	// skip the method of Log, CallerSkip skip more frames above its caller
+	l.logger = zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1+config.CallerSkip))
+	return nil
+}
+
+func (l *Log) getLogLevel() zapcore.Level {
+	switch l.config.Level {
+	case log.LevelInfo:
+		return zapcore.InfoLevel
+	case log.LevelDebug:
+		return zapcore.DebugLevel
+	case log.LevelWarning:
+		return zapcore.WarnLevel
+	case log.LevelError:
+		return zapcore.ErrorLevel
+	case log.LevelFatal:
+		return zapcore.FatalLevel
+	}
+	return zapcore.InfoLevel
+}
+
+func init() {
+	log.Register(log.TypeZap, NewZap)
+}
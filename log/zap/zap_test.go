@@ -0,0 +1,161 @@
+package zap
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dbunion/com/log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZapInfo(t *testing.T) {
+	logger, err := log.NewLogger(log.TypeZap, log.Config{
+		Level:          log.LevelInfo,
+		FilePath:       filepath.Join(t.TempDir(), "zap.log"),
+		HighLighting:   true,
+		RotationCount:  3,
+		RotationTime:   time.Minute,
+		RotationMaxAge: time.Minute * 4,
+	})
+
+	if err != nil {
+		t.Fatalf("create new logger error, err:%v", err)
+	}
+
+	logger.Infof("zap test, date:%v", time.Now().Unix())
+}
+
+func TestZapFields(t *testing.T) {
+	for _, jsonFormatter := range []bool{true, false} {
+		path := filepath.Join(t.TempDir(), "zap.log")
+		logger, err := log.NewLogger(log.TypeZap, log.Config{
+			Level:         log.LevelInfo,
+			FilePath:      path,
+			JSONFormatter: jsonFormatter,
+		})
+		if err != nil {
+			t.Fatalf("create new logger error, err:%v", err)
+		}
+
+		db := logger.Named("store").Named("db").With(log.String("host", "db1"), log.Int("port", 3306))
+		db.InfoKV("connected", "elapsed", time.Second, log.Err(errors.New("slow dial")))
+		db.Errorf("query %d", 1)
+		db.DebugKV("hidden", "k", "v")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read log error, err:%v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expect 2 lines, got:%q", lines)
+		}
+
+		if jsonFormatter {
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+				t.Fatalf("unmarshal json line error, err:%v", err)
+			}
+			expect := map[string]interface{}{
+				"msg": "connected", "level": "info", "logger": "store.db", "host": "db1", "port": float64(3306),
+				"elapsed": "1s", "error": "slow dial",
+			}
+			for k, v := range expect {
+				if entry[k] != v {
+					t.Fatalf("expect %s=%v, got:%v", k, v, entry[k])
+				}
+			}
+			if caller, _ := entry["caller"].(string); !strings.HasPrefix(caller, "zap/zap_test.go:") {
+				t.Fatalf("expect caller of test, got:%v", entry["caller"])
+			}
+			continue
+		}
+		for _, s := range []string{"INFO", "store.db", "zap/zap_test.go:", "connected",
+			`"host": "db1"`, `"port": 3306`, `"elapsed": "1s"`, `"error": "slow dial"`} {
+			if !strings.Contains(lines[0], s) {
+				t.Fatalf("expect %s in line:%s", s, lines[0])
+			}
+		}
+		if !strings.Contains(lines[1], "query 1") || !strings.Contains(lines[1], "ERROR") {
+			t.Fatalf("expect error line, got:%s", lines[1])
+		}
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zap.log")
+	logger, err := log.NewLogger(log.TypeZap, log.Config{
+		Level:         log.LevelDebug,
+		FilePath:      path,
+		JSONFormatter: true,
+	})
+	if err != nil {
+		t.Fatalf("create new logger error, err:%v", err)
+	}
+
+	sl := slog.New(log.NewSlogHandler(logger, slog.LevelInfo))
+	sl.With("lib", "client").WithGroup("req").Warn("retry", "attempt", 2, slog.Group("peer", "addr", "10.0.0.1"))
+	sl.Debug("hidden")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log error, err:%v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expect 1 line, got:%q", lines)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("unmarshal json line error, err:%v", err)
+	}
+	expect := map[string]interface{}{
+		"msg": "retry", "level": "warn", "lib": "client", "req.attempt": float64(2), "req.peer.addr": "10.0.0.1",
+	}
+	for k, v := range expect {
+		if entry[k] != v {
+			t.Fatalf("expect %s=%v, got:%v", k, v, entry[k])
+		}
+	}
+}
+
+func TestZapClose(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := log.NewLogger(log.TypeZap, log.Config{
+		Level:    log.LevelInfo,
+		FilePath: filepath.Join(dir, "zap.log"),
+	})
+	if err != nil {
+		t.Fatalf("create new logger error, err:%v", err)
+	}
+
+	logger.Named("child").Infof("zap close test")
+	if n := openFiles(t, dir); n != 1 {
+		t.Fatalf("expect 1 open log file, got:%v", n)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("close logger error, err:%v", err)
+	}
+	if n := openFiles(t, dir); n != 0 {
+		t.Fatalf("expect log file closed, got:%v open", n)
+	}
+}
+
+// openFiles count the files in dir opened by the process.
+func openFiles(t *testing.T, dir string) int {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skipf("list open files error, err:%v", err)
+	}
+	n := 0
+	for _, fd := range fds {
+		if path, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name())); err == nil && strings.HasPrefix(path, dir) {
+			n++
+		}
+	}
+	return n
+}
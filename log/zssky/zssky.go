@@ -1,16 +1,23 @@
 package zssky
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"github.com/dbunion/com/log"
 	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
 	zslog "github.com/zssky/log"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 )
 
-// Log is log adapter.
+// Log is log adapter. zssky log has no fields, fields are rendered into
+// the message as key=value pairs, or as a json object if JSONFormatter.
 type Log struct {
 	config log.Config
+	fields []log.Field
 }
 
 // NewZsskyLog create new zssky log with default collection name.
@@ -20,97 +27,218 @@ func NewZsskyLog() log.Logger {
 
 // Infof - info format log
 func (l *Log) Infof(format string, v ...interface{}) {
+	format, v = l.formatf(format, v)
 	zslog.Infof(format, v...)
 }
 
 // Info - info format log
 func (l *Log) Info(v ...interface{}) {
-	zslog.Info(v...)
+	zslog.Info(l.values(v)...)
 }
 
 // Debugf - debug format log
 func (l *Log) Debugf(format string, v ...interface{}) {
+	format, v = l.formatf(format, v)
 	zslog.Debugf(format, v...)
 }
 
 // Debug - debug log
 func (l *Log) Debug(v ...interface{}) {
-	zslog.Debug(v...)
+	zslog.Debug(l.values(v)...)
 }
 
 // Warnf - warn format log
 func (l *Log) Warnf(format string, v ...interface{}) {
+	format, v = l.formatf(format, v)
 	zslog.Warnf(format, v...)
 }
 
 // Warn - warn log
 func (l *Log) Warn(v ...interface{}) {
-	zslog.Warn(v...)
+	zslog.Warn(l.values(v)...)
 }
 
 // Warningf - Warning format log
 func (l *Log) Warningf(format string, v ...interface{}) {
+	format, v = l.formatf(format, v)
 	zslog.Warningf(format, v...)
 }
 
 // Warning - Warning log
 func (l *Log) Warning(v ...interface{}) {
-	zslog.Warning(v...)
+	zslog.Warning(l.values(v)...)
 }
 
 // Errorf - error format log
 func (l *Log) Errorf(format string, v ...interface{}) {
+	format, v = l.formatf(format, v)
 	zslog.Errorf(format, v...)
 }
 
 // Error - error log
 func (l *Log) Error(v ...interface{}) {
-	zslog.Error(v...)
+	zslog.Error(l.values(v)...)
 }
 
 // Fatalf - fatal format log
 func (l *Log) Fatalf(format string, v ...interface{}) {
+	format, v = l.formatf(format, v)
 	zslog.Fatalf(format, v...)
 }
 
 // Fatal - fatal format log
 func (l *Log) Fatal(v ...interface{}) {
-	zslog.Fatal(v...)
+	zslog.Fatal(l.values(v)...)
 }
 
 // Fatalln - fatal log
 func (l *Log) Fatalln(v ...interface{}) {
-	zslog.Fatal(v...)
+	zslog.Fatal(l.values(v)...)
 }
 
 // Printf - print format log
 func (l *Log) Printf(format string, v ...interface{}) {
+	format, v = l.formatf(format, v)
 	zslog.Infof(format, v...)
 }
 
 // Print - print format log
 func (l *Log) Print(v ...interface{}) {
-	zslog.Info(v...)
+	zslog.Info(l.values(v)...)
 }
 
 // Println - print value
 func (l *Log) Println(v ...interface{}) {
-	zslog.Info(v...)
+	zslog.Info(l.values(v)...)
 }
 
 // Panic - panic
 func (l *Log) Panic(v ...interface{}) {
-	zslog.Error(v...)
+	zslog.Error(l.values(v)...)
 }
 
 // Panicf - panic format value
 func (l *Log) Panicf(format string, v ...interface{}) {
+	format, v = l.formatf(format, v)
 	zslog.Errorf(format, v...)
 }
 
 // Panicln - panic
 func (l *Log) Panicln(v ...interface{}) {
-	zslog.Error(v...)
+	zslog.Error(l.values(v)...)
+}
+
+// With - child logger which log fields with every line
+func (l *Log) With(fields ...log.Field) log.Logger {
+	child := *l
+	child.fields = append(append([]log.Field(nil), l.fields...), fields...)
+	return &child
+}
+
+// Named - child logger of a component
+func (l *Log) Named(name string) log.Logger {
+	child := *l
+	child.fields = make([]log.Field, 0, len(l.fields)+1)
+	parent := ""
+	for _, f := range l.fields {
+		if f.Key == log.FieldLogger {
+			parent, _ = f.Value.(string)
+			continue
+		}
+		child.fields = append(child.fields, f)
+	}
+	child.fields = append([]log.Field{log.String(log.FieldLogger, log.JoinName(parent, name))}, child.fields...)
+	return &child
+}
+
+// DebugKV - debug log with key/value pairs
+func (l *Log) DebugKV(msg string, kv ...interface{}) {
+	zslog.Debugf("%s", l.line(msg, log.Fields(kv...)))
+}
+
+// InfoKV - info log with key/value pairs
+func (l *Log) InfoKV(msg string, kv ...interface{}) {
+	zslog.Infof("%s", l.line(msg, log.Fields(kv...)))
+}
+
+// WarnKV - warn log with key/value pairs
+func (l *Log) WarnKV(msg string, kv ...interface{}) {
+	zslog.Warnf("%s", l.line(msg, log.Fields(kv...)))
+}
+
+// ErrorKV - error log with key/value pairs
+func (l *Log) ErrorKV(msg string, kv ...interface{}) {
+	zslog.Errorf("%s", l.line(msg, log.Fields(kv...)))
+}
+
+// formatf render a format log with the fields of the logger.
+func (l *Log) formatf(format string, v []interface{}) (string, []interface{}) {
+	if len(l.fields) == 0 {
+		return format, v
+	}
+	return "%s", []interface{}{l.line(fmt.Sprintf(format, v...), nil)}
+}
+
+// values render a log of values with the fields of the logger.
+func (l *Log) values(v []interface{}) []interface{} {
+	if len(l.fields) == 0 {
+		return v
+	}
+	return []interface{}{l.line(strings.TrimSuffix(fmt.Sprintln(v...), "\n"), nil)}
+}
+
+// line render msg with the fields of the logger and fields.
+func (l *Log) line(msg string, fields []log.Field) string {
+	var buf bytes.Buffer
+	if l.config.JSONFormatter {
+		buf.WriteString(`{"msg":`)
+		writeJSON(&buf, msg)
+		for _, f := range append(l.fields[:len(l.fields):len(l.fields)], fields...) {
+			buf.WriteByte(',')
+			writeJSON(&buf, f.Key)
+			buf.WriteByte(':')
+			writeJSON(&buf, f.Value)
+		}
+		buf.WriteByte('}')
+		return buf.String()
+	}
+
+	buf.WriteString(msg)
+	for _, f := range append(l.fields[:len(l.fields):len(l.fields)], fields...) {
+		buf.WriteByte(' ')
+		buf.WriteString(quote(f.Key))
+		buf.WriteByte('=')
+		buf.WriteString(quote(text(f.Value)))
+	}
+	return buf.String()
+}
+
+// writeJSON write the json of value, a value which can not be marshaled
+// is written as its string.
+func writeJSON(buf *bytes.Buffer, value interface{}) {
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(text(value))
+	}
+	buf.Write(data)
+}
+
+func text(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+// quote quote s if it is empty or has spaces, quotes or equal signs.
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
 }
 
 // Close connection
@@ -1,7 +1,11 @@
 package zssky
 
 import (
+	"errors"
 	"github.com/dbunion/com/log"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -79,3 +83,47 @@ func TestZsskyErrorLog(t *testing.T) {
 
 	logger.Errorf("log test, date:%v", time.Now().Unix())
 }
+
+func TestZsskyFields(t *testing.T) {
+	for _, jsonFormatter := range []bool{true, false} {
+		path := filepath.Join(t.TempDir(), "zssky.log")
+		logger, err := log.NewLogger(log.TypeZsskyLog, log.Config{
+			Level:         log.LevelInfo,
+			FilePath:      path,
+			JSONFormatter: jsonFormatter,
+		})
+		if err != nil {
+			t.Fatalf("create new logger error, err:%v", err)
+		}
+
+		db := logger.Named("store").Named("db").With(log.String("host", "db1"), log.Int("port", 3306))
+		db.InfoKV("connected", "elapsed", time.Second, log.Err(errors.New("slow dial")))
+		db.Infof("query %d", 1)
+		db.DebugKV("hidden", "k", "v")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read log error, err:%v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expect 2 lines, got:%q", lines)
+		}
+
+		expect := []string{
+			`connected logger=store.db host=db1 port=3306 elapsed=1s error="slow dial"`,
+			`query 1 logger=store.db host=db1 port=3306`,
+		}
+		if jsonFormatter {
+			expect = []string{
+				`{"msg":"connected","logger":"store.db","host":"db1","port":3306,"elapsed":1000000000,"error":"slow dial"}`,
+				`{"msg":"query 1","logger":"store.db","host":"db1","port":3306}`,
+			}
+		}
+		for i, line := range lines {
+			if !strings.HasSuffix(line, expect[i]) || !strings.Contains(line, "TestZsskyFields") {
+				t.Fatalf("expect line with %s, got:%s", expect[i], line)
+			}
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package rpc
 
-import "time"
+import (
+	"time"
+
+	"github.com/dbunion/com/log"
+)
 
 const (
 	// DefaultMaxMessageSize - Default Max message size
@@ -69,6 +73,10 @@ type Config struct {
 
 	// After having pinged for keepalive check, the client waits for a duration of Timeout and if no activity is seen even after that the connection is closed.
 	GRPCKeepAliveTimeout time.Duration
+
+	// Logger if set the server put a logger with the request and trace ids of every request
+	// into its context, get it by log.FromContext.
+	Logger log.Logger
 }
 
 // IsGRPCEnabled returns true if gRPC server is set
@@ -0,0 +1,131 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/dbunion/com/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader - the metadata key of the request id, a valid id sent by
+// the client is kept, otherwise one is generated. It is sent back in the header.
+const RequestIDHeader = "x-request-id"
+
+// maxRequestIDLen - the max length of a request id sent by the client
+const maxRequestIDLen = 64
+
+// FieldMethod - the key of the full method of a request logger
+const FieldMethod = "grpc_method"
+
+type requestIDKey struct{}
+
+// RequestID return the request id of a request context.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// LoggingStreamInterceptor put a request logger into the context of every
+// stream, see LoggingUnaryInterceptor.
+func LoggingStreamInterceptor(logger log.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, id := requestContext(stream.Context(), logger, info.FullMethod)
+		if err := stream.SetHeader(metadata.Pairs(RequestIDHeader, id)); err != nil {
+			log.FromContext(ctx).WarnKV("set request id header failed", log.Err(err))
+		}
+
+		wrapped := WrapServerStream(stream)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+// LoggingUnaryInterceptor put a request logger into the context of every
+// request, get it by log.FromContext. Every line of the logger carry the
+// request id, the method and the trace and span id of the request. The
+// trace of a request without span is continued from its traceparent.
+func LoggingUnaryInterceptor(logger log.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, id := requestContext(ctx, logger, info.FullMethod)
+		if err := grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id)); err != nil {
+			log.FromContext(ctx).WarnKV("set request id header failed", log.Err(err))
+		}
+		return handler(ctx, req)
+	}
+}
+
+// requestContext return ctx with the request id and the request logger.
+func requestContext(ctx context.Context, logger log.Logger, method string) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var id string
+	if ids := md.Get(RequestIDHeader); len(ids) > 0 && validRequestID(ids[0]) {
+		id = ids[0]
+	} else {
+		id = newRequestID()
+	}
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		propagator := otel.GetTextMapPropagator()
+		if len(propagator.Fields()) == 0 {
+			propagator = propagation.TraceContext{}
+		}
+		ctx = propagator.Extract(ctx, metadataCarrier(md))
+		sc = trace.RemoteSpanContextFromContext(ctx)
+	}
+
+	fields := []log.Field{log.String(log.FieldRequestID, id), log.String(FieldMethod, method)}
+	if sc.IsValid() {
+		fields = append(fields, log.String(log.FieldTraceID, sc.TraceID.String()),
+			log.String(log.FieldSpanID, sc.SpanID.String()))
+	}
+	return log.WithContext(ctx, logger.With(fields...)), id
+}
+
+// validRequestID check id is 1 to maxRequestIDLen chars of [A-Za-z0-9._-],
+// so a client can not forge log lines or blow them up by its id.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// metadataCarrier - incoming metadata as a propagation carrier
+type metadataCarrier metadata.MD
+
+// Get get the first value of key.
+func (c metadataCarrier) Get(key string) string {
+	if vals := metadata.MD(c).Get(key); len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+// Set set key to value.
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
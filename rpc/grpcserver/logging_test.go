@@ -0,0 +1,93 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dbunion/com/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// fieldLogger record the fields of the lines of InfoKV.
+type fieldLogger struct {
+	log.Logger
+	fields []log.Field
+	lines  *[]map[string]interface{}
+}
+
+func (l *fieldLogger) With(fields ...log.Field) log.Logger {
+	return &fieldLogger{Logger: l.Logger, fields: append(append([]log.Field(nil), l.fields...), fields...), lines: l.lines}
+}
+
+func (l *fieldLogger) InfoKV(msg string, kv ...interface{}) {
+	line := map[string]interface{}{"msg": msg}
+	for _, f := range append(l.fields, log.Fields(kv...)...) {
+		line[f.Key] = f.Value
+	}
+	*l.lines = append(*l.lines, line)
+}
+
+func TestLoggingUnaryInterceptor(t *testing.T) {
+	var lines []map[string]interface{}
+	logger := &fieldLogger{Logger: log.Nop(), lines: &lines}
+	interceptor := LoggingUnaryInterceptor(logger)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		log.FromContext(ctx).InfoKV("handled", "req", req)
+		return RequestID(ctx), nil
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		RequestIDHeader, "req-1",
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+	resp, err := interceptor(ctx, 1, info, handler)
+	if err != nil || resp != "req-1" {
+		t.Fatalf("expect request id req-1, got:%v err:%v", resp, err)
+	}
+	expect := map[string]interface{}{
+		"msg":              "handled",
+		"req":              1,
+		log.FieldRequestID: "req-1",
+		FieldMethod:        "/test.Service/Get",
+		log.FieldTraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+		log.FieldSpanID:    "00f067aa0ba902b7",
+	}
+	if len(lines) != 1 || len(lines[0]) != len(expect) {
+		t.Fatalf("expect line %v, got:%v", expect, lines)
+	}
+	for k, v := range expect {
+		if lines[0][k] != v {
+			t.Fatalf("expect %s=%v, got:%v", k, v, lines[0][k])
+		}
+	}
+
+	// a request without ids get a new request id and no trace
+	lines = nil
+	resp, err = interceptor(context.Background(), 2, info, handler)
+	if err != nil || len(resp.(string)) != 32 {
+		t.Fatalf("expect generated request id, got:%v err:%v", resp, err)
+	}
+	if len(lines) != 1 || lines[0][log.FieldRequestID] != resp || lines[0][log.FieldTraceID] != nil {
+		t.Fatalf("expect line with request id %v, got:%v", resp, lines)
+	}
+
+	// an invalid request id is replaced by a new one
+	for _, id := range []string{"req 1\nlevel=error", strings.Repeat("a", 65)} {
+		ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDHeader, id))
+		resp, err = interceptor(ctx, 3, info, handler)
+		if err != nil || resp == id || len(resp.(string)) != 32 {
+			t.Fatalf("expect generated request id for %q, got:%v err:%v", id, resp, err)
+		}
+	}
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDHeader, strings.Repeat("a", 64)))
+	if resp, err = interceptor(ctx, 4, info, handler); err != nil || resp != strings.Repeat("a", 64) {
+		t.Fatalf("expect request id of 64 chars kept, got:%v err:%v", resp, err)
+	}
+
+	// a context without logger get the default logger
+	if _, ok := log.FromContext(context.Background()).(*fieldLogger); ok {
+		t.Fatalf("expect default logger of context without logger")
+	}
+}
@@ -108,6 +108,10 @@ func NewRPCServer(cfg *rpc.Config, outOpts ...grpc.ServerOption) (*Server, error
 func interceptors(cfg *rpc.Config) []grpc.ServerOption {
 	interceptors := &InterceptorBuilder{}
 
+	if cfg.Logger != nil {
+		interceptors.Add(LoggingStreamInterceptor(cfg.Logger), LoggingUnaryInterceptor(cfg.Logger))
+	}
+
 	if cfg.GRPCAuth != "" {
 		pluginInitializer, err := GetAuthenticator(cfg.GRPCAuth)
 		if err != nil {
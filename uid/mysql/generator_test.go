@@ -0,0 +1,306 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dbunion/com/uid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// newMockUID create MyUID on a sqlmock db.
+func newMockUID(t *testing.T, config uid.Config) (*MyUID, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("create sqlmock error, err:%v", err)
+	}
+	r := &MyUID{done: make(chan struct{})}
+	if err := r.configure(config); err != nil {
+		t.Fatalf("configure error, err:%v", err)
+	}
+	r.db = db
+	return r, mock
+}
+
+// expectSegment expect a segment of step ids reserved from nextID of key.
+func expectSegment(mock sqlmock.Sqlmock, key interface{}, nextID, cache, step int64) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`select next_id, cache from seq where (id|biz_tag) = \? for update`).WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"next_id", "cache"}).AddRow(nextID, cache))
+	mock.ExpectExec(`update seq set next_id = \? where (id|biz_tag) = \?`).WithArgs(nextID+step, key).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+// expectCreate expect the row of key missing and inserted by insert ignore, affected is 0 if
+// another process inserted it.
+func expectCreate(mock sqlmock.Sqlmock, table string, key interface{}, initValue, cache, affected int64) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`select next_id, cache from ` + table + ` where (id|biz_tag) = \? for update`).WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"next_id", "cache"}))
+	mock.ExpectRollback()
+	mock.ExpectExec(`insert ignore into `+table).WithArgs(key, initValue, cache).
+		WillReturnResult(sqlmock.NewResult(0, affected))
+}
+
+func TestMyUIDGenerator(t *testing.T) {
+	r, mock := newMockUID(t, uid.Config{TableName: "seq", InitValue: 100, Step: 3})
+	ctx := context.Background()
+
+	// the first segment initializes the sequence row
+	expectCreate(mock, "seq", 0, 100, 3, 1)
+	expectSegment(mock, 0, 100, 3, 3)
+
+	id, err := r.Next(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), id)
+
+	// a batch spanning segments
+	expectSegment(mock, 0, 200, 3, 3)
+	ids, err := r.NextN(ctx, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{101, 102, 200, 201}, ids)
+
+	id32, err := r.Next32(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(202), id32)
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	// errors are returned instead of -1
+	mock.ExpectBegin().WillReturnError(errors.New("connection refused"))
+	_, err = r.Next(ctx)
+	assert.EqualError(t, err, "connection refused")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("select next_id, cache from seq").
+		WillReturnRows(sqlmock.NewRows([]string{"next_id", "cache"}).AddRow(300, 3))
+	mock.ExpectExec("update seq set next_id").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	_, err = r.Next(ctx)
+	assert.NotNil(t, err)
+
+	// the wrapper keeps the old -1 contract
+	mock.ExpectBegin().WillReturnError(errors.New("connection refused"))
+	assert.Equal(t, int64(-1), uid.Wrap(r).NextUID64())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMyUIDDoubleBuffer(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	r, mock := newMockUID(t, uid.Config{
+		TableName: "seq",
+		Step:      10,
+		Adapter: &Config{
+			BizTag:            "order",
+			DoubleBuffer:      true,
+			PrefetchThreshold: 0.5,
+			Metrics:           true,
+			Registerer:        reg,
+		},
+	})
+	ctx := context.Background()
+
+	expectSegment(mock, "order", 0, 10, 10)
+	ids, err := r.NextN(ctx, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{0, 1, 2, 3}, ids)
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	// passing half of the segment load the next one in background
+	expectSegment(mock, "order", 10, 10, 10)
+	ids, err = r.NextN(ctx, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{4, 5}, ids)
+	r.waitLoaded()
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	// the prefetched segment is used without a round trip
+	ids, err = r.NextN(ctx, 8)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{6, 7, 8, 9, 10, 11, 12, 13}, ids)
+
+	r.waitLoaded()
+	assert.Equal(t, float64(1), testutil.ToFloat64(r.metrics.loads.WithLabelValues("seq", "order", "sync")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(r.metrics.loads.WithLabelValues("seq", "order", "async")))
+	assert.Equal(t, float64(10), testutil.ToFloat64(r.metrics.step.WithLabelValues("seq", "order")))
+}
+
+func TestMyUIDConcurrent(t *testing.T) {
+	r, mock := newMockUID(t, uid.Config{
+		TableName: "seq",
+		Step:      100,
+		Adapter:   &Config{BizTag: "order", DoubleBuffer: true},
+	})
+	mock.MatchExpectationsInOrder(true)
+	for i := int64(0); i < 10; i++ {
+		expectSegment(mock, "order", i*100, 100, 100)
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	seen := make(map[int64]bool)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				id, err := r.Next(context.Background())
+				assert.Nil(t, err)
+				mutex.Lock()
+				assert.False(t, seen[id])
+				seen[id] = true
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	r.waitLoaded()
+	assert.Len(t, seen, 800)
+}
+
+func TestMyUIDAdaptiveStep(t *testing.T) {
+	now := time.Now()
+	r, mock := newMockUID(t, uid.Config{
+		TableName: "seq",
+		Step:      10,
+		Adapter: &Config{
+			BizTag:          "order",
+			MaxStep:         40,
+			SegmentDuration: time.Minute,
+		},
+	})
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	expectSegment(mock, "order", 0, 10, 10)
+	_, err := r.NextN(ctx, 10)
+	assert.Nil(t, err)
+
+	// segments running out fast double the step up to MaxStep
+	expectSegment(mock, "order", 10, 10, 20)
+	_, err = r.NextN(ctx, 20)
+	assert.Nil(t, err)
+	expectSegment(mock, "order", 30, 10, 40)
+	_, err = r.NextN(ctx, 40)
+	assert.Nil(t, err)
+	expectSegment(mock, "order", 70, 10, 40)
+	_, err = r.NextN(ctx, 40)
+	assert.Nil(t, err)
+
+	// slow segments halve the step, not below the cache of the row
+	now = now.Add(2 * time.Minute)
+	expectSegment(mock, "order", 110, 10, 20)
+	_, err = r.NextN(ctx, 20)
+	assert.Nil(t, err)
+	now = now.Add(2 * time.Minute)
+	expectSegment(mock, "order", 130, 10, 10)
+	_, err = r.NextN(ctx, 10)
+	assert.Nil(t, err)
+	now = now.Add(2 * time.Minute)
+	expectSegment(mock, "order", 140, 10, 10)
+	ids, err := r.NextN(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{140}, ids)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMyUIDSequences(t *testing.T) {
+	r, mock := newMockUID(t, uid.Config{
+		DBName:          "test",
+		TableName:       "seq",
+		Step:            2,
+		AutoCreateTable: true,
+		Sequences:       map[string]uid.SequenceConfig{"order": {InitValue: 1000, Step: 5}},
+	})
+	reg, err := uid.AsRegistry(r)
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	// the sequence table is created once and rows are inserted on first use
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS test.uid_sequence").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectCreate(mock, "uid_sequence", "order", 1000, 5, 1)
+	expectSequenceSegment(mock, "order", 1000, 5)
+	ids, err := reg.NextN(ctx, "order", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1000, 1001, 1002}, ids)
+
+	expectCreate(mock, "uid_sequence", "user", 0, 2, 1)
+	expectSequenceSegment(mock, "user", 0, 2)
+	id, err := reg.Next(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), id)
+
+	// sequences are cached by name
+	id, err = reg.Next(ctx, "order")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1003), id)
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	_, err = reg.Next(ctx, "")
+	assert.NotNil(t, err)
+}
+
+// expectSequenceSegment expect a segment of cache ids reserved from nextID of the uid_sequence row key.
+func expectSequenceSegment(mock sqlmock.Sqlmock, key string, nextID, cache int64) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`select next_id, cache from uid_sequence where biz_tag = \? for update`).WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"next_id", "cache"}).AddRow(nextID, cache))
+	mock.ExpectExec(`update uid_sequence set next_id = \? where biz_tag = \?`).WithArgs(nextID+cache, key).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestMyUIDConcurrentCreate(t *testing.T) {
+	// two processes find the row missing at the same time, one insert is
+	// ignored and both reserve a segment of the row created once
+	config := uid.Config{TableName: "seq", InitValue: 100, Step: 3, Adapter: &Config{BizTag: "order"}}
+	first, firstMock := newMockUID(t, config)
+	second, secondMock := newMockUID(t, config)
+	expectCreate(firstMock, "seq", "order", 100, 3, 1)
+	expectSegment(firstMock, "order", 100, 3, 3)
+	expectCreate(secondMock, "seq", "order", 100, 3, 0)
+	expectSegment(secondMock, "order", 103, 3, 3)
+
+	var wg sync.WaitGroup
+	ids := make([][]int64, 2)
+	for i, r := range []*MyUID{first, second} {
+		wg.Add(1)
+		go func(i int, r *MyUID) {
+			defer wg.Done()
+			var err error
+			ids[i], err = r.NextN(context.Background(), 3)
+			assert.Nil(t, err)
+		}(i, r)
+	}
+	wg.Wait()
+	assert.Equal(t, []int64{100, 101, 102}, ids[0])
+	assert.Equal(t, []int64{103, 104, 105}, ids[1])
+	assert.Nil(t, firstMock.ExpectationsWereMet())
+	assert.Nil(t, secondMock.ExpectationsWereMet())
+
+	// a row still missing after the insert fails the load
+	r, mock := newMockUID(t, config)
+	expectCreate(mock, "seq", "order", 100, 3, 0)
+	mock.ExpectBegin()
+	mock.ExpectQuery("select next_id, cache from seq").WithArgs("order").
+		WillReturnRows(sqlmock.NewRows([]string{"next_id", "cache"}))
+	mock.ExpectRollback()
+	_, err := r.Next(context.Background())
+	assert.NotNil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+// waitLoaded wait for the background load to finish.
+func (a *allocator) waitLoaded() {
+	a.mutex.Lock()
+	for a.loading {
+		a.cond.Wait()
+	}
+	a.mutex.Unlock()
+}
@@ -0,0 +1,62 @@
+package mysql
+
+import (
+	"github.com/dbunion/com/internal/prom"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics - collectors shared by every MyUID of a registerer
+type metrics struct {
+	loads    *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	step     *prometheus.GaugeVec
+}
+
+var registered prom.Registry[metrics]
+
+// register return the collectors of reg, registering them once.
+func register(reg prometheus.Registerer) (*metrics, error) {
+	return registered.Load(reg, newMetrics)
+}
+
+// newMetrics create and register the collectors of reg.
+func newMetrics(reg prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		loads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "uid",
+			Name:      "segment_loads_total",
+			Help:      "Number of segments loaded from the sequence table, mode is sync if a request waited for it.",
+		}, []string{"table", "biz_tag", "mode"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "uid",
+			Name:      "segment_load_errors_total",
+			Help:      "Number of failed segment loads.",
+		}, []string{"table", "biz_tag", "mode"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "uid",
+			Name:      "segment_load_duration_seconds",
+			Help:      "Latency of segment loads.",
+			Buckets:   []float64{.001, .005, .01, .05, .1, .5, 1, 5},
+		}, []string{"table", "biz_tag"}),
+		step: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "uid",
+			Name:      "segment_step",
+			Help:      "Number of ids of the last loaded segment.",
+		}, []string{"table", "biz_tag"}),
+	}
+	var err error
+	if m.loads, err = prom.Register(reg, m.loads); err != nil {
+		return nil, err
+	}
+	if m.errors, err = prom.Register(reg, m.errors); err != nil {
+		return nil, err
+	}
+	if m.duration, err = prom.Register(reg, m.duration); err != nil {
+		return nil, err
+	}
+	if m.step, err = prom.Register(reg, m.step); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -1,17 +1,16 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"github.com/dbunion/com/uid"
+	"math"
 	"sync"
-	"sync/atomic"
 	"time"
-)
 
-var (
-	errAlreadyCache = errors.New("already alloc cache")
+	"github.com/dbunion/com/uid"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 /**
@@ -40,153 +39,432 @@ CREATE TABLE IF NOT EXISTS %s.%s (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='uid sequence';
 `
 
-// MyUID - mysql uid gen
+var bizTagTemplate = `
+CREATE TABLE IF NOT EXISTS %s.%s (
+  biz_tag varchar(128) NOT NULL,
+  next_id bigint(20) NOT NULL,
+  cache bigint(20) NOT NULL,
+  update_time timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (biz_tag)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='uid sequence';
+`
+
+const (
+	defaultPrefetchThreshold = 0.1
+	defaultSegmentDuration   = 15 * time.Minute
+	defaultSequenceTable     = "uid_sequence"
+	maxBizTagLength          = 128
+)
+
+// segment - ids [next, max) reserved from the sequence table
+type segment struct {
+	next int64
+	max  int64
+	// cache of the row when the segment was loaded
+	base int64
+}
+
+func (s *segment) size() int64 {
+	return s.max - s.next
+}
+
+// Config - segment allocation config of mysql, set it as uid.Config.Adapter
+type Config struct {
+	// row of the sequence table, use the id = 0 row of the legacy table if empty
+	BizTag string `json:"biz_tag"`
+	// biz_tag table of named sequences, default TableName if BizTag is set, else uid_sequence
+	SequenceTable string `json:"sequence_table"`
+	// load the next segment asynchronously before the current one runs out
+	DoubleBuffer bool `json:"double_buffer"`
+	// used share of a segment to start loading the next one, default 0.1
+	PrefetchThreshold float64 `json:"prefetch_threshold"`
+	// adapt step to the allocation rate up to MaxStep, disabled if not greater than Step
+	MaxStep int64 `json:"max_step"`
+	// target lifetime of a segment of adaptive step, default 15 minutes
+	SegmentDuration time.Duration `json:"segment_duration"`
+	// export prometheus metrics of segment loads
+	Metrics bool `json:"metrics"`
+	// registerer of metrics, default prometheus.DefaultRegisterer
+	Registerer prometheus.Registerer `json:"-"`
+}
+
+// MyUID - mysql uid gen, ids are allocated from segments reserved in
+// the sequence table. In double buffer mode the next segment is loaded
+// asynchronously once PrefetchThreshold of the current one is used.
 type MyUID struct {
-	config   *uid.Config
-	done     chan struct{}
-	current  int64
-	remain   int64
-	mutex    sync.Mutex
-	db       *sql.DB
-	reqInt32 bool
-	reqInt64 bool
+	config  *uid.Config
+	adapter *Config
+	done    chan struct{}
+	db      *sql.DB
+	now     func() time.Time
+
+	metrics *metrics
+
+	// the default sequence, the BizTag row or the id = 0 row of the legacy table
+	*allocator
+
+	// named sequences, rows of the sequence table
+	sequenceMutex sync.Mutex
+	sequences     map[string]*allocator
+	tableCreated  bool
 }
 
-// NewMyUID - create new uid with default collection name.
+// allocator - segments of one row of a sequence table
+type allocator struct {
+	r *MyUID
+
+	table     string
+	keyColumn string
+	key       interface{}
+	initValue int64
+	baseStep  int64
+	// create the table before the first fetch
+	autoCreate bool
+
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	current *segment
+	// size of current when loaded
+	currentSize int64
+	next        *segment
+	loading     bool
+
+	// adaptive step
+	step     int64
+	loadedAt time.Time
+}
+
+// NewMyUID - create new uid generator.
 func NewMyUID() uid.UID {
+	return uid.Wrap(NewMyUIDGenerator())
+}
+
+// NewMyUIDGenerator - create new context-aware uid generator.
+func NewMyUIDGenerator() uid.Generator {
 	return &MyUID{
 		done: make(chan struct{}),
 	}
 }
 
-// HasInt32 - has int32 uid
-func (r *MyUID) HasInt32() bool {
-	return true
-}
+// configure apply config defaults and register metrics.
+func (r *MyUID) configure(config uid.Config) error {
+	var mc Config
+	if err := config.AdapterConfig(&mc); err != nil {
+		return err
+	}
+	if mc.PrefetchThreshold <= 0 || mc.PrefetchThreshold >= 1 {
+		mc.PrefetchThreshold = defaultPrefetchThreshold
+	}
+	if mc.SegmentDuration <= 0 {
+		mc.SegmentDuration = defaultSegmentDuration
+	}
+	if mc.SequenceTable == "" {
+		mc.SequenceTable = defaultSequenceTable
+		if mc.BizTag != "" {
+			mc.SequenceTable = config.TableName
+		}
+	}
+	r.config = &config
+	r.adapter = &mc
+	r.sequences = make(map[string]*allocator)
+	if r.now == nil {
+		r.now = time.Now
+	}
 
-func (r *MyUID) next() error {
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
+	r.allocator = r.newAllocator(config.TableName, "id", 0, config.InitValue, config.Step)
+	if mc.BizTag != "" {
+		r.allocator = r.newAllocator(config.TableName, "biz_tag", mc.BizTag, config.InitValue, config.Step)
+	}
 
-	if atomic.LoadInt64(&r.remain) > 0 {
-		return errAlreadyCache
+	if mc.Metrics {
+		reg := mc.Registerer
+		if reg == nil {
+			reg = prometheus.DefaultRegisterer
+		}
+		m, err := register(reg)
+		if err != nil {
+			return err
+		}
+		r.metrics = m
 	}
+	return nil
+}
 
-	txn, err := r.db.Begin()
-	if err != nil {
-		return err
+// newAllocator create the allocator of the row key of table.
+func (r *MyUID) newAllocator(table, keyColumn string, key interface{}, initValue, step int64) *allocator {
+	a := &allocator{
+		r:         r,
+		table:     table,
+		keyColumn: keyColumn,
+		key:       key,
+		initValue: initValue,
+		baseStep:  step,
+		current:   &segment{},
 	}
+	a.cond = sync.NewCond(&a.mutex)
+	return a
+}
 
-	defer func() {
-		_ = txn.Rollback()
-	}()
+// Sequence return the named sequence, a biz_tag row of SequenceTable
+// inserted on the first load.
+func (r *MyUID) Sequence(name string) (uid.Sequence, error) {
+	if name == "" || len(name) > maxBizTagLength {
+		return nil, fmt.Errorf("invalid sequence name %q", name)
+	}
 
-	query := fmt.Sprintf("select next_id, cache from %s where id = 0 for update", r.config.TableName)
-	rows, err := txn.Query(query)
-	if err != nil {
-		return err
+	r.sequenceMutex.Lock()
+	defer r.sequenceMutex.Unlock()
+	if a, ok := r.sequences[name]; ok {
+		return a, nil
 	}
+	seq := r.config.Sequence(name)
+	a := r.newAllocator(r.adapter.SequenceTable, "biz_tag", name, seq.InitValue, seq.Step)
+	a.autoCreate = r.config.AutoCreateTable
+	r.sequences[name] = a
+	return a, nil
+}
 
-	var nextID int64 = r.config.InitValue
-	var cache int64 = r.config.Step
+// createSequenceTable create SequenceTable once.
+func (r *MyUID) createSequenceTable(ctx context.Context) error {
+	r.sequenceMutex.Lock()
+	defer r.sequenceMutex.Unlock()
+	if r.tableCreated {
+		return nil
+	}
+	sql := fmt.Sprintf(bizTagTemplate, r.config.DBName, r.adapter.SequenceTable)
+	if _, err := r.db.ExecContext(ctx, sql); err != nil {
+		return fmt.Errorf("prepare init table err:%v sql:%v", err, sql)
+	}
+	r.tableCreated = true
+	return nil
+}
 
-	if !rows.Next() {
-		if err := rows.Close(); err != nil {
-			return err
-		}
+// errNoRow is returned by reserve if the row of the sequence is missing.
+var errNoRow = errors.New("sequence row not found")
 
-		sql := fmt.Sprintf("insert into %s (id, next_id, cache) values(0, %d, %d)", r.config.TableName, nextID, cache)
-		result, err := txn.Exec(sql)
-		if err != nil {
-			return err
+// fetch reserve step ids from the sequence table, 0 or a step
+// smaller than the cache of the row use the cache. A missing row
+// is inserted with the init value and step first.
+func (a *allocator) fetch(ctx context.Context, step int64) (*segment, error) {
+	if a.autoCreate {
+		if err := a.r.createSequenceTable(ctx); err != nil {
+			return nil, err
 		}
+	}
 
-		rowAffected, err := result.RowsAffected()
-		if err != nil || rowAffected != 1 {
-			return err
-		}
-	} else {
-		if err := rows.Scan(&nextID, &cache); err != nil {
-			return rows.Close()
-		}
-		if err := rows.Close(); err != nil {
-			return err
+	seg, err := a.reserve(ctx, step)
+	if err != errNoRow {
+		return seg, err
+	}
+	if err := a.create(ctx); err != nil {
+		return nil, err
+	}
+	seg, err = a.reserve(ctx, step)
+	if err == errNoRow {
+		return nil, fmt.Errorf("init sequence %v of %s failed, row not found", a.key, a.table)
+	}
+	return seg, err
+}
+
+// create insert the row of the sequence if it is missing. It runs out of
+// the reserving transaction, so processes creating the row at the same
+// time do not deadlock on the gap lock of select for update, and all but
+// one insert are ignored.
+func (a *allocator) create(ctx context.Context) error {
+	sql := fmt.Sprintf("insert ignore into %s (%s, next_id, cache) values(?, ?, ?)", a.table, a.keyColumn)
+	if _, err := a.r.db.ExecContext(ctx, sql, a.key, a.initValue, a.baseStep); err != nil {
+		return fmt.Errorf("init sequence %v of %s err:%v", a.key, a.table, err)
+	}
+	return nil
+}
+
+// reserve reserve a segment from the row of the sequence, errNoRow if it is missing.
+func (a *allocator) reserve(ctx context.Context, step int64) (*segment, error) {
+	txn, err := a.r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = txn.Rollback()
+	}()
+
+	query := fmt.Sprintf("select next_id, cache from %s where %s = ? for update", a.table, a.keyColumn)
+	var nextID, cache int64
+	if err := txn.QueryRowContext(ctx, query, a.key).Scan(&nextID, &cache); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errNoRow
 		}
+		return nil, err
+	}
+	if cache <= 0 {
+		return nil, fmt.Errorf("invalid cache %d of sequence %v of %s", cache, a.key, a.table)
 	}
 
-	newLast := nextID + cache
+	seg := &segment{next: nextID, max: nextID + cache, base: cache}
+	if step > cache {
+		seg.max = nextID + step
+	}
 
 	// update new id
-	query = fmt.Sprintf("update %s set next_id = %d where id = 0", r.config.TableName, newLast)
-	result, err := txn.Exec(query)
+	query = fmt.Sprintf("update %s set next_id = ? where %s = ?", a.table, a.keyColumn)
+	result, err := txn.ExecContext(ctx, query, seg.max, a.key)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	rowAffected, err := result.RowsAffected()
-	if err != nil || rowAffected != 1 {
-		return err
+	if err != nil {
+		return nil, err
+	}
+	if rowAffected != 1 {
+		return nil, fmt.Errorf("update sequence %v of %s failed, affected rows:%d", a.key, a.table, rowAffected)
 	}
 
-	atomic.StoreInt64(&r.current, nextID-1)
-	atomic.StoreInt64(&r.remain, cache)
-	return txn.Commit()
+	if err := txn.Commit(); err != nil {
+		return nil, err
+	}
+	return seg, nil
 }
 
-// NextUID32 - next int32 uid
-func (r *MyUID) NextUID32() int32 {
-	if r.reqInt64 {
-		return -1
+// nextStep return the step of the next segment, must be called with
+// mutex held. The step doubles while segments last less than
+// SegmentDuration and halves when they last twice as long.
+func (a *allocator) nextStep() int64 {
+	config := a.r.adapter
+	if config.MaxStep <= a.baseStep || a.loadedAt.IsZero() {
+		return a.step
 	}
-
-	r.reqInt32 = true
-
-	if atomic.LoadInt64(&r.remain) > 0 && atomic.AddInt64(&r.remain, -1) >= 0 {
-		return int32(atomic.AddInt64(&r.current, 1))
+	elapsed := a.r.now().Sub(a.loadedAt)
+	switch {
+	case elapsed < config.SegmentDuration && a.step*2 <= config.MaxStep:
+		return a.step * 2
+	case elapsed >= 2*config.SegmentDuration:
+		return a.step / 2
 	}
+	return a.step
+}
 
-	// request new cache
-	if err := r.next(); err != nil && err != errAlreadyCache {
-		fmt.Println(err)
-		return -1
+// load fetch a segment and record metrics, mode is sync or async.
+func (a *allocator) load(ctx context.Context, step int64, mode string) (*segment, error) {
+	start := time.Now()
+	seg, err := a.fetch(ctx, step)
+	if m := a.r.metrics; m != nil {
+		tag := fmt.Sprint(a.key)
+		m.duration.WithLabelValues(a.table, tag).Observe(time.Since(start).Seconds())
+		if err != nil {
+			m.errors.WithLabelValues(a.table, tag, mode).Inc()
+		} else {
+			m.loads.WithLabelValues(a.table, tag, mode).Inc()
+			m.step.WithLabelValues(a.table, tag).Set(float64(seg.size()))
+		}
 	}
+	return seg, err
+}
 
-	if atomic.LoadInt64(&r.remain) > 0 && atomic.AddInt64(&r.remain, -1) >= 0 {
-		return int32(atomic.AddInt64(&r.current, 1))
+// loaded record seg as the latest segment, must be called with mutex held.
+func (a *allocator) loaded(seg *segment) {
+	a.step = seg.size()
+	if a.step < seg.base {
+		a.step = seg.base
 	}
-
-	return r.NextUID32()
+	a.loadedAt = a.r.now()
 }
 
-// NextUID64 - next int64 uid
-func (r *MyUID) NextUID64() int64 {
-	if r.reqInt32 {
-		return -1
+// prefetch load the next segment in background, failures are left
+// to the synchronous load when the current segment runs out.
+func (a *allocator) prefetch(step int64) {
+	seg, err := a.load(context.Background(), step, "async")
+
+	a.mutex.Lock()
+	a.loading = false
+	if err == nil {
+		a.next = seg
+		a.loaded(seg)
 	}
+	a.cond.Broadcast()
+	a.mutex.Unlock()
+}
 
-	r.reqInt64 = true
+// maybePrefetch start loading the next segment once PrefetchThreshold
+// of the current one is used, must be called with mutex held.
+func (a *allocator) maybePrefetch() {
+	config := a.r.adapter
+	if !config.DoubleBuffer || a.next != nil || a.loading {
+		return
+	}
+	used := a.currentSize - a.current.size()
+	if float64(used) >= config.PrefetchThreshold*float64(a.currentSize) {
+		a.loading = true
+		go a.prefetch(a.nextStep())
+	}
+}
 
-	if atomic.LoadInt64(&r.remain) > 0 && atomic.AddInt64(&r.remain, -1) >= 0 {
-		return atomic.AddInt64(&r.current, 1)
+// Next32 - next int32 uid
+func (a *allocator) Next32(ctx context.Context) (int32, error) {
+	id, err := a.Next(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if id > math.MaxInt32 {
+		return 0, fmt.Errorf("int32 uid overflow, value:%d", id)
 	}
+	return int32(id), nil
+}
 
-	// request new cache
-	if err := r.next(); err != nil && err != errAlreadyCache {
-		fmt.Println(err)
-		return -1
+// Next - next int64 uid
+func (a *allocator) Next(ctx context.Context) (int64, error) {
+	ids, err := a.NextN(ctx, 1)
+	if err != nil {
+		return 0, err
 	}
+	return ids[0], nil
+}
 
-	if atomic.LoadInt64(&r.remain) > 0 && atomic.AddInt64(&r.remain, -1) >= 0 {
-		return atomic.AddInt64(&r.current, 1)
+// NextN - next n int64 uids, new segments are loaded as needed
+func (a *allocator) NextN(ctx context.Context, n int) ([]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+	if n <= 0 {
+		return []int64{}, nil
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	ids := make([]int64, 0, n)
+	for len(ids) < n {
+		if cur := a.current; cur.size() > 0 {
+			for cur.next < cur.max && len(ids) < n {
+				ids = append(ids, cur.next)
+				cur.next++
+			}
+			a.maybePrefetch()
+			continue
+		}
 
-	return r.NextUID64()
+		switch {
+		case a.next != nil:
+			a.current, a.currentSize, a.next = a.next, a.next.size(), nil
+		case a.loading:
+			a.cond.Wait()
+		default:
+			seg, err := a.load(ctx, a.nextStep(), "sync")
+			if err != nil {
+				return nil, err
+			}
+			a.loaded(seg)
+			a.current, a.currentSize = seg, seg.size()
+		}
+	}
+	return ids, nil
 }
 
 // Close - close connection
 func (r *MyUID) Close() error {
 	// release resource
-	r.done <- struct{}{}
+	close(r.done)
 	return r.db.Close()
 }
 
@@ -194,7 +472,9 @@ func (r *MyUID) Close() error {
 // config is like {"node":"11"}
 // so no gc operation.
 func (r *MyUID) StartAndGC(config uid.Config) error {
-	r.config = &config
+	if err := r.configure(config); err != nil {
+		return err
+	}
 
 	db, err := sql.Open("mysql",
 		fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?&timeout=30s", r.config.User, r.config.Password, r.config.Server, r.config.Port, r.config.DBName))
@@ -213,10 +493,15 @@ func (r *MyUID) StartAndGC(config uid.Config) error {
 
 	// check table
 	if config.AutoCreateTable {
-		sql := fmt.Sprintf(sequenceTemplate, config.DBName, config.TableName)
+		template := sequenceTemplate
+		if r.adapter.BizTag != "" {
+			template = bizTagTemplate
+		}
+		sql := fmt.Sprintf(template, config.DBName, config.TableName)
 		if _, err := db.Exec(sql); err != nil {
 			return fmt.Errorf("prepare init table err:%v sql:%v", err, sql)
 		}
+		r.tableCreated = r.adapter.SequenceTable == config.TableName && r.adapter.BizTag != ""
 	}
 
 	r.db = db
@@ -239,5 +524,5 @@ func (r *MyUID) StartAndGC(config uid.Config) error {
 }
 
 func init() {
-	uid.Register(uid.TypeMySQL, NewMyUID)
+	uid.RegisterGenerator(uid.TypeMySQL, NewMyUIDGenerator)
 }
@@ -0,0 +1,112 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dbunion/com/uid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisGenerator(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	defer mr.Close()
+	host, port, _ := strings.Cut(mr.Addr(), ":")
+	p, _ := strconv.ParseInt(port, 10, 64)
+
+	g, err := uid.NewGenerator(uid.TypeRedis, uid.Config{Server: host, Port: p})
+	if err != nil {
+		t.Fatalf("create new generator error, err:%v", err)
+	}
+	defer g.Close()
+
+	ctx := context.Background()
+	first, err := g.Next(ctx)
+	assert.Nil(t, err)
+	ids, err := g.NextN(ctx, 10)
+	assert.Nil(t, err)
+	assert.Len(t, ids, 10)
+	for i, id := range ids {
+		assert.Equal(t, first+int64(i)+1, id)
+	}
+	last := ids[9]
+	ids, err = g.NextN(ctx, 0)
+	assert.Nil(t, err)
+	assert.Empty(t, ids)
+
+	id32, err := g.(uid.Int32Generator).Next32(ctx)
+	assert.Nil(t, err)
+	assert.Greater(t, id32, int32(0))
+
+	// old interface is a wrapper of the generator
+	s, err := uid.NewUID(uid.TypeRedis, uid.Config{Server: host, Port: p})
+	assert.Nil(t, err)
+	assert.True(t, s.HasInt32())
+	assert.Equal(t, last+1, s.NextUID64())
+	assert.Equal(t, id32+1, s.NextUID32())
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = g.Next(canceled)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestRedisSequences(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	defer mr.Close()
+	host, port, _ := strings.Cut(mr.Addr(), ":")
+	p, _ := strconv.ParseInt(port, 10, 64)
+
+	config := uid.Config{
+		Server:    host,
+		Port:      p,
+		Key:       "seq",
+		Sequences: map[string]uid.SequenceConfig{"order": {InitValue: 1000, Step: 10}},
+	}
+	r, err := uid.NewRegistry(uid.TypeRedis, config)
+	if err != nil {
+		t.Fatalf("create new registry error, err:%v", err)
+	}
+	defer r.Close()
+
+	ctx := context.Background()
+	ids, err := r.NextN(ctx, "order", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1000, 1001, 1002}, ids)
+	// a step is reserved by one INCRBY and never expires
+	v, _ := mr.Get("seq:order")
+	assert.Equal(t, "1009", v)
+	assert.Equal(t, time.Duration(0), mr.TTL("seq:order"))
+
+	// a batch larger than the step is reserved at once
+	ids, err = r.NextN(ctx, "order", 20)
+	assert.Nil(t, err)
+	assert.Len(t, ids, 20)
+	assert.Equal(t, int64(1003), ids[0])
+	assert.Equal(t, int64(1022), ids[19])
+	v, _ = mr.Get("seq:order")
+	assert.Equal(t, "1022", v)
+
+	id, err := r.Next(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), id)
+	id, err = r.Next(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), id)
+	// the default step is the step of mysql
+	v, _ = mr.Get("seq:user")
+	assert.Equal(t, strconv.Itoa(uid.DefaultSequenceStep-1), v)
+
+	_, err = r.Next(ctx, "")
+	assert.NotNil(t, err)
+}
@@ -1,7 +1,10 @@
 package redis
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"sync"
 	"time"
 
 	"github.com/dbunion/com/uid"
@@ -12,51 +15,160 @@ const (
 	int32Key = "uid_int32_key"
 	int64Key = "uid_int64_key"
 	timeout  = time.Second * 60
+
+	defaultKeyPrefix = "uid"
 )
 
 // UID - Redis uuid gen
 type UID struct {
 	client *redis.Client
+	config uid.Config
+
+	mutex     sync.Mutex
+	sequences map[string]*sequence
 }
 
-// NewRedisUID - create new uid with default collection name.
-func NewRedisUID() uid.UID {
-	return &UID{}
+// sequence - a named sequence kept in key, ids are reserved step
+// at a time and handed out locally
+type sequence struct {
+	r    *UID
+	key  string
+	init int64
+	step int64
+
+	mutex sync.Mutex
+	// ids [next, max) reserved
+	next int64
+	max  int64
 }
 
-// HasInt32 - has int32 uid
-func (r *UID) HasInt32() bool {
-	return true
+// NewRedisUID - create new uid generator.
+func NewRedisUID() uid.UID {
+	return uid.Wrap(NewRedisUIDGenerator())
 }
 
-// NextUID32 - next int32 uid
-func (r *UID) NextUID32() int32 {
+// NewRedisUIDGenerator - create new context-aware uid generator.
+func NewRedisUIDGenerator() uid.Generator {
+	return &UID{}
+}
 
-	if _, err := r.client.Get(int32Key).Result(); err == redis.Nil {
-		r.client.Set(int32Key, time.Now().Unix(), timeout)
+// incrBy reserve n ids of key and return the last one,
+// a missing key starts from init.
+func (r *UID) incrBy(ctx context.Context, key string, init int64, n int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
 	}
+	client := r.client.WithContext(ctx)
+	if err := client.SetNX(key, init, timeout).Err(); err != nil {
+		return 0, err
+	}
+	return client.IncrBy(key, int64(n)).Result()
+}
 
-	val, err := r.client.IncrBy(int32Key, 1).Result()
+// Next32 - next int32 uid
+func (r *UID) Next32(ctx context.Context) (int32, error) {
+	val, err := r.incrBy(ctx, int32Key, time.Now().Unix(), 1)
 	if err != nil {
-		return -1
+		return 0, err
 	}
+	if val > math.MaxInt32 {
+		return 0, fmt.Errorf("int32 uid overflow, value:%d", val)
+	}
+	return int32(val), nil
+}
 
-	return int32(val)
+// Next - next int64 uid
+func (r *UID) Next(ctx context.Context) (int64, error) {
+	return r.incrBy(ctx, int64Key, time.Now().UnixNano(), 1)
 }
 
-// NextUID64 - next int64 uid
-func (r *UID) NextUID64() int64 {
+// NextN - next n int64 uids, reserved by one INCRBY
+func (r *UID) NextN(ctx context.Context, n int) ([]int64, error) {
+	if n <= 0 {
+		return []int64{}, nil
+	}
+	last, err := r.incrBy(ctx, int64Key, time.Now().UnixNano(), n)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, n)
+	for id := last - int64(n) + 1; id <= last; id++ {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
 
-	if _, err := r.client.Get(int64Key).Result(); err == redis.Nil {
-		r.client.Set(int64Key, time.Now().UnixNano(), timeout)
+// Sequence return the named sequence kept in key Config.Key:name,
+// the key is created without expiration on first use.
+func (r *UID) Sequence(name string) (uid.Sequence, error) {
+	if name == "" {
+		return nil, fmt.Errorf("invalid sequence name %q", name)
 	}
 
-	val, err := r.client.IncrBy(int64Key, 1).Result()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if s, ok := r.sequences[name]; ok {
+		return s, nil
+	}
+	prefix := r.config.Key
+	if prefix == "" {
+		prefix = defaultKeyPrefix
+	}
+	seq := r.config.Sequence(name)
+	s := &sequence{r: r, key: prefix + ":" + name, init: seq.InitValue, step: seq.Step}
+	r.sequences[name] = s
+	return s, nil
+}
+
+// Next - next int64 uid
+func (s *sequence) Next(ctx context.Context) (int64, error) {
+	ids, err := s.NextN(ctx, 1)
 	if err != nil {
-		return -1
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// NextN - next n int64 uids, one INCRBY reserves at least step ids
+func (s *sequence) NextN(ctx context.Context, n int) ([]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return []int64{}, nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ids := make([]int64, 0, n)
+	for s.next < s.max && len(ids) < n {
+		ids = append(ids, s.next)
+		s.next++
+	}
+	if len(ids) == n {
+		return ids, nil
 	}
 
-	return val
+	reserve := s.step
+	if need := int64(n - len(ids)); need > reserve {
+		reserve = need
+	}
+	client := s.r.client.WithContext(ctx)
+	// the first id is init as in the mysql sequence table
+	if err := client.SetNX(s.key, s.init-1, 0).Err(); err != nil {
+		return nil, err
+	}
+	last, err := client.IncrBy(s.key, reserve).Result()
+	if err != nil {
+		return nil, err
+	}
+	s.next, s.max = last-reserve+1, last+1
+	for len(ids) < n {
+		ids = append(ids, s.next)
+		s.next++
+	}
+	return ids, nil
 }
 
 // Close - close connection
@@ -88,10 +200,12 @@ func (r *UID) StartAndGC(config uid.Config) error {
 	}
 
 	r.client = client
+	r.config = config
+	r.sequences = make(map[string]*sequence)
 
 	return nil
 }
 
 func init() {
-	uid.Register(uid.TypeRedis, NewRedisUID)
+	uid.RegisterGenerator(uid.TypeRedis, NewRedisUIDGenerator)
 }
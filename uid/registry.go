@@ -0,0 +1,113 @@
+package uid
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrSequenceNotSupported is returned if the adapter does not implement Sequencer.
+var ErrSequenceNotSupported = errors.New("UID: named sequences not supported")
+
+// DefaultSequenceStep is the step of named sequences if neither
+// SequenceConfig nor Config set one.
+const DefaultSequenceStep = 1000
+
+// SequenceConfig - init value and step of a named sequence,
+// zero fields use InitValue and Step of Config
+type SequenceConfig struct {
+	InitValue int64 `json:"init_value"`
+	Step      int64 `json:"step"`
+}
+
+// Sequence return the config of the sequence name.
+func (c Config) Sequence(name string) SequenceConfig {
+	seq := c.Sequences[name]
+	if seq.InitValue == 0 {
+		seq.InitValue = c.InitValue
+	}
+	if seq.Step <= 0 {
+		seq.Step = c.Step
+	}
+	if seq.Step <= 0 {
+		seq.Step = DefaultSequenceStep
+	}
+	return seq
+}
+
+// Sequence is one named sequence of a Sequencer.
+type Sequence interface {
+	// next int64 uid
+	Next(ctx context.Context) (int64, error)
+
+	// next n int64 uids in ascending order
+	NextN(ctx context.Context, n int) ([]int64, error)
+}
+
+// Sequencer is implemented by generators which serve many named
+// sequences over one connection.
+type Sequencer interface {
+	// return the sequence name, it is created in the backend on first use
+	Sequence(name string) (Sequence, error)
+}
+
+// Registry - named sequences of one generator
+// usage:
+//
+//	r, err := uid.NewRegistry(uid.TypeRedis, config)
+//	id, err := r.Next(ctx, "order")
+type Registry struct {
+	g Generator
+	s Sequencer
+}
+
+// NewRegistry Create a new Registry by adapter name and config,
+// the adapter must implement Sequencer.
+func NewRegistry(adapterName string, config Config) (*Registry, error) {
+	g, err := NewGenerator(adapterName, config)
+	if err != nil {
+		return nil, err
+	}
+	r, err := AsRegistry(g)
+	if err != nil {
+		_ = g.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// AsRegistry return the Registry of g, g must implement Sequencer.
+func AsRegistry(g Generator) (*Registry, error) {
+	s, ok := g.(Sequencer)
+	if !ok {
+		return nil, ErrSequenceNotSupported
+	}
+	return &Registry{g: g, s: s}, nil
+}
+
+// Next - next int64 uid of the sequence name
+func (r *Registry) Next(ctx context.Context, name string) (int64, error) {
+	s, err := r.s.Sequence(name)
+	if err != nil {
+		return 0, err
+	}
+	return s.Next(ctx)
+}
+
+// NextN - next n int64 uids of the sequence name
+func (r *Registry) NextN(ctx context.Context, name string, n int) ([]int64, error) {
+	s, err := r.s.Sequence(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.NextN(ctx, n)
+}
+
+// Generator return the underlying generator.
+func (r *Registry) Generator() Generator {
+	return r.g
+}
+
+// Close - close connection
+func (r *Registry) Close() error {
+	return r.g.Close()
+}
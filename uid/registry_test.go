@@ -0,0 +1,60 @@
+package uid
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sequences keep a counter per name.
+type sequences struct {
+	counter
+	named map[string]*counter
+}
+
+func (s *sequences) Sequence(name string) (Sequence, error) {
+	c, ok := s.named[name]
+	if !ok {
+		c = &counter{}
+		s.named[name] = c
+	}
+	return c, nil
+}
+
+func TestRegistry(t *testing.T) {
+	s := &sequences{named: make(map[string]*counter)}
+	r, err := AsRegistry(s)
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	for i := int64(1); i <= 3; i++ {
+		id, err := r.Next(ctx, "order")
+		assert.Nil(t, err)
+		assert.Equal(t, i, id)
+	}
+	id, err := r.Next(ctx, "user")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), id)
+	assert.Equal(t, Generator(s), r.Generator())
+
+	_, err = AsRegistry(&counter{})
+	assert.Equal(t, ErrSequenceNotSupported, err)
+	_, err = NewRegistry("test_registry_missing", Config{})
+	assert.NotNil(t, err)
+}
+
+func TestConfigSequence(t *testing.T) {
+	config := Config{
+		InitValue: 1,
+		Step:      100,
+		Sequences: map[string]SequenceConfig{
+			"order": {InitValue: 1000},
+			"user":  {InitValue: 5, Step: 10},
+		},
+	}
+	assert.Equal(t, SequenceConfig{InitValue: 1000, Step: 100}, config.Sequence("order"))
+	assert.Equal(t, SequenceConfig{InitValue: 5, Step: 10}, config.Sequence("user"))
+	assert.Equal(t, SequenceConfig{InitValue: 1, Step: 100}, config.Sequence("other"))
+	assert.Equal(t, SequenceConfig{Step: DefaultSequenceStep}, Config{}.Sequence("other"))
+}
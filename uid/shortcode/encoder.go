@@ -0,0 +1,135 @@
+package shortcode
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DefaultAlphabet is the base62 alphabet of short codes.
+const DefaultAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+const minAlphabetLength = 16
+
+// Encoder - reversible short codes of non-negative ids in the style of hashids.
+// The first character is picked by the id and shuffles the alphabet of the
+// rest with the salt, so consecutive ids look unrelated. It hides the ids
+// from casual readers, it is not encryption.
+type Encoder struct {
+	alphabet  []byte
+	salt      string
+	minLength int
+}
+
+// NewEncoder create an encoder, an empty alphabet use DefaultAlphabet.
+func NewEncoder(alphabet, salt string, minLength int) (*Encoder, error) {
+	if alphabet == "" {
+		alphabet = DefaultAlphabet
+	}
+	if len(alphabet) < minAlphabetLength {
+		return nil, fmt.Errorf("short code alphabet must have at least %d characters", minAlphabetLength)
+	}
+	seen := make(map[byte]bool, len(alphabet))
+	for i := 0; i < len(alphabet); i++ {
+		c := alphabet[i]
+		if c >= 0x80 || c == ' ' || seen[c] {
+			return nil, fmt.Errorf("invalid short code alphabet character %q", c)
+		}
+		seen[c] = true
+	}
+	if minLength < 0 {
+		minLength = 0
+	}
+	return &Encoder{
+		alphabet:  shuffle([]byte(alphabet), salt),
+		salt:      salt,
+		minLength: minLength,
+	}, nil
+}
+
+// shuffle alphabet by salt in place, the consistent shuffle of hashids.
+func shuffle(alphabet []byte, salt string) []byte {
+	if salt == "" {
+		return alphabet
+	}
+	for i, v, p := len(alphabet)-1, 0, 0; i > 0; i-- {
+		v %= len(salt)
+		n := int(salt[v])
+		p += n
+		j := (n + v + p) % i
+		alphabet[i], alphabet[j] = alphabet[j], alphabet[i]
+		v++
+	}
+	return alphabet
+}
+
+// digits return the alphabet of the characters after lottery.
+func (e *Encoder) digits(lottery byte) []byte {
+	return shuffle(append([]byte(nil), e.alphabet...), string(lottery)+e.salt)
+}
+
+// Encode return the short code of id.
+func (e *Encoder) Encode(id int64) (string, error) {
+	if id < 0 {
+		return "", fmt.Errorf("short code of negative id %d", id)
+	}
+	base := int64(len(e.alphabet))
+	lottery := e.alphabet[id%base]
+	digits := e.digits(lottery)
+
+	var buf []byte
+	for n := id; ; n /= base {
+		buf = append(buf, digits[n%base])
+		if n < base {
+			break
+		}
+	}
+	// leading zero digits pad the code
+	for len(buf)+1 < e.minLength {
+		buf = append(buf, digits[0])
+	}
+
+	var b strings.Builder
+	b.Grow(len(buf) + 1)
+	b.WriteByte(lottery)
+	for i := len(buf) - 1; i >= 0; i-- {
+		b.WriteByte(buf[i])
+	}
+	return b.String(), nil
+}
+
+// Decode return the id of a short code.
+func (e *Encoder) Decode(code string) (int64, error) {
+	if len(code) < 2 {
+		return 0, fmt.Errorf("invalid short code %q", code)
+	}
+	digits := e.digits(code[0])
+	base := int64(len(digits))
+
+	var id int64
+	for i := 1; i < len(code); i++ {
+		d := indexOf(digits, code[i])
+		if d < 0 {
+			return 0, fmt.Errorf("invalid short code %q, character %q", code, code[i])
+		}
+		if id > (math.MaxInt64-d)/base {
+			return 0, fmt.Errorf("invalid short code %q, overflow", code)
+		}
+		id = id*base + d
+	}
+
+	// only the code of Encode is accepted
+	if expected, _ := e.Encode(id); expected != code {
+		return 0, fmt.Errorf("invalid short code %q", code)
+	}
+	return id, nil
+}
+
+func indexOf(alphabet []byte, c byte) int64 {
+	for i, a := range alphabet {
+		if a == c {
+			return int64(i)
+		}
+	}
+	return -1
+}
@@ -0,0 +1,78 @@
+package shortcode
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dbunion/com/uid"
+)
+
+// ShortCode - short codes of the ids of a numeric adapter
+type ShortCode struct {
+	g       uid.Generator
+	encoder *Encoder
+}
+
+// NewShortCode create new uid generator.
+func NewShortCode() uid.StringGenerator {
+	return &ShortCode{}
+}
+
+// NextString - short code of the next uid of the source adapter
+func (s *ShortCode) NextString(ctx context.Context) (string, error) {
+	id, err := s.g.Next(ctx)
+	if err != nil {
+		return "", err
+	}
+	return s.encoder.Encode(id)
+}
+
+// NextStringN - short codes of the next n uids of the source adapter
+func (s *ShortCode) NextStringN(ctx context.Context, n int) ([]string, error) {
+	ids, err := s.g.NextN(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, len(ids))
+	for _, id := range ids {
+		code, err := s.encoder.Encode(id)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// Decode return the uid of a short code.
+func (s *ShortCode) Decode(code string) (int64, error) {
+	return s.encoder.Decode(code)
+}
+
+// Close - close the source adapter
+func (s *ShortCode) Close() error {
+	return s.g.Close()
+}
+
+// StartAndGC start uid adapter.
+// config is like {"short_code":{"source":"redis","salt":"xxx"}, ...},
+// the rest of config is the config of the source adapter.
+func (s *ShortCode) StartAndGC(config uid.Config) (err error) {
+	sc := config.ShortCode
+	if sc == nil || sc.Source == "" {
+		return errors.New("short code source adapter is empty")
+	}
+	if sc.Source == uid.TypeShortCode {
+		return errors.New("short code source adapter must be numeric")
+	}
+	s.encoder, err = NewEncoder(sc.Alphabet, sc.Salt, sc.MinLength)
+	if err != nil {
+		return err
+	}
+	s.g, err = uid.NewGenerator(sc.Source, config)
+	return err
+}
+
+func init() {
+	uid.RegisterStringGenerator(uid.TypeShortCode, NewShortCode)
+}
@@ -0,0 +1,79 @@
+package shortcode
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/dbunion/com/uid"
+	_ "github.com/dbunion/com/uid/snowflake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoder(t *testing.T) {
+	e, err := NewEncoder("", "salt", 6)
+	assert.Nil(t, err)
+
+	seen := make(map[string]bool)
+	for _, id := range []int64{0, 1, 2, 61, 62, 12345, 1 << 40, math.MaxInt64} {
+		code, err := e.Encode(id)
+		assert.Nil(t, err)
+		assert.GreaterOrEqual(t, len(code), 6)
+		assert.False(t, seen[code])
+		seen[code] = true
+
+		decoded, err := e.Decode(code)
+		assert.Nil(t, err)
+		assert.Equal(t, id, decoded)
+	}
+
+	// consecutive ids look unrelated and another salt gives other codes
+	c1, _ := e.Encode(100)
+	c2, _ := e.Encode(101)
+	assert.NotEqual(t, c1[1:], c2[1:])
+	other, err := NewEncoder("", "pepper", 6)
+	assert.Nil(t, err)
+	c3, _ := other.Encode(100)
+	assert.NotEqual(t, c1, c3)
+
+	_, err = e.Encode(-1)
+	assert.NotNil(t, err)
+	for _, code := range []string{"", "a", c1 + "!", "zzzzzzzzzzzzzzzz"} {
+		_, err := e.Decode(code)
+		assert.NotNil(t, err)
+	}
+
+	_, err = NewEncoder("abc", "", 0)
+	assert.NotNil(t, err)
+	_, err = NewEncoder("0123456789abcdee", "", 0)
+	assert.NotNil(t, err)
+}
+
+func TestShortCode(t *testing.T) {
+	g, err := uid.NewStringGenerator(uid.TypeShortCode, uid.Config{
+		ShortCode: &uid.ShortCodeConfig{
+			Source:    uid.TypeSnowFlake,
+			Salt:      "salt",
+			MinLength: 8,
+		},
+		NodeID: 1,
+	})
+	if err != nil {
+		t.Fatalf("create new generator error, err:%v", err)
+	}
+	defer g.Close()
+
+	codes, err := g.NextStringN(context.Background(), 100)
+	assert.Nil(t, err)
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		assert.False(t, seen[code])
+		seen[code] = true
+		id, err := g.(*ShortCode).Decode(code)
+		assert.Nil(t, err)
+		assert.Greater(t, id, int64(0))
+	}
+
+	_, err = uid.NewStringGenerator(uid.TypeShortCode, uid.Config{})
+	assert.NotNil(t, err)
+}
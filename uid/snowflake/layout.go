@@ -0,0 +1,88 @@
+package snowflake
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultLayout is the layout of bwmarrin/snowflake ids,
+// the twitter epoch with 41 bits of milliseconds, 10 of node and 12 of sequence.
+var DefaultLayout = Layout{
+	Epoch:        time.UnixMilli(1288834974657),
+	TimeBits:     41,
+	NodeBits:     10,
+	SequenceBits: 12,
+}
+
+// Layout - bit layout of snowflake ids, time is in milliseconds since Epoch
+type Layout struct {
+	Epoch        time.Time
+	TimeBits     uint8
+	NodeBits     uint8
+	SequenceBits uint8
+}
+
+// ID - parts of a snowflake id
+type ID struct {
+	Time     time.Time
+	Node     int64
+	Sequence int64
+}
+
+// newLayout build the layout of config values, zero values use DefaultLayout.
+func newLayout(epoch int64, timeBits, nodeBits, sequenceBits uint8) (Layout, error) {
+	l := DefaultLayout
+	if epoch != 0 {
+		l.Epoch = time.UnixMilli(epoch)
+	}
+	if nodeBits != 0 {
+		l.NodeBits = nodeBits
+	}
+	if sequenceBits != 0 {
+		l.SequenceBits = sequenceBits
+	}
+	l.TimeBits = timeBits
+	if l.TimeBits == 0 && int(l.NodeBits)+int(l.SequenceBits) < 63 {
+		l.TimeBits = 63 - l.NodeBits - l.SequenceBits
+	}
+	if l.TimeBits == 0 || int(l.TimeBits)+int(l.NodeBits)+int(l.SequenceBits) > 63 {
+		return l, fmt.Errorf("invalid snowflake layout, time:%d node:%d sequence:%d bits exceed 63",
+			l.TimeBits, l.NodeBits, l.SequenceBits)
+	}
+	return l, nil
+}
+
+// MaxNode return the largest node id.
+func (l Layout) MaxNode() int64 {
+	return 1<<l.NodeBits - 1
+}
+
+// maxSequence return the largest sequence of a millisecond.
+func (l Layout) maxSequence() int64 {
+	return 1<<l.SequenceBits - 1
+}
+
+// maxTime return the largest milliseconds since Epoch.
+func (l Layout) maxTime() int64 {
+	return 1<<l.TimeBits - 1
+}
+
+// Compose build the id of ms milliseconds since Epoch.
+func (l Layout) Compose(ms, node, sequence int64) int64 {
+	return ms<<(l.NodeBits+l.SequenceBits) | node<<l.SequenceBits | sequence
+}
+
+// Decompose recover the time, node and sequence of id.
+func (l Layout) Decompose(id int64) ID {
+	ms := id >> (l.NodeBits + l.SequenceBits)
+	return ID{
+		Time:     l.Epoch.Add(time.Duration(ms) * time.Millisecond),
+		Node:     id >> l.SequenceBits & l.MaxNode(),
+		Sequence: id & l.maxSequence(),
+	}
+}
+
+// Decompose recover the time, node and sequence of id of DefaultLayout.
+func Decompose(id int64) ID {
+	return DefaultLayout.Decompose(id)
+}
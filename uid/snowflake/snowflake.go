@@ -1,54 +1,259 @@
 package snowflake
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/bwmarrin/snowflake"
+	"sync"
+	"time"
+
+	"github.com/dbunion/com/lock"
 	"github.com/dbunion/com/uid"
 )
 
-// Snowflake - Snowflake uuid gen
+const (
+	defaultMaxClockBackward = time.Second
+)
+
+const (
+	// ClockBackwardWait - wait for the clock to catch up
+	ClockBackwardWait = "wait"
+	// ClockBackwardFail - fail immediately
+	ClockBackwardFail = "fail"
+)
+
+var (
+	// ErrClockBackward is returned if the clock moved backwards further
+	// than allowed, or at all in ClockBackwardFail mode.
+	ErrClockBackward = errors.New("snowflake: clock moved backwards")
+	// ErrLeaseLost is returned while the leased node id is not held.
+	ErrLeaseLost = errors.New("snowflake: worker id lease lost")
+	// ErrNoWorkerID is returned if every node id is leased by someone else.
+	ErrNoWorkerID = errors.New("snowflake: no free worker id")
+)
+
+// Config - snow flake id layout and node id lease config, set it as
+// uid.Config.Adapter
+type Config struct {
+	// lease the node id with this lock adapter instead of NodeID, e.g. redis or mysql
+	WorkerLease string `json:"worker_lease"`
+	// config of the lock adapter of WorkerLease, the lease is refreshed every TTL/3
+	LeaseConfig lock.Config `json:"lease_config"`
+	// lease the node id with this locker, it is not closed with the generator
+	Locker lock.Locker `json:"-"`
+	// epoch of snow flake ids in milliseconds, default 1288834974657
+	Epoch int64 `json:"epoch"`
+	// bit layout of snow flake ids, default 41 bits of time, 10 of node and 12 of sequence,
+	// time takes the bits left of 63 if 0
+	TimeBits     uint8 `json:"time_bits"`
+	NodeBits     uint8 `json:"node_bits"`
+	SequenceBits uint8 `json:"sequence_bits"`
+	// ClockBackwardWait or ClockBackwardFail if the clock moves backwards, default wait
+	ClockBackward string `json:"clock_backward"`
+	// longest backward jump to wait for, longer ones fail, default 1s
+	MaxClockBackward time.Duration `json:"max_clock_backward"`
+}
+
+// Snowflake - Snowflake uuid gen. The node id is NodeID or leased
+// from a lock adapter, and refreshed in background while running.
 type Snowflake struct {
-	node *snowflake.Node
+	layout       Layout
+	failBackward bool
+	maxBackward  time.Duration
+	now          func() time.Time
+
+	mutex sync.Mutex
+	node  int64
+	// milliseconds since epoch of the last id
+	last     int64
+	sequence int64
+
+	worker *worker
 }
 
-// NewSnowflake create new uid with default collection name.
+// NewSnowflake create new uid generator.
 func NewSnowflake() uid.UID {
-	return &Snowflake{}
+	return uid.Wrap(NewSnowflakeGenerator())
+}
+
+// NewSnowflakeGenerator create new context-aware uid generator.
+func NewSnowflakeGenerator() uid.Generator {
+	return &Snowflake{now: time.Now}
+}
+
+// tick return milliseconds since epoch.
+func (s *Snowflake) tick() int64 {
+	return s.now().Sub(s.layout.Epoch).Milliseconds()
+}
+
+// Decompose recover the time, node and sequence of id.
+func (s *Snowflake) Decompose(id int64) ID {
+	return s.layout.Decompose(id)
+}
+
+// generate return the next id, must be called with mutex held.
+func (s *Snowflake) generate(ctx context.Context) (int64, error) {
+	if s.worker != nil && !s.worker.valid(s.now()) {
+		return 0, s.worker.lost()
+	}
+
+	now := s.tick()
+	if now < s.last {
+		backward := time.Duration(s.last-now) * time.Millisecond
+		if s.failBackward || backward > s.maxBackward {
+			return 0, fmt.Errorf("%w by %v", ErrClockBackward, backward)
+		}
+		timer := time.NewTimer(backward)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, ctx.Err()
+		case <-timer.C:
+		}
+		if now = s.tick(); now < s.last {
+			return 0, fmt.Errorf("%w by %v", ErrClockBackward, time.Duration(s.last-now)*time.Millisecond)
+		}
+	}
+
+	if now == s.last {
+		s.sequence = (s.sequence + 1) & s.layout.maxSequence()
+		if s.sequence == 0 {
+			// sequence exhausted, wait for the next millisecond
+			for now <= s.last {
+				now = s.tick()
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+	if now > s.layout.maxTime() {
+		return 0, fmt.Errorf("snowflake time overflow, %d ms since epoch", now)
+	}
+	s.last = now
+	return s.layout.Compose(now, s.node, s.sequence), nil
+}
+
+// Next - next int64 uid
+func (s *Snowflake) Next(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.generate(ctx)
+}
+
+// NextN - next n int64 uids
+func (s *Snowflake) NextN(ctx context.Context, n int) ([]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return []int64{}, nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ids := make([]int64, 0, n)
+	for i := 0; i < n; i++ {
+		id, err := s.generate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
 }
 
-// HasInt32 - has int32 uid
-func (s *Snowflake) HasInt32() bool {
-	return false
+// Sequence return the generator itself, snowflake ids are unique
+// across all names so sequences share the node.
+func (s *Snowflake) Sequence(name string) (uid.Sequence, error) {
+	if name == "" {
+		return nil, fmt.Errorf("invalid sequence name %q", name)
+	}
+	return s, nil
 }
 
-// NextUID32 - next int32 uid
-func (s *Snowflake) NextUID32() int32 {
-	return 0
+// Node return the node id in use.
+func (s *Snowflake) Node() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.node
 }
 
-// NextUID64 - next int64 uid
-func (s *Snowflake) NextUID64() int64 {
-	return s.node.Generate().Int64()
+// Err return the error of the last refresh of the leased node id, nil if
+// it succeeded or the node id is not leased.
+func (s *Snowflake) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.worker == nil {
+		return nil
+	}
+	return s.worker.err
 }
 
-// Close - close connection
+// Close - release the leased node id
 func (s *Snowflake) Close() error {
-	// do nothing
-	return nil
+	if s.worker == nil {
+		return nil
+	}
+	return s.worker.close()
 }
 
 // StartAndGC start uid adapter.
-// config is like {"node":"11"}
-// so no gc operation.
+// config is like {"node":"11"}, or {"adapter":{"worker_lease":"redis"}} to lease the node id.
 func (s *Snowflake) StartAndGC(config uid.Config) (err error) {
-	s.node, err = snowflake.NewNode(config.NodeID)
+	var sc Config
+	if err := config.AdapterConfig(&sc); err != nil {
+		return err
+	}
+	s.layout, err = newLayout(sc.Epoch, sc.TimeBits, sc.NodeBits, sc.SequenceBits)
 	if err != nil {
-		return fmt.Errorf("create new snowflake err:%v", err)
+		return err
+	}
+	if s.now == nil {
+		s.now = time.Now
 	}
 
+	switch sc.ClockBackward {
+	case "", ClockBackwardWait:
+	case ClockBackwardFail:
+		s.failBackward = true
+	default:
+		return fmt.Errorf("unknown clock backward mode %q", sc.ClockBackward)
+	}
+	s.maxBackward = sc.MaxClockBackward
+	if s.maxBackward <= 0 {
+		s.maxBackward = defaultMaxClockBackward
+	}
+
+	if sc.WorkerLease == "" && sc.Locker == nil {
+		if config.NodeID < 0 || config.NodeID > s.layout.MaxNode() {
+			return fmt.Errorf("create new snowflake err:node number must be between 0 and %d", s.layout.MaxNode())
+		}
+		s.node = config.NodeID
+		return nil
+	}
+
+	leaseConfig := sc.LeaseConfig
+	leaseConfig.CheckWithDefault()
+	leaseConfig.AutoRefresh = false
+	locker, owned := sc.Locker, false
+	if locker == nil {
+		if locker, err = lock.NewLocker(sc.WorkerLease, leaseConfig); err != nil {
+			return fmt.Errorf("create worker lease locker err:%v", err)
+		}
+		owned = true
+	}
+	s.worker = newWorker(s, locker, owned, leaseConfig.TTL)
+	if err := s.worker.acquire(context.Background()); err != nil {
+		_ = s.worker.close()
+		s.worker = nil
+		return err
+	}
+	go s.worker.heartbeat()
 	return nil
 }
 
 func init() {
-	uid.Register(uid.TypeSnowFlake, NewSnowflake)
+	uid.RegisterGenerator(uid.TypeSnowFlake, NewSnowflakeGenerator)
 }
@@ -1,6 +1,7 @@
 package snowflake
 
 import (
+	"context"
 	"github.com/dbunion/com/uid"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -45,3 +46,46 @@ func BenchmarkNewSnowflake(b *testing.B) {
 		b.Logf("Node:3 UID:%d", s3.NextUID64())
 	}
 }
+
+func TestSnowflakeGenerator(t *testing.T) {
+	g, err := uid.NewGenerator(uid.TypeSnowFlake, uid.Config{NodeID: 1})
+	if err != nil {
+		t.Fatalf("create new generator error, err:%v", err)
+	}
+
+	ids, err := g.NextN(context.Background(), 100)
+	assert.Nil(t, err)
+	assert.Len(t, ids, 100)
+	for i := 1; i < len(ids); i++ {
+		assert.Greater(t, ids[i], ids[i-1])
+	}
+
+	id, err := g.Next(context.Background())
+	assert.Nil(t, err)
+	assert.Greater(t, id, ids[99])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = g.Next(ctx)
+	assert.Equal(t, context.Canceled, err)
+
+	// snowflake has no int32 uid
+	s, err := uid.NewUID(uid.TypeSnowFlake, uid.Config{NodeID: 1})
+	assert.Nil(t, err)
+	assert.False(t, s.HasInt32())
+	assert.Equal(t, int32(0), s.NextUID32())
+}
+
+func TestSnowflakeSequences(t *testing.T) {
+	r, err := uid.NewRegistry(uid.TypeSnowFlake, uid.Config{NodeID: 1})
+	if err != nil {
+		t.Fatalf("create new registry error, err:%v", err)
+	}
+
+	ctx := context.Background()
+	order, err := r.Next(ctx, "order")
+	assert.Nil(t, err)
+	user, err := r.Next(ctx, "user")
+	assert.Nil(t, err)
+	assert.Greater(t, user, order)
+}
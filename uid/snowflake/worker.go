@@ -0,0 +1,153 @@
+package snowflake
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/dbunion/com/lock"
+)
+
+// worker - node id leased from a locker. The lease is refreshed every
+// ttl/3, ids are refused once ttl passed since the last successful refresh
+// and a new node id is leased if the old one is lost.
+type worker struct {
+	s      *Snowflake
+	locker lock.Locker
+	owned  bool
+	ttl    time.Duration
+
+	// guarded by s.mutex
+	lease      *lock.Lease
+	validUntil time.Time
+	// error of the last refresh, nil if it succeeded
+	err error
+
+	done chan struct{}
+	once sync.Once
+}
+
+func newWorker(s *Snowflake, locker lock.Locker, owned bool, ttl time.Duration) *worker {
+	return &worker{
+		s:      s,
+		locker: locker,
+		owned:  owned,
+		ttl:    ttl,
+		done:   make(chan struct{}),
+	}
+}
+
+// workerKey return the lock key of node id.
+func workerKey(node int64) string {
+	return fmt.Sprintf("snowflake_worker:%d", node)
+}
+
+// valid report whether the lease is held at now, must be called with s.mutex held.
+func (w *worker) valid(now time.Time) bool {
+	return w.lease != nil && now.Before(w.validUntil)
+}
+
+// acquire lease a free node id, scanning from a random one so nodes
+// starting together rarely compete for the same ids.
+func (w *worker) acquire(ctx context.Context) error {
+	max := w.s.layout.MaxNode() + 1
+	start := rand.Int63n(max)
+	for i := int64(0); i < max; i++ {
+		node := (start + i) % max
+		begin := w.s.now()
+		lease, err := w.locker.TryLock(ctx, workerKey(node))
+		if err == lock.ErrLocked {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		w.s.mutex.Lock()
+		w.s.node = node
+		w.lease = lease
+		w.validUntil = begin.Add(w.ttl)
+		w.s.mutex.Unlock()
+		return nil
+	}
+	return ErrNoWorkerID
+}
+
+// refresh extend the lease, a lease no longer held is dropped.
+// The error is kept for Err and the error of refused ids.
+func (w *worker) refresh(ctx context.Context) error {
+	w.s.mutex.Lock()
+	lease := w.lease
+	w.s.mutex.Unlock()
+	if lease == nil {
+		err := w.acquire(ctx)
+		w.s.mutex.Lock()
+		w.err = err
+		w.s.mutex.Unlock()
+		return err
+	}
+
+	begin := w.s.now()
+	err := w.locker.Refresh(ctx, lease)
+	w.s.mutex.Lock()
+	defer w.s.mutex.Unlock()
+	switch err {
+	case nil:
+		w.validUntil = begin.Add(w.ttl)
+	case lock.ErrNotHeld:
+		w.lease = nil
+	}
+	w.err = err
+	return err
+}
+
+// lost return the error of ids refused while the lease is not held,
+// must be called with s.mutex held.
+func (w *worker) lost() error {
+	if w.err != nil {
+		return fmt.Errorf("%w: %v", ErrLeaseLost, w.err)
+	}
+	return ErrLeaseLost
+}
+
+// heartbeat refresh the lease every ttl/3 until closed.
+func (w *worker) heartbeat() {
+	interval := w.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			// the error is reported by Err and Next
+			_ = w.refresh(ctx)
+			cancel()
+		}
+	}
+}
+
+// close stop the heartbeat and release the lease.
+func (w *worker) close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+
+		w.s.mutex.Lock()
+		lease := w.lease
+		w.lease = nil
+		w.s.mutex.Unlock()
+		if lease != nil {
+			err = w.locker.Unlock(context.Background(), lease)
+		}
+		if w.owned {
+			if cErr := w.locker.Close(); cErr != nil && err == nil {
+				err = cErr
+			}
+		}
+	})
+	return err
+}
@@ -0,0 +1,174 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dbunion/com/lock"
+	"github.com/dbunion/com/lock/memory"
+	_ "github.com/dbunion/com/lock/redis"
+	"github.com/dbunion/com/uid"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSnowflake start a Snowflake with config.
+func newTestSnowflake(t *testing.T, config uid.Config) *Snowflake {
+	s := NewSnowflakeGenerator().(*Snowflake)
+	if err := s.StartAndGC(config); err != nil {
+		t.Fatalf("start snowflake error, err:%v", err)
+	}
+	return s
+}
+
+func TestLayout(t *testing.T) {
+	epoch := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestSnowflake(t, uid.Config{
+		NodeID: 5,
+		Adapter: &Config{
+			Epoch:        epoch.UnixMilli(),
+			NodeBits:     4,
+			SequenceBits: 8,
+		},
+	})
+	assert.Equal(t, uint8(51), s.layout.TimeBits)
+
+	before := time.Now().Truncate(time.Millisecond)
+	ids, err := s.NextN(context.Background(), 3)
+	assert.Nil(t, err)
+	for _, id := range ids {
+		parts := s.Decompose(id)
+		assert.Equal(t, int64(5), parts.Node)
+		assert.False(t, parts.Time.Before(before))
+		assert.False(t, parts.Time.After(time.Now()))
+	}
+
+	// the default layout is the layout of bwmarrin/snowflake
+	id := DefaultLayout.Compose(1000, 3, 7)
+	assert.Equal(t, int64(1000<<22|3<<12|7), id)
+	assert.Equal(t, ID{Time: DefaultLayout.Epoch.Add(time.Second), Node: 3, Sequence: 7}, Decompose(id))
+
+	for _, config := range []uid.Config{
+		{Adapter: &Config{NodeBits: 30, SequenceBits: 33}},
+		{Adapter: &Config{TimeBits: 42, NodeBits: 10, SequenceBits: 12}},
+		{NodeID: 16, Adapter: &Config{NodeBits: 4}},
+		{Adapter: &Config{ClockBackward: "ignore"}},
+	} {
+		assert.NotNil(t, NewSnowflakeGenerator().StartAndGC(config))
+	}
+}
+
+func TestClockBackward(t *testing.T) {
+	var offset time.Duration
+	now := func() time.Time { return time.Now().Add(-offset) }
+	ctx := context.Background()
+
+	// small jumps are waited for
+	s := NewSnowflakeGenerator().(*Snowflake)
+	s.now = now
+	assert.Nil(t, s.StartAndGC(uid.Config{NodeID: 1, Adapter: &Config{MaxClockBackward: 50 * time.Millisecond}}))
+	last, err := s.Next(ctx)
+	assert.Nil(t, err)
+	offset = 20 * time.Millisecond
+	id, err := s.Next(ctx)
+	assert.Nil(t, err)
+	assert.Greater(t, id, last)
+
+	// larger jumps fail
+	offset += time.Second
+	_, err = s.Next(ctx)
+	assert.True(t, errors.Is(err, ErrClockBackward))
+
+	// fail mode never waits
+	offset = 0
+	s = NewSnowflakeGenerator().(*Snowflake)
+	s.now = now
+	assert.Nil(t, s.StartAndGC(uid.Config{NodeID: 1, Adapter: &Config{ClockBackward: ClockBackwardFail}}))
+	_, err = s.Next(ctx)
+	assert.Nil(t, err)
+	offset = 5 * time.Millisecond
+	_, err = s.Next(ctx)
+	assert.True(t, errors.Is(err, ErrClockBackward))
+}
+
+func TestWorkerLease(t *testing.T) {
+	locker := memory.NewMemoryLocker()
+	assert.Nil(t, locker.StartAndGC(lock.Config{TTL: time.Minute}))
+	config := uid.Config{Adapter: &Config{Locker: locker, NodeBits: 1, LeaseConfig: lock.Config{TTL: time.Minute}}}
+	ctx := context.Background()
+
+	s1 := newTestSnowflake(t, config)
+	s2 := newTestSnowflake(t, config)
+	assert.NotEqual(t, s1.Node(), s2.Node())
+
+	// every node id is leased
+	assert.Equal(t, ErrNoWorkerID, NewSnowflakeGenerator().StartAndGC(config))
+	assert.Nil(t, s1.Close())
+	s3 := newTestSnowflake(t, config)
+	assert.Equal(t, s1.Node(), s3.Node())
+
+	id, err := s2.Next(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, s2.Node(), s2.Decompose(id).Node)
+
+	// a lease lost to someone else stop the generator until a new one is leased
+	s2.mutex.Lock()
+	lease := s2.worker.lease
+	s2.mutex.Unlock()
+	assert.Nil(t, locker.Unlock(ctx, lease))
+	assert.Equal(t, lock.ErrNotHeld, s2.worker.refresh(ctx))
+	assert.Equal(t, lock.ErrNotHeld, s2.Err())
+	_, err = s2.Next(ctx)
+	assert.True(t, errors.Is(err, ErrLeaseLost))
+	assert.Contains(t, err.Error(), lock.ErrNotHeld.Error())
+	assert.Nil(t, s2.worker.refresh(ctx))
+	assert.Nil(t, s2.Err())
+	_, err = s2.Next(ctx)
+	assert.Nil(t, err)
+
+	// ids are refused once the lease may have expired
+	s3.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, err = s3.Next(ctx)
+	assert.Equal(t, ErrLeaseLost, err)
+
+	assert.Nil(t, s2.Close())
+	assert.Nil(t, s3.Close())
+	assert.Nil(t, locker.Close())
+}
+
+func TestWorkerLeaseRedis(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("start miniredis error, err:%v", err)
+	}
+	defer mr.Close()
+	host, port, _ := strings.Cut(mr.Addr(), ":")
+	p, _ := strconv.ParseInt(port, 10, 64)
+
+	config := uid.Config{Adapter: &Config{
+		WorkerLease: lock.TypeRedis,
+		NodeBits:    2,
+		LeaseConfig: lock.Config{Server: host, Port: p, Key: "uid", TTL: 300 * time.Millisecond},
+	}}
+	g, err := uid.NewGenerator(uid.TypeSnowFlake, config)
+	if err != nil {
+		t.Fatalf("create new generator error, err:%v", err)
+	}
+	s := g.(*Snowflake)
+	key := "uid:{" + workerKey(s.Node()) + "}"
+	assert.True(t, mr.Exists(key))
+
+	// the heartbeat keep the lease past its ttl
+	time.Sleep(500 * time.Millisecond)
+	mr.FastForward(200 * time.Millisecond)
+	assert.True(t, mr.Exists(key))
+	_, err = g.Next(context.Background())
+	assert.Nil(t, err)
+
+	assert.Nil(t, g.Close())
+	assert.False(t, mr.Exists(key))
+}
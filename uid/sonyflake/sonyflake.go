@@ -0,0 +1,175 @@
+package sonyflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/dbunion/com/uid"
+)
+
+const (
+	timeBits     = 39
+	sequenceBits = 8
+	machineBits  = 16
+	timeUnit     = 10 * time.Millisecond
+)
+
+// DefaultStartTime is the start time of sonyflake ids if Config.StartTime is 0.
+var DefaultStartTime = time.Date(2014, 9, 1, 0, 0, 0, 0, time.UTC)
+
+// Sonyflake - sonyflake uuid gen, 39 bits of 10 milliseconds since the
+// start time, 8 bits of sequence and 16 bits of machine id. A clock moved
+// backwards keeps counting on the last time unit.
+type Sonyflake struct {
+	start   time.Time
+	machine int64
+	now     func() time.Time
+
+	mutex    sync.Mutex
+	last     int64
+	sequence int64
+}
+
+// ID - parts of a sonyflake id
+type ID struct {
+	Time     time.Time
+	Sequence int64
+	Machine  int64
+}
+
+// NewSonyflake create new uid generator.
+func NewSonyflake() uid.Generator {
+	return &Sonyflake{now: time.Now}
+}
+
+// elapsed return the time units since start.
+func (s *Sonyflake) elapsed() int64 {
+	return int64(s.now().Sub(s.start) / timeUnit)
+}
+
+// generate return the next id, must be called with mutex held.
+func (s *Sonyflake) generate(ctx context.Context) (int64, error) {
+	if elapsed := s.elapsed(); s.last < elapsed {
+		s.last = elapsed
+		s.sequence = 0
+	} else {
+		s.sequence = (s.sequence + 1) & (1<<sequenceBits - 1)
+		if s.sequence == 0 {
+			// sequence exhausted, borrow the next time unit and wait for it
+			s.last++
+			wait := s.start.Add(time.Duration(s.last) * timeUnit).Sub(s.now())
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return 0, ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+	}
+	if s.last >= 1<<timeBits {
+		return 0, errors.New("sonyflake time overflow")
+	}
+	return s.last<<(sequenceBits+machineBits) | s.sequence<<machineBits | s.machine, nil
+}
+
+// Next - next int64 uid
+func (s *Sonyflake) Next(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.generate(ctx)
+}
+
+// NextN - next n int64 uids
+func (s *Sonyflake) NextN(ctx context.Context, n int) ([]int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return []int64{}, nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ids := make([]int64, 0, n)
+	for i := 0; i < n; i++ {
+		id, err := s.generate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Decompose recover the time, sequence and machine id of id.
+func (s *Sonyflake) Decompose(id int64) ID {
+	return ID{
+		Time:     s.start.Add(time.Duration(id>>(sequenceBits+machineBits)) * timeUnit),
+		Sequence: id >> machineBits & (1<<sequenceBits - 1),
+		Machine:  id & (1<<machineBits - 1),
+	}
+}
+
+// Close - close connection
+func (s *Sonyflake) Close() error {
+	// do nothing
+	return nil
+}
+
+// StartAndGC start uid adapter.
+// config is like {"node_id":11}, the private ip is used if node_id is 0
+// so no gc operation.
+func (s *Sonyflake) StartAndGC(config uid.Config) error {
+	s.start = DefaultStartTime
+	if config.Sonyflake != nil && config.Sonyflake.StartTime != 0 {
+		s.start = time.UnixMilli(config.Sonyflake.StartTime)
+	}
+	if s.start.After(time.Now()) {
+		return fmt.Errorf("sonyflake start time %v is in the future", s.start)
+	}
+
+	switch {
+	case config.NodeID < 0 || config.NodeID >= 1<<machineBits:
+		return fmt.Errorf("sonyflake machine id must be between 0 and %d", 1<<machineBits-1)
+	case config.NodeID > 0:
+		s.machine = config.NodeID
+	default:
+		ip, err := privateIPv4()
+		if err != nil {
+			return err
+		}
+		s.machine = int64(ip[2])<<8 | int64(ip[3])
+	}
+	return nil
+}
+
+// privateIPv4 return the first private ipv4 address of interfaces.
+func privateIPv4() (net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() {
+			continue
+		}
+		if ip := ipnet.IP.To4(); ip != nil && ip.IsPrivate() {
+			return ip, nil
+		}
+	}
+	return nil, errors.New("sonyflake: no private ip address, set node_id")
+}
+
+func init() {
+	uid.RegisterGenerator(uid.TypeSonyflake, NewSonyflake)
+}
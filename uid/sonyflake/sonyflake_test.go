@@ -0,0 +1,75 @@
+package sonyflake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/uid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSonyflake(t *testing.T) {
+	g, err := uid.NewGenerator(uid.TypeSonyflake, uid.Config{NodeID: 7})
+	if err != nil {
+		t.Fatalf("create new generator error, err:%v", err)
+	}
+	defer g.Close()
+
+	before := time.Now().Truncate(timeUnit)
+	ids, err := g.NextN(context.Background(), 600)
+	assert.Nil(t, err)
+	for i, id := range ids {
+		if i > 0 {
+			assert.Greater(t, id, ids[i-1])
+		}
+		parts := g.(*Sonyflake).Decompose(id)
+		assert.Equal(t, int64(7), parts.Machine)
+		assert.False(t, parts.Time.Before(before))
+	}
+
+	// string form is decimal
+	s, err := uid.NewStringGenerator(uid.TypeSonyflake, uid.Config{NodeID: 7})
+	assert.Nil(t, err)
+	id, err := s.NextString(context.Background())
+	assert.Nil(t, err)
+	assert.Regexp(t, "^[0-9]+$", id)
+
+	for _, config := range []uid.Config{
+		{NodeID: 1 << 16},
+		{NodeID: 1, Sonyflake: &uid.SonyflakeConfig{StartTime: time.Now().Add(time.Hour).UnixMilli()}},
+	} {
+		assert.NotNil(t, NewSonyflake().StartAndGC(config))
+	}
+}
+
+func TestSonyflakeSequence(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	now := start.Add(time.Second)
+	s := &Sonyflake{now: func() time.Time { return now }}
+	assert.Nil(t, s.StartAndGC(uid.Config{NodeID: 1, Sonyflake: &uid.SonyflakeConfig{StartTime: start.UnixMilli()}}))
+	ctx := context.Background()
+
+	ids, err := s.NextN(ctx, 200)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(199), s.Decompose(ids[199]).Sequence)
+
+	// a clock moved backwards keep counting on the last time unit
+	now = now.Add(-time.Minute)
+	id, err := s.Next(ctx)
+	assert.Nil(t, err)
+	assert.Greater(t, id, ids[199])
+	assert.Equal(t, s.Decompose(ids[0]).Time, s.Decompose(id).Time)
+
+	// an exhausted sequence borrow the next time unit
+	now = now.Add(time.Minute)
+	ids, err = s.NextN(ctx, 56)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), s.Decompose(ids[55]).Sequence)
+	assert.Equal(t, s.Decompose(id).Time.Add(timeUnit), s.Decompose(ids[55]).Time)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.Next(canceled)
+	assert.Equal(t, context.Canceled, err)
+}
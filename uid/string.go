@@ -0,0 +1,119 @@
+package uid
+
+import (
+	"context"
+	"strconv"
+)
+
+// StringGenerator generates string ids, e.g. ULID and UUIDv7.
+type StringGenerator interface {
+	// next string uid
+	NextString(ctx context.Context) (string, error)
+
+	// next n string uids
+	NextStringN(ctx context.Context, n int) ([]string, error)
+
+	// close connection
+	Close() error
+
+	// start gc routine based on config settings.
+	StartAndGC(config Config) error
+}
+
+// StringGeneratorInstance is a function create a new StringGenerator Instance
+type StringGeneratorInstance func() StringGenerator
+
+var stringGenerators = make(map[string]StringGeneratorInstance)
+
+// RegisterStringGenerator makes a StringGenerator adapter available by the adapter name.
+// If RegisterStringGenerator is called twice with the same name or if driver is nil,
+// it panics.
+func RegisterStringGenerator(name string, adapter StringGeneratorInstance) {
+	if adapter == nil {
+		panic("UID: RegisterStringGenerator adapter is nil")
+	}
+	if _, ok := stringGenerators[name]; ok {
+		panic("UID: RegisterStringGenerator called twice for adapter " + name)
+	}
+	if _, ok := generators[name]; ok {
+		panic("UID: RegisterStringGenerator called twice for adapter " + name)
+	}
+	if _, ok := adapters[name]; ok {
+		panic("UID: RegisterStringGenerator called twice for adapter " + name)
+	}
+	stringGenerators[name] = adapter
+}
+
+// NewStringGenerator Create a new StringGenerator by adapter name and config,
+// numeric adapters are adapted with AsStringGenerator.
+// it will start gc automatically.
+func NewStringGenerator(adapterName string, config Config) (StringGenerator, error) {
+	instanceFunc, ok := stringGenerators[adapterName]
+	if !ok {
+		g, err := NewGenerator(adapterName, config)
+		if err != nil {
+			return nil, err
+		}
+		return AsStringGenerator(g), nil
+	}
+	g := instanceFunc()
+	if err := g.StartAndGC(config); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// AsStringGenerator return the StringGenerator view of g, generators
+// without a string form return their ids in decimal.
+func AsStringGenerator(g Generator) StringGenerator {
+	if s, ok := g.(StringGenerator); ok {
+		return s
+	}
+	return &decimalGenerator{Generator: g}
+}
+
+// decimalGenerator adapts a Generator to the StringGenerator interface.
+type decimalGenerator struct {
+	Generator
+}
+
+// NextString - next uid in decimal
+func (g *decimalGenerator) NextString(ctx context.Context) (string, error) {
+	id, err := g.Next(ctx)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+// NextStringN - next n uids in decimal
+func (g *decimalGenerator) NextStringN(ctx context.Context, n int) ([]string, error) {
+	ids, err := g.NextN(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	s := make([]string, 0, len(ids))
+	for _, id := range ids {
+		s = append(s, strconv.FormatInt(id, 10))
+	}
+	return s, nil
+}
+
+// NextStringN call next n times, it helps adapters implement NextStringN.
+func NextStringN(ctx context.Context, n int, next func() (string, error)) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return []string{}, nil
+	}
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		id, err := next()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
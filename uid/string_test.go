@@ -0,0 +1,60 @@
+package uid
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// letters generate a, b, c...
+type letters struct {
+	n int
+}
+
+func (l *letters) NextString(ctx context.Context) (string, error) {
+	l.n++
+	return string(rune('a' + l.n - 1)), nil
+}
+
+func (l *letters) NextStringN(ctx context.Context, n int) ([]string, error) {
+	return NextStringN(ctx, n, func() (string, error) {
+		return l.NextString(ctx)
+	})
+}
+
+func (l *letters) Close() error                   { return nil }
+func (l *letters) StartAndGC(config Config) error { return nil }
+
+func TestStringGenerator(t *testing.T) {
+	RegisterStringGenerator("test_letters", func() StringGenerator { return &letters{} })
+	RegisterGenerator("test_decimal", func() Generator { return &counter{} })
+	assert.Panics(t, func() { RegisterGenerator("test_letters", func() Generator { return &counter{} }) })
+	assert.Panics(t, func() { RegisterStringGenerator("test_decimal", func() StringGenerator { return &letters{} }) })
+
+	ctx := context.Background()
+	s, err := NewStringGenerator("test_letters", Config{})
+	assert.Nil(t, err)
+	ids, err := s.NextStringN(ctx, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, ids)
+	ids, err = s.NextStringN(ctx, 0)
+	assert.Nil(t, err)
+	assert.Empty(t, ids)
+
+	_, err = NewGenerator("test_letters", Config{})
+	assert.EqualError(t, err, `UID: adapter "test_letters" generates string uids, use NewStringGenerator`)
+
+	// numeric generators are formatted in decimal
+	s, err = NewStringGenerator("test_decimal", Config{})
+	assert.Nil(t, err)
+	for i := 1; i <= 3; i++ {
+		id, err := s.NextString(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, strconv.Itoa(i), id)
+	}
+
+	_, err = NewStringGenerator("missing", Config{})
+	assert.NotNil(t, err)
+}
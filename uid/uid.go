@@ -1,7 +1,11 @@
 package uid
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
 )
 
 const (
@@ -11,6 +15,14 @@ const (
 	TypeRedis = "redis"
 	// TypeSnowFlake - uid use snow flake
 	TypeSnowFlake = "snow_flake"
+	// TypeSonyflake - uid use sonyflake
+	TypeSonyflake = "sonyflake"
+	// TypeULID - string uid use ULID
+	TypeULID = "ulid"
+	// TypeUUIDv7 - string uid use UUID version 7
+	TypeUUIDv7 = "uuid_v7"
+	// TypeShortCode - string uid use short codes of a numeric adapter
+	TypeShortCode = "short_code"
 )
 
 // Config - uid config
@@ -34,14 +46,86 @@ type Config struct {
 	TableName       string `json:"table_name"`
 	AutoCreateTable bool   `json:"auto_create_table"`
 
+	// init value and step of named sequences, others use InitValue and Step
+	Sequences map[string]SequenceConfig `json:"sequences"`
+
+	// adapter config, nil use the defaults of the adapter
+	Sonyflake *SonyflakeConfig `json:"sonyflake"`
+	ShortCode *ShortCodeConfig `json:"short_code"`
+	// config defined by the adapter package, e.g. *snowflake.Config or
+	// *mysql.Config, nil use the defaults of the adapter
+	Adapter interface{} `json:"adapter"`
+
 	// Extend fields
 	// Extended fields can be used if there is a special implementation
 	Extend1 string `json:"extend_1"`
 	Extend2 string `json:"extend_2"`
 }
 
+// SonyflakeConfig - sonyflake config, machine id is NodeID,
+// or the lower 16 bits of the private ip if 0
+type SonyflakeConfig struct {
+	// start time in milliseconds, default 2014-09-01 00:00:00 UTC
+	StartTime int64 `json:"start_time"`
+}
+
+// AdapterConfig copy Adapter into v, a pointer to the config struct of
+// the adapter. Adapter may be v, the struct v points to, or decoded JSON,
+// v is left unchanged if Adapter is nil.
+func (c Config) AdapterConfig(v interface{}) error {
+	if c.Adapter == nil {
+		return nil
+	}
+	dst := reflect.ValueOf(v)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return fmt.Errorf("UID: adapter config %T is not a pointer", v)
+	}
+	src := reflect.ValueOf(c.Adapter)
+	switch {
+	case src.Type() == dst.Type():
+		if !src.IsNil() {
+			dst.Elem().Set(src.Elem())
+		}
+		return nil
+	case src.Type() == dst.Elem().Type():
+		dst.Elem().Set(src)
+		return nil
+	}
+
+	var data []byte
+	switch a := c.Adapter.(type) {
+	case json.RawMessage:
+		data = a
+	case map[string]interface{}:
+		var err error
+		if data, err = json.Marshal(a); err != nil {
+			return fmt.Errorf("UID: marshal adapter config err:%v", err)
+		}
+	default:
+		return fmt.Errorf("UID: adapter config is %T, want %T", c.Adapter, v)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("UID: unmarshal adapter config err:%v", err)
+	}
+	return nil
+}
+
+// ShortCodeConfig - short code config, the rest of Config is the
+// config of the source adapter
+type ShortCodeConfig struct {
+	// numeric adapter encoded by short_code
+	Source string `json:"source"`
+	// alphabet of short codes, default base62
+	Alphabet string `json:"alphabet"`
+	// salt shuffle the alphabet and the ids
+	Salt string `json:"salt"`
+	// short codes are padded to this length
+	MinLength int `json:"min_length"`
+}
+
 // UID interface contains all behaviors for UID adapter.
 // usage:
+//
 //	uid.Register("uid", uid.NewUID)
 type UID interface {
 	// has int32 uid
@@ -60,10 +144,38 @@ type UID interface {
 	StartAndGC(config Config) error
 }
 
+// Generator is the context-aware v2 of UID, failures are returned
+// as errors instead of sentinel ids.
+type Generator interface {
+	// next int64 uid
+	Next(ctx context.Context) (int64, error)
+
+	// next n int64 uids in ascending order
+	NextN(ctx context.Context, n int) ([]int64, error)
+
+	// close connection
+	Close() error
+
+	// start gc routine based on config settings.
+	StartAndGC(config Config) error
+}
+
+// Int32Generator is implemented by generators which have int32 uid.
+type Int32Generator interface {
+	// next int32 uid
+	Next32(ctx context.Context) (int32, error)
+}
+
 // Instance is a function create a new UID Instance
 type Instance func() UID
 
-var adapters = make(map[string]Instance)
+// GeneratorInstance is a function create a new Generator Instance
+type GeneratorInstance func() Generator
+
+var (
+	adapters   = make(map[string]Instance)
+	generators = make(map[string]GeneratorInstance)
+)
 
 // Register makes a UID adapter available by the adapter name.
 // If Register is called twice with the same name or if driver is nil,
@@ -75,9 +187,35 @@ func Register(name string, adapter Instance) {
 	if _, ok := adapters[name]; ok {
 		panic("UID: Register called twice for adapter " + name)
 	}
+	if _, ok := generators[name]; ok {
+		panic("UID: Register called twice for adapter " + name)
+	}
+	if _, ok := stringGenerators[name]; ok {
+		panic("UID: Register called twice for adapter " + name)
+	}
 	adapters[name] = adapter
 }
 
+// RegisterGenerator makes a Generator adapter available by the adapter name,
+// it is also available to NewUID through Wrap.
+// If RegisterGenerator is called twice with the same name or if driver is nil,
+// it panics.
+func RegisterGenerator(name string, adapter GeneratorInstance) {
+	if adapter == nil {
+		panic("UID: RegisterGenerator adapter is nil")
+	}
+	if _, ok := generators[name]; ok {
+		panic("UID: RegisterGenerator called twice for adapter " + name)
+	}
+	if _, ok := adapters[name]; ok {
+		panic("UID: RegisterGenerator called twice for adapter " + name)
+	}
+	if _, ok := stringGenerators[name]; ok {
+		panic("UID: RegisterGenerator called twice for adapter " + name)
+	}
+	generators[name] = adapter
+}
+
 // NewUID Create a new UID driver by adapter name and config string.
 // config need to be correct JSON as string:
 // {"server": "localhost:9092", "user": "xxxx", "password":"xxxxx"}.
@@ -85,6 +223,13 @@ func Register(name string, adapter Instance) {
 func NewUID(adapterName string, config Config) (adapter UID, err error) {
 	instanceFunc, ok := adapters[adapterName]
 	if !ok {
+		if _, ok := generators[adapterName]; ok {
+			g, err := NewGenerator(adapterName, config)
+			if err != nil {
+				return nil, err
+			}
+			return Wrap(g), nil
+		}
 		err = fmt.Errorf("UID: unknown adapter name %q (forgot to import?)", adapterName)
 		return
 	}
@@ -95,3 +240,137 @@ func NewUID(adapterName string, config Config) (adapter UID, err error) {
 	}
 	return
 }
+
+// NewGenerator Create a new Generator by adapter name and config,
+// adapters registered by Register are adapted with AsGenerator.
+// it will start gc automatically.
+func NewGenerator(adapterName string, config Config) (Generator, error) {
+	instanceFunc, ok := generators[adapterName]
+	if !ok {
+		if _, ok := adapters[adapterName]; ok {
+			u, err := NewUID(adapterName, config)
+			if err != nil {
+				return nil, err
+			}
+			return AsGenerator(u), nil
+		}
+		if _, ok := stringGenerators[adapterName]; ok {
+			return nil, fmt.Errorf("UID: adapter %q generates string uids, use NewStringGenerator", adapterName)
+		}
+		return nil, fmt.Errorf("UID: unknown adapter name %q (forgot to import?)", adapterName)
+	}
+	g := instanceFunc()
+	if err := g.StartAndGC(config); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// ErrGenerate is returned by generators adapted from a UID which
+// returned a negative id.
+var ErrGenerate = errors.New("UID: generate failure")
+
+// Wrap return the UID view of g, failures are reported as -1.
+func Wrap(g Generator) UID {
+	return &wrapper{g: g}
+}
+
+// wrapper adapts a Generator to the UID interface.
+type wrapper struct {
+	g Generator
+}
+
+// Unwrap return the wrapped generator.
+func (w *wrapper) Unwrap() Generator {
+	return w.g
+}
+
+// HasInt32 - has int32 uid
+func (w *wrapper) HasInt32() bool {
+	_, ok := w.g.(Int32Generator)
+	return ok
+}
+
+// NextUID32 - next int32 uid, -1 on failure and 0 if int32 is not supported
+func (w *wrapper) NextUID32() int32 {
+	g, ok := w.g.(Int32Generator)
+	if !ok {
+		return 0
+	}
+	id, err := g.Next32(context.Background())
+	if err != nil {
+		return -1
+	}
+	return id
+}
+
+// NextUID64 - next int64 uid, -1 on failure
+func (w *wrapper) NextUID64() int64 {
+	id, err := w.g.Next(context.Background())
+	if err != nil {
+		return -1
+	}
+	return id
+}
+
+// Close - close connection
+func (w *wrapper) Close() error {
+	return w.g.Close()
+}
+
+// StartAndGC start the wrapped generator.
+func (w *wrapper) StartAndGC(config Config) error {
+	return w.g.StartAndGC(config)
+}
+
+// AsGenerator return the Generator view of u, a negative id is
+// reported as ErrGenerate.
+func AsGenerator(u UID) Generator {
+	if w, ok := u.(*wrapper); ok {
+		return w.g
+	}
+	return &uidGenerator{u: u}
+}
+
+// uidGenerator adapts a UID to the Generator interface.
+type uidGenerator struct {
+	u UID
+}
+
+// Next - next int64 uid
+func (g *uidGenerator) Next(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	id := g.u.NextUID64()
+	if id < 0 {
+		return 0, ErrGenerate
+	}
+	return id, nil
+}
+
+// NextN - next n int64 uids, one NextUID64 per id
+func (g *uidGenerator) NextN(ctx context.Context, n int) ([]int64, error) {
+	if n <= 0 {
+		return []int64{}, nil
+	}
+	ids := make([]int64, 0, n)
+	for i := 0; i < n; i++ {
+		id, err := g.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Close - close connection
+func (g *uidGenerator) Close() error {
+	return g.u.Close()
+}
+
+// StartAndGC start the adapted UID.
+func (g *uidGenerator) StartAndGC(config Config) error {
+	return g.u.StartAndGC(config)
+}
@@ -0,0 +1,122 @@
+package uid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// counter count from 1 and fail once failed is set.
+type counter struct {
+	n      int64
+	failed bool
+}
+
+func (c *counter) Next(ctx context.Context) (int64, error) {
+	if c.failed {
+		return 0, errors.New("next failed")
+	}
+	c.n++
+	return c.n, nil
+}
+
+func (c *counter) NextN(ctx context.Context, n int) ([]int64, error) {
+	return nil, nil
+}
+
+func (c *counter) Next32(ctx context.Context) (int32, error) {
+	id, err := c.Next(ctx)
+	return int32(id), err
+}
+
+func (c *counter) Close() error                   { return nil }
+func (c *counter) StartAndGC(config Config) error { return nil }
+
+// legacy is an old style adapter returning -1 on failure.
+type legacy struct {
+	counter
+}
+
+func (l *legacy) HasInt32() bool   { return false }
+func (l *legacy) NextUID32() int32 { return 0 }
+func (l *legacy) NextUID64() int64 {
+	id, err := l.Next(context.Background())
+	if err != nil {
+		return -1
+	}
+	return id
+}
+
+func TestWrap(t *testing.T) {
+	c := &counter{}
+	u := Wrap(c)
+	assert.True(t, u.HasInt32())
+	assert.Equal(t, int64(1), u.NextUID64())
+	assert.Equal(t, int32(2), u.NextUID32())
+	assert.Same(t, c, AsGenerator(u))
+
+	c.failed = true
+	assert.Equal(t, int64(-1), u.NextUID64())
+	assert.Equal(t, int32(-1), u.NextUID32())
+}
+
+func TestAsGenerator(t *testing.T) {
+	l := &legacy{}
+	g := AsGenerator(l)
+
+	ids, err := g.NextN(context.Background(), 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+
+	l.failed = true
+	_, err = g.Next(context.Background())
+	assert.Equal(t, ErrGenerate, err)
+}
+
+func TestRegisterGenerator(t *testing.T) {
+	RegisterGenerator("test_counter", func() Generator { return &counter{} })
+	Register("test_uid", func() UID { return &legacy{} })
+	assert.Panics(t, func() { Register("test_counter", func() UID { return &legacy{} }) })
+
+	u, err := NewUID("test_counter", Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), u.NextUID64())
+
+	g, err := NewGenerator("test_uid", Config{})
+	assert.Nil(t, err)
+	id, err := g.Next(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), id)
+
+	_, err = NewGenerator("missing", Config{})
+	assert.NotNil(t, err)
+}
+
+func TestAdapterConfig(t *testing.T) {
+	type options struct {
+		Name string `json:"name"`
+		Size int    `json:"size"`
+	}
+
+	var o options
+	assert.Nil(t, Config{}.AdapterConfig(&o))
+	assert.Equal(t, options{}, o)
+
+	assert.Nil(t, Config{Adapter: &options{Name: "pointer"}}.AdapterConfig(&o))
+	assert.Equal(t, options{Name: "pointer"}, o)
+
+	assert.Nil(t, Config{Adapter: options{Name: "value", Size: 1}}.AdapterConfig(&o))
+	assert.Equal(t, options{Name: "value", Size: 1}, o)
+
+	var c Config
+	assert.Nil(t, json.Unmarshal([]byte(`{"adapter":{"name":"json","size":2}}`), &c))
+	o = options{}
+	assert.Nil(t, c.AdapterConfig(&o))
+	assert.Equal(t, options{Name: "json", Size: 2}, o)
+
+	assert.NotNil(t, Config{Adapter: &struct{ Name string }{}}.AdapterConfig(&o))
+	assert.NotNil(t, Config{Adapter: &options{}}.AdapterConfig(o))
+}
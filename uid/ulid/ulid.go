@@ -0,0 +1,169 @@
+package ulid
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dbunion/com/uid"
+)
+
+// encoding is the Crockford base32 alphabet of ULIDs.
+const encoding = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ErrOverflow is returned if the random part of a millisecond is exhausted.
+var ErrOverflow = errors.New("ulid: monotonic entropy overflow")
+
+// ULID - monotonic ULID gen, 48 bits of milliseconds and 80 random bits.
+// ids of the same millisecond, or of a clock moved backwards, increment
+// the random part of the last id so they stay sorted.
+type ULID struct {
+	mutex   sync.Mutex
+	now     func() time.Time
+	entropy io.Reader
+	last    uint64
+	random  [10]byte
+}
+
+// NewULID create new uid generator.
+func NewULID() uid.StringGenerator {
+	return &ULID{now: time.Now, entropy: rand.Reader}
+}
+
+// increment add one to the random part, false on overflow.
+func (u *ULID) increment() bool {
+	for i := len(u.random) - 1; i >= 0; i-- {
+		u.random[i]++
+		if u.random[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// next return the next ULID, must be called with mutex held.
+func (u *ULID) next() (string, error) {
+	ms := uint64(u.now().UnixMilli())
+	if ms > u.last {
+		if _, err := io.ReadFull(u.entropy, u.random[:]); err != nil {
+			return "", err
+		}
+		u.last = ms
+	} else if !u.increment() {
+		return "", ErrOverflow
+	}
+
+	var id [16]byte
+	for i := 0; i < 6; i++ {
+		id[i] = byte(u.last >> (40 - 8*i))
+	}
+	copy(id[6:], u.random[:])
+	return encode(id), nil
+}
+
+// NextString - next ULID
+func (u *ULID) NextString(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.next()
+}
+
+// NextStringN - next n ULIDs in ascending order
+func (u *ULID) NextStringN(ctx context.Context, n int) ([]string, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return uid.NextStringN(ctx, n, u.next)
+}
+
+// Close - close connection
+func (u *ULID) Close() error {
+	// do nothing
+	return nil
+}
+
+// StartAndGC start uid adapter.
+// so no gc operation.
+func (u *ULID) StartAndGC(config uid.Config) error {
+	return nil
+}
+
+// encode the 128 bits of id into 26 characters, the first one
+// holds the 3 high bits.
+func encode(id [16]byte) string {
+	bit := func(j int) byte {
+		if j < 0 {
+			return 0
+		}
+		return id[j/8] >> (7 - j%8) & 1
+	}
+	s := make([]byte, 26)
+	for i := range s {
+		var v byte
+		for j := i*5 - 2; j < i*5+3; j++ {
+			v = v<<1 | bit(j)
+		}
+		s[i] = encoding[v]
+	}
+	return string(s)
+}
+
+// decode the 128 bits of a ULID.
+func decode(s string) ([16]byte, error) {
+	var id [16]byte
+	if len(s) != 26 {
+		return id, fmt.Errorf("invalid ulid %q, length:%d", s, len(s))
+	}
+	if s[0] > '7' {
+		return id, fmt.Errorf("invalid ulid %q, overflow", s)
+	}
+	for i := 0; i < len(s); i++ {
+		v := indexOf(s[i])
+		if v < 0 {
+			return id, fmt.Errorf("invalid ulid %q, character %q", s, s[i])
+		}
+		for k := 0; k < 5; k++ {
+			j := i*5 - 2 + k
+			if j >= 0 && v>>(4-k)&1 == 1 {
+				id[j/8] |= 1 << (7 - j%8)
+			}
+		}
+	}
+	return id, nil
+}
+
+// indexOf return the value of a base32 character, lower case is accepted.
+func indexOf(c byte) int {
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	for i := 0; i < len(encoding); i++ {
+		if encoding[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// Time return the time a ULID was generated.
+func Time(s string) (time.Time, error) {
+	id, err := decode(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var ms uint64
+	for i := 0; i < 6; i++ {
+		ms = ms<<8 | uint64(id[i])
+	}
+	return time.UnixMilli(int64(ms)), nil
+}
+
+func init() {
+	uid.RegisterStringGenerator(uid.TypeULID, NewULID)
+}
@@ -0,0 +1,71 @@
+package ulid
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/uid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestULID(t *testing.T) {
+	g, err := uid.NewStringGenerator(uid.TypeULID, uid.Config{})
+	if err != nil {
+		t.Fatalf("create new generator error, err:%v", err)
+	}
+	defer g.Close()
+
+	before := time.Now().Truncate(time.Millisecond)
+	ids, err := g.NextStringN(context.Background(), 1000)
+	assert.Nil(t, err)
+	assert.Len(t, ids, 1000)
+	assert.True(t, sort.StringsAreSorted(ids))
+	for _, id := range ids {
+		assert.Len(t, id, 26)
+		ts, err := Time(id)
+		assert.Nil(t, err)
+		assert.False(t, ts.Before(before))
+		assert.False(t, ts.After(time.Now()))
+	}
+
+	// numeric interfaces are not available
+	_, err = uid.NewGenerator(uid.TypeULID, uid.Config{})
+	assert.NotNil(t, err)
+}
+
+func TestULIDMonotonic(t *testing.T) {
+	now := time.UnixMilli(1469918176385)
+	u := &ULID{now: func() time.Time { return now }, entropy: bytes.NewReader(make([]byte, 20))}
+	ctx := context.Background()
+
+	// the known encoding of the time, zero entropy increment in the same millisecond
+	id, err := u.NextString(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "01ARYZ6S410000000000000000", id)
+	id, err = u.NextString(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "01ARYZ6S410000000000000001", id)
+
+	// a clock moved backwards keep incrementing
+	now = now.Add(-time.Second)
+	next, err := u.NextString(ctx)
+	assert.Nil(t, err)
+	assert.Greater(t, next, id)
+
+	for i := range u.random {
+		u.random[i] = 0xff
+	}
+	_, err = u.NextString(ctx)
+	assert.Equal(t, ErrOverflow, err)
+
+	for _, s := range []string{"01ARYZ6S41", "81ARYZ6S410000000000000000", "01ARYZ6S41000000000000000U"} {
+		_, err := Time(s)
+		assert.NotNil(t, err)
+	}
+	ts, err := Time("01aryz6s410000000000000000")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1469918176385), ts.UnixMilli())
+}
@@ -0,0 +1,107 @@
+package uuidv7
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dbunion/com/uid"
+	"github.com/google/uuid"
+)
+
+// UUIDv7 - UUID version 7 gen, 48 bits of milliseconds, a 12 bits
+// counter and 62 random bits. The counter starts from a random value
+// below 2048 each millisecond, ids stay sorted if it overflows or the
+// clock moves backwards by moving the timestamp forward.
+type UUIDv7 struct {
+	mutex   sync.Mutex
+	now     func() time.Time
+	entropy io.Reader
+	last    int64
+	counter uint16
+}
+
+// NewUUIDv7 create new uid generator.
+func NewUUIDv7() uid.StringGenerator {
+	return &UUIDv7{now: time.Now, entropy: rand.Reader}
+}
+
+// next return the next uuid, must be called with mutex held.
+func (u *UUIDv7) next() (string, error) {
+	var id uuid.UUID
+	if _, err := io.ReadFull(u.entropy, id[6:]); err != nil {
+		return "", err
+	}
+
+	ms := u.now().UnixMilli()
+	if ms > u.last {
+		u.last = ms
+		u.counter = (uint16(id[6])<<8 | uint16(id[7])) & 0x7ff
+	} else {
+		u.counter++
+		if u.counter > 0xfff {
+			u.last++
+			u.counter = 0
+		}
+	}
+
+	for i := 0; i < 6; i++ {
+		id[i] = byte(u.last >> (40 - 8*i))
+	}
+	id[6] = 0x70 | byte(u.counter>>8)
+	id[7] = byte(u.counter)
+	id[8] = 0x80 | id[8]&0x3f
+	return id.String(), nil
+}
+
+// NextString - next UUIDv7
+func (u *UUIDv7) NextString(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.next()
+}
+
+// NextStringN - next n UUIDv7s in ascending order
+func (u *UUIDv7) NextStringN(ctx context.Context, n int) ([]string, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return uid.NextStringN(ctx, n, u.next)
+}
+
+// Close - close connection
+func (u *UUIDv7) Close() error {
+	// do nothing
+	return nil
+}
+
+// StartAndGC start uid adapter.
+// so no gc operation.
+func (u *UUIDv7) StartAndGC(config uid.Config) error {
+	return nil
+}
+
+// Time return the time a UUIDv7 was generated.
+func Time(s string) (time.Time, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if id.Version() != 7 {
+		return time.Time{}, fmt.Errorf("invalid uuid v7 %q, version:%d", s, id.Version())
+	}
+	var ms int64
+	for i := 0; i < 6; i++ {
+		ms = ms<<8 | int64(id[i])
+	}
+	return time.UnixMilli(ms), nil
+}
+
+func init() {
+	uid.RegisterStringGenerator(uid.TypeUUIDv7, NewUUIDv7)
+}
@@ -0,0 +1,56 @@
+package uuidv7
+
+import (
+	"context"
+	"crypto/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dbunion/com/uid"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUUIDv7(t *testing.T) {
+	g, err := uid.NewStringGenerator(uid.TypeUUIDv7, uid.Config{})
+	if err != nil {
+		t.Fatalf("create new generator error, err:%v", err)
+	}
+	defer g.Close()
+
+	before := time.Now().Truncate(time.Millisecond)
+	ids, err := g.NextStringN(context.Background(), 5000)
+	assert.Nil(t, err)
+	assert.True(t, sort.StringsAreSorted(ids))
+	for _, id := range ids {
+		u, err := uuid.Parse(id)
+		assert.Nil(t, err)
+		assert.Equal(t, uuid.Version(7), u.Version())
+		assert.Equal(t, uuid.RFC4122, u.Variant())
+		ts, err := Time(id)
+		assert.Nil(t, err)
+		assert.False(t, ts.Before(before))
+	}
+
+	_, err = Time(uuid.New().String())
+	assert.NotNil(t, err)
+}
+
+func TestUUIDv7Monotonic(t *testing.T) {
+	now := time.Now()
+	u := &UUIDv7{now: func() time.Time { return now }, entropy: rand.Reader}
+	ctx := context.Background()
+
+	// the counter overflow and a clock moved backwards move the timestamp forward
+	ids, err := u.NextStringN(ctx, 4096)
+	assert.Nil(t, err)
+	now = now.Add(-time.Second)
+	more, err := u.NextStringN(ctx, 10)
+	assert.Nil(t, err)
+	ids = append(ids, more...)
+	assert.True(t, sort.StringsAreSorted(ids))
+	last, err := Time(ids[len(ids)-1])
+	assert.Nil(t, err)
+	assert.Equal(t, now.Add(time.Second).UnixMilli()+1, last.UnixMilli())
+}